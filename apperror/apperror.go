@@ -0,0 +1,144 @@
+// Package apperror berisi error aplikasi bertipe: status HTTP, kode yang stabil untuk klien
+// (misalnya ITEM_NOT_FOUND) dan pesan yang aman ditampilkan. Handler cukup memanggil
+// c.Error(err), lalu middleware.ErrorHandler merender error sebagai application/problem+json.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/stock"
+
+	"gorm.io/gorm"
+)
+
+// Daftar kode error yang dikirim ke klien
+const (
+	CodeBadRequest            = "BAD_REQUEST"
+	CodeValidationFailed      = "VALIDATION_FAILED"
+	CodeInvalidQuery          = "INVALID_QUERY"
+	CodeInvalidDate           = "INVALID_DATE"
+	CodeInvalidTransition     = "INVALID_TRANSITION"
+	CodeInvalidStatus         = "INVALID_STATUS"
+	CodeInsufficientStock     = "INSUFFICIENT_STOCK"
+	CodeItemInUse             = "ITEM_IN_USE"
+	CodeInvitationInvalid     = "INVITATION_INVALID"
+	CodeEmailTaken            = "EMAIL_TAKEN"
+	CodeUnauthorized          = "UNAUTHORIZED"
+	CodeInvalidCredentials    = "INVALID_CREDENTIALS"
+	CodeInvalidToken          = "INVALID_TOKEN"
+	CodeLoginLocked           = "LOGIN_LOCKED"
+	CodeInvalidMFACode        = "INVALID_MFA_CODE"
+	CodeMFARequired           = "MFA_REQUIRED"
+	CodeInvalidAPIKey         = "INVALID_API_KEY"
+	CodeSessionRequired       = "SESSION_REQUIRED"
+	CodePasswordLoginDisabled = "PASSWORD_LOGIN_DISABLED"
+	CodeSSOFailed             = "SSO_FAILED"
+	CodeForbidden             = "FORBIDDEN"
+	CodeEmailNotVerified      = "EMAIL_NOT_VERIFIED"
+	CodeNotFound              = "NOT_FOUND"
+	CodeRouteNotFound         = "ROUTE_NOT_FOUND"
+	CodeItemNotFound          = "ITEM_NOT_FOUND"
+	CodeUserNotFound          = "USER_NOT_FOUND"
+	CodeAdminNotFound         = "ADMIN_NOT_FOUND"
+	CodeDetailNotFound        = "DETAIL_NOT_FOUND"
+	CodeCartNotFound          = "TRANSACTION_NOT_FOUND"
+	CodeInvitationMissing     = "INVITATION_NOT_FOUND"
+	CodeUnknownAction         = "UNKNOWN_ACTION"
+	CodeConflict              = "CONFLICT"
+	CodeInternal              = "INTERNAL_ERROR"
+)
+
+// Error adalah error aplikasi yang dirender sebagai problem+json
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Details berisi pesan per field untuk error validasi
+	Details []string
+	// Err adalah penyebab asli, hanya dicatat di log dan tidak pernah dikirim ke klien
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New membuat error dengan status HTTP, kode dan pesan untuk klien
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Validation membuat error 400 berisi daftar pesan validasi per field
+func Validation(details []string) *Error {
+	return &Error{Status: 400, Code: CodeValidationFailed, Message: "Request validation failed", Details: details}
+}
+
+// Internal membungkus error tak terduga menjadi 500 tanpa membocorkan pesan aslinya ke klien
+func Internal(err error) *Error {
+	return &Error{Status: 500, Code: CodeInternal, Message: "Internal server error", Err: err}
+}
+
+// NotFound mengembalikan error 404 dengan kode dan pesan jika err berarti data tidak ditemukan.
+// Error lain, misalnya koneksi database terputus, tetap diperlakukan sebagai error internal.
+func NotFound(err error, code, message string) *Error {
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Status: 404, Code: code, Message: message, Err: err}
+	}
+	return From(err)
+}
+
+// From mengubah error apa pun menjadi *Error. Error domain dari loan, stock dan repository
+// dipetakan ke kode masing-masing, sisanya menjadi error internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	// Pesan loan.Error ditujukan untuk klien, error domain lain memakai pesan bawaan
+	var loanErr *loan.Error
+	errors.As(err, &loanErr)
+	domain := func(status int, code, message string) *Error {
+		if loanErr != nil {
+			message = loanErr.Message
+		}
+		return &Error{Status: status, Code: code, Message: message, Err: err}
+	}
+
+	switch {
+	case errors.Is(err, loan.ErrDetailNotFound):
+		return domain(404, CodeDetailNotFound, "Detail not found")
+	case errors.Is(err, loan.ErrItemNotFound), errors.Is(err, stock.ErrItemNotFound):
+		return domain(404, CodeItemNotFound, "Item not found")
+	case errors.Is(err, loan.ErrUnknownAction):
+		return domain(404, CodeUnknownAction, "Unknown action")
+	case errors.Is(err, loan.ErrForbidden):
+		return domain(403, CodeForbidden, "Forbidden")
+	case errors.Is(err, loan.ErrConflict), errors.Is(err, stock.ErrConflict):
+		return domain(409, CodeConflict, "Resource was changed by another request, please retry")
+	case errors.Is(err, loan.ErrInvalidTransition):
+		return domain(400, CodeInvalidTransition, "Invalid transition")
+	case errors.Is(err, loan.ErrInsufficientStock), errors.Is(err, stock.ErrInsufficientStock):
+		return domain(400, CodeInsufficientStock, "Not enough stock available")
+	case errors.Is(err, loan.ErrInvalidRange):
+		return domain(400, CodeInvalidDate, "Invalid date range")
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return domain(404, CodeNotFound, "Resource not found")
+	case errors.Is(err, repository.ErrAPIKeyRevoked):
+		return domain(409, CodeConflict, "API key is already revoked")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return domain(409, CodeConflict, "Resource already exists")
+	default:
+		return Internal(err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"Gin-Inventory/controller"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/migration"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/stock"
+
+	"gorm.io/gorm"
+)
+
+// runCommand menjalankan subcommand CLI, misalnya: ./main create-admin -name "Admin" -email a@b.c -password rahasia
+func runCommand(db *gorm.DB, args []string) {
+	switch args[0] {
+	case "create-admin":
+		createAdminCommand(db, args[1:])
+	case "reconcile-stock":
+		reconcileStockCommand(db, args[1:])
+	case "reset-mfa":
+		resetMFACommand(db, args[1:])
+	default:
+		log.Fatalf("Unknown command: %s", args[0])
+	}
+}
+
+// createAdminCommand membuat admin pertama. Setelah ada admin, gunakan undangan dari admin yang sudah ada.
+func createAdminCommand(db *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	name := fs.String("name", "", "admin name")
+	email := fs.String("email", "", "admin email")
+	password := fs.String("password", os.Getenv("ADMIN_PASSWORD"), "admin password (default from ADMIN_PASSWORD)")
+	fs.Parse(args)
+
+	adminData := middleware.UserSchema{Name: *name, Email: *email, Password: *password}
+	if validationErrors := middleware.ValidateInput(adminData); validationErrors != nil {
+		log.Fatalf("Invalid admin data: %v", validationErrors)
+	}
+
+	users := repository.NewGorm(db).Users
+	admin, err := controller.CreateAdminAccount(users, adminData)
+	if errors.Is(err, repository.ErrSetupCompleted) {
+		log.Fatalf("An admin already exists, new admins must be invited by an existing admin")
+	}
+	if err != nil {
+		log.Fatalf("Failed to create admin: %v", err)
+	}
+
+	log.Printf("Admin %s created with ID %d", admin.Email, admin.ID)
+}
+
+// resetMFACommand mematikan TOTP akun yang kehilangan perangkat authenticator dan recovery code-nya.
+// Jika role akun wajib MFA, akun harus melakukan enrollment ulang setelah login.
+func resetMFACommand(db *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("reset-mfa", flag.ExitOnError)
+	email := fs.String("email", "", "account email")
+	fs.Parse(args)
+
+	user, err := repository.NewGorm(db).Users.FindByEmail(*email)
+	if err != nil {
+		log.Fatalf("Account %s not found: %v", *email, err)
+	}
+	if err := middleware.ResetMFA(db, user.ID); err != nil {
+		log.Fatalf("Failed to reset two-factor authentication: %v", err)
+	}
+
+	log.Printf("Two-factor authentication of %s has been reset", user.Email)
+}
+
+// reconcileStockCommand menghitung ulang stok dari ledger dan melaporkan item yang tidak cocok.
+// Dengan -fix, stok item diperbarui mengikuti ledger.
+func reconcileStockCommand(db *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("reconcile-stock", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "update item stock to match the ledger")
+	fs.Parse(args)
+
+	drifts, err := stock.Reconcile(db, *fix)
+	if err != nil {
+		log.Fatalf("Failed to reconcile stock: %v", err)
+	}
+
+	if len(drifts) == 0 {
+		log.Println("All item stock matches the ledger")
+		return
+	}
+
+	for _, drift := range drifts {
+		log.Printf("Item %d (%s): stock %d, ledger %d, drift %d", drift.ItemID, drift.Name, drift.Stock, drift.LedgerStock, drift.Stock-drift.LedgerStock)
+	}
+	if *fix {
+		log.Printf("Updated stock of %d item(s) to match the ledger", len(drifts))
+		return
+	}
+	os.Exit(1)
+}
+
+// migrateCommand mengelola versi skema database: migrate up, migrate down [-steps N] atau migrate status
+func migrateCommand(db *gorm.DB, args []string) {
+	if len(args) == 0 {
+		log.Fatalf("Usage: migrate up|down|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migration.Up(db)
+		for _, m := range applied {
+			log.Printf("Applied migration %d (%s)", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database schema is up to date")
+		}
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		fs.Parse(args[1:])
+
+		reverted, err := migration.Down(db, *steps)
+		for _, m := range reverted {
+			log.Printf("Rolled back migration %d (%s)", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to roll back database: %v", err)
+		}
+	case "status":
+		current, err := migration.Current(db)
+		if err != nil {
+			log.Fatalf("Failed to read schema version: %v", err)
+		}
+		entries, err := migration.Status(db)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+
+		fmt.Printf("Schema version %d, binary knows up to %d\n", current, migration.Latest())
+		for _, entry := range entries {
+			status := "pending"
+			if entry.AppliedAt != nil {
+				status = "applied " + entry.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-30s %s\n", entry.Version, entry.Name, status)
+		}
+		if current > migration.Latest() {
+			os.Exit(1)
+		}
+	default:
+		log.Fatalf("Unknown migrate command: %s, use up, down or status", args[0])
+	}
+}
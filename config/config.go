@@ -1,44 +1,503 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
-	"Gin-Inventory/model"
+	"Gin-Inventory/mail"
+	"Gin-Inventory/migration"
+	"Gin-Inventory/sso"
+	"Gin-Inventory/store"
 
+	"github.com/goccy/go-yaml"
 	"github.com/joho/godotenv"
-	"gorm.io/driver/mysql"
+	"github.com/pelletier/go-toml/v2"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-var JWTSecret string
+// Config berisi semua pengaturan aplikasi. Nilai dibaca dari (prioritas tertinggi dulu):
+// environment variable, file .env, file konfigurasi YAML/TOML dari CONFIG_FILE, lalu default.
+// Kunci di file konfigurasi sama dengan nama env dalam huruf kecil, misalnya jwt_secret.
+type Config struct {
+	Port int
+	// PublicURL adalah alamat aplikasi yang dibuka pengguna (skema dan host, misalnya
+	// https://inventory.example.com), dipakai untuk tautan di email dan undangan. Tidak diambil
+	// dari header Host agar tautan tidak bisa diarahkan ke domain lain.
+	PublicURL string
+	// TrustedProxies adalah IP atau CIDR reverse proxy yang header X-Forwarded-For-nya dipercaya
+	// untuk menentukan IP klien, diisi dipisah koma. Kosong berarti IP koneksi langsung yang dipakai.
+	TrustedProxies []string
+	// ShutdownTimeout adalah batas waktu menunggu request dan job selesai saat SIGTERM
+	ShutdownTimeout time.Duration
+	// LogLevel adalah level log minimum: debug, info, warn atau error
+	LogLevel slog.Level
+	// MetricsToken melindungi /metrics dengan bearer token, kosong berarti terbuka
+	MetricsToken string
 
-func InitConfig() {
-	// Load environment variables
-	err := godotenv.Load()
+	DatabaseURL string
+	// MigrateOnStart menjalankan migrasi yang tertunda saat start
+	MigrateOnStart bool
+
+	JWTSecret          string
+	JWTExpire          time.Duration
+	RefreshTokenExpire time.Duration
+	// TokenStore adalah penyimpanan token yang dicabut: "database" atau "memory" (satu instance)
+	TokenStore string
+	// TokenPruneInterval adalah jeda pembersihan token dicabut yang sudah kedaluwarsa
+	TokenPruneInterval time.Duration
+
+	// LoginAttemptStore adalah penyimpanan hitungan login gagal: "database" atau "memory" (satu instance)
+	LoginAttemptStore string
+	// LoginMaxAttempts dan LoginMaxAttemptsPerIP adalah jumlah login gagal sebelum akun atau IP dikunci
+	LoginMaxAttempts      int
+	LoginMaxAttemptsPerIP int
+	// LoginLockout adalah lama kunci pertama, berlipat dua setiap gagal lagi sampai LoginMaxLockout
+	LoginLockout    time.Duration
+	LoginMaxLockout time.Duration
+	// LoginAttemptWindow adalah lama login gagal diingat sejak kegagalan terakhir
+	LoginAttemptWindow time.Duration
+
+	// MFARequiredRoles adalah role yang wajib memakai TOTP, diisi dipisah koma (MFA_REQUIRED_ROLES=admin)
+	MFARequiredRoles []string
+	// MFAIssuer adalah nama aplikasi yang tampil di aplikasi authenticator
+	MFAIssuer string
+	// MFAChallengeExpire adalah masa berlaku challenge token di antara langkah password dan kode TOTP
+	MFAChallengeExpire time.Duration
+
+	// OIDCIssuer mengaktifkan login SSO OpenID Connect, kosong berarti hanya login password
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	// OIDCRedirectURL adalah URL /api/v1/oidc/callback yang didaftarkan di identity provider
+	OIDCRedirectURL string
+	OIDCScopes      []string
+	// OIDCGroupsClaim adalah klaim ID token yang berisi grup untuk OIDCRoleMapping
+	OIDCGroupsClaim string
+	// OIDCRoleMapping memetakan grup ke role, diisi "grup=role,grup=role" dengan role tertinggi dulu
+	OIDCRoleMapping []sso.RoleMapping
+	// OIDCDefaultRole adalah role jika tidak ada grup yang cocok, kosong berarti login SSO ditolak
+	OIDCDefaultRole string
+	// OIDCAutoProvision membuat akun saat login SSO pertama jika email belum terdaftar
+	OIDCAutoProvision bool
+	// OIDCStateExpire adalah batas waktu antara membuka halaman login IdP dan callback
+	OIDCStateExpire time.Duration
+
+	// AdminSetupToken adalah token sekali pakai untuk membuat admin pertama lewat API
+	AdminSetupToken       string
+	AdminInvitationExpire time.Duration
+
+	// Mailer adalah pengirim email: "log" (hanya ditulis ke log), "file" (ke MAIL_DIR) atau "smtp"
+	Mailer       string
+	MailFrom     string
+	MailDir      string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	// MailTimeout membatasi lama satu email dikirim di background, termasuk saat shutdown
+	MailTimeout time.Duration
+	// PasswordResetURL adalah halaman frontend untuk reset password; token ditambahkan sebagai
+	// query ?token=. Kosong berarti email hanya berisi token.
+	PasswordResetURL        string
+	PasswordResetExpire     time.Duration
+	EmailVerificationExpire time.Duration
+
+	OverdueCheckInterval time.Duration
+	LoanCodePrefix       string
+	// LoanCodeDateFormat adalah layout tanggal Go pada kode peminjaman, kosong berarti tanpa tanggal
+	LoanCodeDateFormat string
+}
+
+// Default mengembalikan konfigurasi bawaan sebelum env dan file konfigurasi dibaca
+func Default() Config {
+	return Config{
+		Port:                    8080,
+		PublicURL:               "http://localhost:8080",
+		ShutdownTimeout:         time.Second * 30,
+		MigrateOnStart:          true,
+		JWTExpire:               time.Hour * 1,
+		RefreshTokenExpire:      time.Hour * 24 * 30,
+		TokenStore:              "database",
+		TokenPruneInterval:      time.Hour * 1,
+		LoginAttemptStore:       "database",
+		LoginMaxAttempts:        5,
+		LoginMaxAttemptsPerIP:   20,
+		LoginLockout:            time.Minute * 1,
+		LoginMaxLockout:         time.Hour * 1,
+		LoginAttemptWindow:      time.Hour * 1,
+		MFAIssuer:               "Gin-Inventory",
+		MFAChallengeExpire:      time.Minute * 5,
+		OIDCScopes:              []string{"openid", "email", "profile"},
+		OIDCGroupsClaim:         "groups",
+		OIDCDefaultRole:         "user",
+		OIDCAutoProvision:       true,
+		OIDCStateExpire:         time.Minute * 10,
+		AdminInvitationExpire:   time.Hour * 72,
+		Mailer:                  "log",
+		MailFrom:                "noreply@localhost",
+		MailTimeout:             time.Second * 30,
+		MailDir:                 "mail",
+		PasswordResetExpire:     time.Hour * 1,
+		EmailVerificationExpire: time.Hour * 48,
+		OverdueCheckInterval:    time.Hour * 1,
+		LoanCodePrefix:          "IVT",
+		LoanCodeDateFormat:      "20060102",
+	}
+}
+
+// Load membaca .env (jika ada), file konfigurasi dari CONFIG_FILE (jika diisi) dan environment
+// variable, lalu memvalidasi hasilnya. Variabel yang sudah ada di environment tidak ditimpa .env.
+func Load() (Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("failed to load .env: %w", err)
+	}
+
+	file := map[string]string{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	return parse(func(key string) (string, bool) {
+		if value, ok := os.LookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := file[strings.ToLower(key)]
+		return value, ok
+	})
+}
+
+// parse mengisi Config dari lookup, kunci yang tidak ada memakai nilai Default
+func parse(lookup func(key string) (string, bool)) (Config, error) {
+	cfg := Default()
+	var errs []error
+
+	str := func(key string, target *string) {
+		if value, ok := lookup(key); ok {
+			*target = value
+		}
+	}
+	num := func(key string, target *int) {
+		if value, ok := lookup(key); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a number", key))
+				return
+			}
+			*target = n
+		}
+	}
+	boolean := func(key string, target *bool) {
+		if value, ok := lookup(key); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be true or false", key))
+				return
+			}
+			*target = b
+		}
+	}
+	duration := func(key string, target *time.Duration) {
+		if value, ok := lookup(key); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a duration such as 15m or 1h", key))
+				return
+			}
+			*target = d
+		}
+	}
+
+	num("PORT", &cfg.Port)
+	str("PUBLIC_URL", &cfg.PublicURL)
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	duration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	if value, ok := lookup("LOG_LEVEL"); ok {
+		if err := cfg.LogLevel.UnmarshalText([]byte(value)); err != nil {
+			errs = append(errs, errors.New("LOG_LEVEL must be debug, info, warn or error"))
+		}
+	}
+	str("METRICS_TOKEN", &cfg.MetricsToken)
+	str("DATABASE_URL", &cfg.DatabaseURL)
+	boolean("MIGRATE_ON_START", &cfg.MigrateOnStart)
+	str("JWT_SECRET", &cfg.JWTSecret)
+	duration("JWT_EXPIRE", &cfg.JWTExpire)
+	duration("REFRESH_TOKEN_EXPIRE", &cfg.RefreshTokenExpire)
+	str("TOKEN_STORE", &cfg.TokenStore)
+	duration("TOKEN_PRUNE_INTERVAL", &cfg.TokenPruneInterval)
+	str("LOGIN_ATTEMPT_STORE", &cfg.LoginAttemptStore)
+	num("LOGIN_MAX_ATTEMPTS", &cfg.LoginMaxAttempts)
+	num("LOGIN_MAX_ATTEMPTS_PER_IP", &cfg.LoginMaxAttemptsPerIP)
+	duration("LOGIN_LOCKOUT", &cfg.LoginLockout)
+	duration("LOGIN_MAX_LOCKOUT", &cfg.LoginMaxLockout)
+	duration("LOGIN_ATTEMPT_WINDOW", &cfg.LoginAttemptWindow)
+	list := func(key string, target *[]string) {
+		if value, ok := lookup(key); ok {
+			*target = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*target = append(*target, item)
+				}
+			}
+		}
+	}
+	list("TRUSTED_PROXIES", &cfg.TrustedProxies)
+	list("MFA_REQUIRED_ROLES", &cfg.MFARequiredRoles)
+	str("MFA_ISSUER", &cfg.MFAIssuer)
+	duration("MFA_CHALLENGE_EXPIRE", &cfg.MFAChallengeExpire)
+	str("OIDC_ISSUER", &cfg.OIDCIssuer)
+	str("OIDC_CLIENT_ID", &cfg.OIDCClientID)
+	str("OIDC_CLIENT_SECRET", &cfg.OIDCClientSecret)
+	str("OIDC_REDIRECT_URL", &cfg.OIDCRedirectURL)
+	list("OIDC_SCOPES", &cfg.OIDCScopes)
+	str("OIDC_GROUPS_CLAIM", &cfg.OIDCGroupsClaim)
+	if value, ok := lookup("OIDC_ROLE_MAPPING"); ok {
+		mappings, err := sso.ParseRoleMapping(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OIDC_ROLE_MAPPING: %w", err))
+		}
+		cfg.OIDCRoleMapping = mappings
+	}
+	str("OIDC_DEFAULT_ROLE", &cfg.OIDCDefaultRole)
+	boolean("OIDC_AUTO_PROVISION", &cfg.OIDCAutoProvision)
+	duration("OIDC_STATE_EXPIRE", &cfg.OIDCStateExpire)
+	str("ADMIN_SETUP_TOKEN", &cfg.AdminSetupToken)
+	duration("ADMIN_INVITATION_EXPIRE", &cfg.AdminInvitationExpire)
+	str("MAILER", &cfg.Mailer)
+	str("MAIL_FROM", &cfg.MailFrom)
+	str("MAIL_DIR", &cfg.MailDir)
+	str("SMTP_ADDR", &cfg.SMTPAddr)
+	str("SMTP_USERNAME", &cfg.SMTPUsername)
+	str("SMTP_PASSWORD", &cfg.SMTPPassword)
+	duration("MAIL_TIMEOUT", &cfg.MailTimeout)
+	str("PASSWORD_RESET_URL", &cfg.PasswordResetURL)
+	duration("PASSWORD_RESET_EXPIRE", &cfg.PasswordResetExpire)
+	duration("EMAIL_VERIFICATION_EXPIRE", &cfg.EmailVerificationExpire)
+	duration("OVERDUE_CHECK_INTERVAL", &cfg.OverdueCheckInterval)
+	str("LOAN_CODE_PREFIX", &cfg.LoanCodePrefix)
+	str("LOAN_CODE_DATE_FORMAT", &cfg.LoanCodeDateFormat)
+	if cfg.LoanCodeDateFormat == "none" {
+		cfg.LoanCodeDateFormat = ""
+	}
+
+	if len(errs) == 0 {
+		errs = append(errs, cfg.Validate())
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// Validate memeriksa nilai yang tidak boleh kosong atau di luar batas
+func (cfg Config) Validate() error {
+	var errs []error
+	if cfg.JWTSecret == "" {
+		errs = append(errs, errors.New("JWT_SECRET must not be empty"))
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		errs = append(errs, errors.New("PORT must be between 1 and 65535"))
+	}
+	if u, err := url.Parse(cfg.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		errs = append(errs, errors.New("PUBLIC_URL must be an absolute http or https URL without query, for example https://inventory.example.com"))
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR", proxy))
+		}
+	}
+	if cfg.TokenStore != "database" && cfg.TokenStore != "memory" {
+		errs = append(errs, errors.New(`TOKEN_STORE must be "database" or "memory"`))
+	}
+	if cfg.LoginAttemptStore != "database" && cfg.LoginAttemptStore != "memory" {
+		errs = append(errs, errors.New(`LOGIN_ATTEMPT_STORE must be "database" or "memory"`))
+	}
+	if cfg.LoginMaxAttempts < 1 || cfg.LoginMaxAttemptsPerIP < 1 {
+		errs = append(errs, errors.New("LOGIN_MAX_ATTEMPTS and LOGIN_MAX_ATTEMPTS_PER_IP must be at least 1"))
+	}
+	if cfg.LoginLockout <= 0 || cfg.LoginMaxLockout < cfg.LoginLockout || cfg.LoginAttemptWindow <= 0 {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT must be greater than zero and not exceed LOGIN_MAX_LOCKOUT"))
+	}
+	if cfg.JWTExpire <= 0 || cfg.RefreshTokenExpire <= 0 || cfg.AdminInvitationExpire <= 0 || cfg.OverdueCheckInterval <= 0 || cfg.TokenPruneInterval <= 0 || cfg.ShutdownTimeout <= 0 ||
+		cfg.PasswordResetExpire <= 0 || cfg.EmailVerificationExpire <= 0 || cfg.MFAChallengeExpire <= 0 || cfg.OIDCStateExpire <= 0 || cfg.MailTimeout <= 0 {
+		errs = append(errs, errors.New("durations must be greater than zero"))
+	}
+	switch cfg.Mailer {
+	case "log", "file", "smtp":
+	default:
+		errs = append(errs, errors.New(`MAILER must be "log", "file" or "smtp"`))
+	}
+	if cfg.Mailer == "smtp" && cfg.SMTPAddr == "" {
+		errs = append(errs, errors.New("SMTP_ADDR must not be empty when MAILER=smtp"))
+	}
+	if cfg.Mailer == "file" && cfg.MailDir == "" {
+		errs = append(errs, errors.New("MAIL_DIR must not be empty when MAILER=file"))
+	}
+	if cfg.MFAIssuer == "" || strings.Contains(cfg.MFAIssuer, ":") {
+		errs = append(errs, errors.New("MFA_ISSUER must not be empty or contain a colon"))
+	}
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "" {
+			errs = append(errs, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must not be empty when OIDC_ISSUER is set"))
+		}
+		if !contains(cfg.OIDCScopes, "openid") {
+			errs = append(errs, errors.New(`OIDC_SCOPES must include "openid"`))
+		}
+	}
+	if cfg.LoanCodePrefix == "" {
+		errs = append(errs, errors.New("LOAN_CODE_PREFIX must not be empty"))
+	}
+	if _, err := Dialector(cfg.DatabaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("DATABASE_URL: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Addr adalah alamat yang dipakai http server
+func (cfg Config) Addr() string {
+	return fmt.Sprintf(":%d", cfg.Port)
+}
+
+// readFile membaca file konfigurasi YAML atau TOML menjadi pasangan kunci-nilai datar
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
 	if err != nil {
-		log.Fatalf("Error loading .env file")
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
 
-	JWTSecret = os.Getenv("JWT_SECRET")
+	file := map[string]string{}
+	for key, value := range values {
+		file[strings.ToLower(key)] = fmt.Sprint(value)
+	}
+	return file, nil
+}
 
-	dsn := os.Getenv("DATABASE_URL")
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// ConnectDatabase membuka koneksi database sesuai DATABASE_URL
+func ConnectDatabase(cfg Config) *gorm.DB {
+	// Driver dipilih dari skema DATABASE_URL (mysql, postgres, sqlite), kosong berarti SQLite tertanam
+	db, err := OpenDatabase(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	return db
+}
 
-	// AutoMigrate models
-	if err := db.AutoMigrate(&model.User{}, &model.Admin{}, &model.Item{}, &model.Detail{}, &model.Transaction{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+// NewRevocationStore memilih penyimpanan token yang dicabut sesuai TOKEN_STORE
+func NewRevocationStore(cfg Config, db *gorm.DB) store.RevocationStore {
+	if cfg.TokenStore == "memory" {
+		return store.NewMemoryRevocationStore()
 	}
+	return store.NewGormRevocationStore(db)
+}
 
-	DB = db
-	log.Println("Database connected successfully!")
+// NewLoginAttemptStore memilih penyimpanan hitungan login gagal sesuai LOGIN_ATTEMPT_STORE
+func NewLoginAttemptStore(cfg Config, db *gorm.DB) store.LoginAttemptStore {
+	if cfg.LoginAttemptStore == "memory" {
+		return store.NewMemoryLoginAttemptStore()
+	}
+	return store.NewGormLoginAttemptStore(db)
+}
+
+// NewSSOProvider membuat client OpenID Connect sesuai OIDC_*, nil jika OIDC_ISSUER kosong
+func NewSSOProvider(cfg Config) *sso.Provider {
+	if cfg.OIDCIssuer == "" {
+		return nil
+	}
+	return sso.New(sso.Config{
+		Issuer:        cfg.OIDCIssuer,
+		ClientID:      cfg.OIDCClientID,
+		ClientSecret:  cfg.OIDCClientSecret,
+		RedirectURL:   cfg.OIDCRedirectURL,
+		Scopes:        cfg.OIDCScopes,
+		GroupsClaim:   cfg.OIDCGroupsClaim,
+		RoleMapping:   cfg.OIDCRoleMapping,
+		DefaultRole:   cfg.OIDCDefaultRole,
+		AutoProvision: cfg.OIDCAutoProvision,
+		HTTPClient:    &http.Client{Timeout: time.Second * 10},
+	})
+}
+
+// NewLogger membuat logger JSON ke stdout dengan level dari LOG_LEVEL
+func NewLogger(cfg Config) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.LogLevel}))
+}
+
+// NewMailer memilih pengirim email sesuai MAILER
+func NewMailer(cfg Config, logger *slog.Logger) mail.Mailer {
+	switch cfg.Mailer {
+	case "smtp":
+		return mail.SMTPMailer{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+			Timeout:  time.Second * 30,
+		}
+	case "file":
+		return mail.FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	default:
+		return mail.LogMailer{Logger: logger}
+	}
 }
 
-func JWTExpireDuration() time.Duration {
-	return time.Hour * 1
+// PrepareDatabase memastikan skema database cocok dengan binary ini lalu mengisi data awal.
+// Start ditolak jika skema lebih baru dari yang dikenal binary. Migrasi yang tertunda
+// dijalankan otomatis, kecuali MIGRATE_ON_START=false yang mengharuskan `migrate up` manual.
+func PrepareDatabase(cfg Config, db *gorm.DB) {
+	pending, err := migration.Check(db)
+	if errors.Is(err, migration.ErrSchemaTooNew) {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+	if err != nil {
+		log.Fatalf("Failed to check database schema: %v", err)
+	}
+
+	if len(pending) > 0 {
+		if !cfg.MigrateOnStart {
+			log.Fatalf("Database schema has %d pending migration(s), run `migrate up` first", len(pending))
+		}
+		applied, err := migration.Up(db)
+		for _, m := range applied {
+			log.Printf("Applied migration %d (%s)", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	if err := seedRoles(db); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
+
+	log.Println("Database connected successfully!")
 }
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	env := map[string]string{"JWT_SECRET": "secret", "PORT": "9000", "JWT_EXPIRE": "15m", "LOAN_CODE_DATE_FORMAT": "none", "LOG_LEVEL": "debug", "MFA_REQUIRED_ROLES": "admin, auditor"}
+	cfg, err := parse(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cfg.Addr() != ":9000" || cfg.JWTExpire != 15*time.Minute || cfg.LoanCodeDateFormat != "" || cfg.LogLevel != slog.LevelDebug ||
+		len(cfg.MFARequiredRoles) != 2 || cfg.MFARequiredRoles[1] != "auditor" {
+		t.Errorf("values from lookup not applied: %+v", cfg)
+	}
+	if cfg.RefreshTokenExpire != Default().RefreshTokenExpire || cfg.TokenStore != "database" {
+		t.Errorf("missing keys should keep defaults: %+v", cfg)
+	}
+
+	invalid := map[string]map[string]string{
+		"JWT_SECRET":        {"JWT_SECRET": ""},
+		"PORT":              {"JWT_SECRET": "secret", "PORT": "http"},
+		"PUBLIC_URL":        {"JWT_SECRET": "secret", "PUBLIC_URL": "inventory.example.com"},
+		"TRUSTED_PROXIES":   {"JWT_SECRET": "secret", "TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"},
+		"JWT_EXPIRE":        {"JWT_SECRET": "secret", "JWT_EXPIRE": "soon"},
+		"TOKEN_STORE":       {"JWT_SECRET": "secret", "TOKEN_STORE": "redis"},
+		"MIGRATE_ON_START":  {"JWT_SECRET": "secret", "MIGRATE_ON_START": "maybe"},
+		"DATABASE_URL":      {"JWT_SECRET": "secret", "DATABASE_URL": "oracle://localhost"},
+		"LOG_LEVEL":         {"JWT_SECRET": "secret", "LOG_LEVEL": "verbose"},
+		"MAILER":            {"JWT_SECRET": "secret", "MAILER": "sendmail"},
+		"SMTP_ADDR":         {"JWT_SECRET": "secret", "MAILER": "smtp"},
+		"LOGIN_LOCKOUT":     {"JWT_SECRET": "secret", "LOGIN_LOCKOUT": "2h", "LOGIN_MAX_LOCKOUT": "1h"},
+		"OIDC_CLIENT_ID":    {"JWT_SECRET": "secret", "OIDC_ISSUER": "https://sso.example.com"},
+		"OIDC_ROLE_MAPPING": {"JWT_SECRET": "secret", "OIDC_ROLE_MAPPING": "admins"},
+	}
+	for key, env := range invalid {
+		_, err := parse(func(k string) (string, bool) {
+			value, ok := env[k]
+			return value, ok
+		})
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error mentioning %s, got %v", key, err)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "port: 9000\njwt_secret: from-file\ntoken_store: memory\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Jalankan dari direktori tanpa .env
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "9100")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cfg.Port != 9100 {
+		t.Errorf("environment should override config file, got port %d", cfg.Port)
+	}
+	if cfg.JWTSecret != "from-file" || cfg.TokenStore != "memory" {
+		t.Errorf("config file values not applied: %+v", cfg)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": "jwt_secret: abc\nport: 9000\nmigrate_on_start: false\n",
+		"config.toml": "jwt_secret = \"abc\"\nport = 9000\nmigrate_on_start = false\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		values, err := readFile(path)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+			continue
+		}
+		if values["jwt_secret"] != "abc" || values["port"] != "9000" || values["migrate_on_start"] != "false" {
+			t.Errorf("%s: unexpected values %v", name, values)
+		}
+	}
+
+	json := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(json, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFile(json); err == nil {
+		t.Error("expected error for unsupported extension")
+	}
+}
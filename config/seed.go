@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+)
+
+// seedRoles memastikan semua permission ada dan role bawaan dibuat dengan permission awalnya.
+// Role yang sudah ada tidak diubah agar perubahan permission dari admin tidak tertimpa.
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		permissions := map[string]model.Permission{}
+		for _, names := range model.DefaultRolePermissions {
+			for _, name := range names {
+				if _, ok := permissions[name]; ok {
+					continue
+				}
+				permission := model.Permission{Name: name}
+				if err := tx.Where("name = ?", name).FirstOrCreate(&permission).Error; err != nil {
+					return err
+				}
+				permissions[name] = permission
+			}
+		}
+
+		for roleName, names := range model.DefaultRolePermissions {
+			var role model.Role
+			err := tx.Where("name = ?", roleName).First(&role).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			role = model.Role{Name: roleName}
+			for _, name := range names {
+				role.Permissions = append(role.Permissions, permissions[name])
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
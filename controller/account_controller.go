@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/helper"
+	"Gin-Inventory/mail"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+	"Gin-Inventory/scheduler"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ForgotPasswordHandler mengirim tautan reset password ke email akun. Jawabannya selalu sama,
+// baik email terdaftar maupun tidak, agar endpoint ini tidak bisa dipakai menebak akun. Token
+// dibuat dan email dikirim di background lewat tasks sehingga lama jawabannya juga tidak
+// membedakan keduanya, dan shutdown menunggu email yang sedang dikirim paling lama mailTimeout.
+func ForgotPasswordHandler(users repository.UserRepository, mailer mail.Mailer, tasks *scheduler.Scheduler, mailTimeout, ttl time.Duration, resetURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Memvalidasi input dengan Middleware ValidateInput.
+		forgotData, valid := helper.ValidationHelper(c, middleware.ForgotPasswordSchema{})
+		if !valid {
+			return
+		}
+
+		user, err := users.FindByEmail(forgotData.Email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			c.Error(err)
+			return
+		}
+
+		// Akun yang hanya boleh login lewat SSO tidak dikirimi tautan reset
+		if err == nil && !user.PasswordLoginDisabled {
+			logger := middleware.Logger(c)
+			tasks.Go(mailTimeout, func(ctx context.Context) {
+				sendPasswordResetEmail(ctx, logger, users, mailer, ttl, resetURL, user)
+			})
+		}
+
+		response.Message(c, 202, "If the email is registered, a password reset link has been sent", nil)
+	}
+}
+
+// sendPasswordResetEmail membuat token reset dan mengirim tautannya. Dijalankan di luar request,
+// jadi kegagalan hanya dicatat dan ctx berasal dari tasks, bukan dari request yang sudah selesai.
+func sendPasswordResetEmail(ctx context.Context, logger *slog.Logger, users repository.UserRepository, mailer mail.Mailer, ttl time.Duration, resetURL string, user model.User) {
+	token, err := createUserToken(users, user.ID, model.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		logger.Error("Failed to create password reset token", "user_id", user.ID, "error", err.Error())
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nSomeone requested a password reset for your account.\n\n", user.Name)
+	if resetURL != "" {
+		body += fmt.Sprintf("Open this link to choose a new password:\n%s\n\n", withToken(resetURL, token))
+	} else {
+		body += fmt.Sprintf("Send this token with your new password to POST /api/v1/password/reset:\n%s\n\n", token)
+	}
+	body += fmt.Sprintf("The link expires in %s. If you did not request this, you can ignore this email.\n", ttl)
+
+	msg := mail.Message{To: user.Email, Subject: "Reset your password", Body: body}
+	if err := mailer.Send(ctx, msg); err != nil {
+		logger.Error("Failed to send password reset email", "user_id", user.ID, "error", err.Error())
+	}
+}
+
+// ResetPasswordHandler mengganti password dengan token dari email. Token hanya bisa dipakai
+// sekali, dan semua sesi akun dicabut sehingga perangkat lain harus login ulang.
+func ResetPasswordHandler(users repository.UserRepository, auth middleware.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Memvalidasi input dengan Middleware ValidateInput.
+		resetData, valid := helper.ValidationHelper(c, middleware.ResetPasswordSchema{})
+		if !valid {
+			return
+		}
+
+		token, ok := findUserToken(c, users, model.TokenPurposePasswordReset, resetData.Token)
+		if !ok {
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(resetData.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to hash password: %w", err))
+			return
+		}
+
+		if err := users.ResetPassword(token, string(hashedPassword)); err != nil {
+			c.Error(userTokenError(err))
+			return
+		}
+
+		user, err := users.Find(token.UserID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if err := middleware.RevokeAllTokens(auth, user.ID); err != nil {
+			c.Error(fmt.Errorf("failed to invalidate sessions: %w", err))
+			return
+		}
+
+		response.Message(c, 200, "Password reset successfully", nil)
+	}
+}
+
+// VerifyEmailHandler memverifikasi email dengan token ?token= dari tautan verifikasi
+func VerifyEmailHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := findUserToken(c, users, model.TokenPurposeEmailVerification, c.Query("token"))
+		if !ok {
+			return
+		}
+
+		if err := users.VerifyEmail(token); err != nil {
+			c.Error(userTokenError(err))
+			return
+		}
+
+		response.Message(c, 200, "Email verified successfully", nil)
+	}
+}
+
+// ResendVerificationHandler mengirim ulang tautan verifikasi ke email akun yang sedang login
+func ResendVerificationHandler(users repository.UserRepository, mailer mail.Mailer, ttl time.Duration, publicURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
+
+		user, err := users.Find(currentUserID)
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Email is already verified"))
+			return
+		}
+
+		if err := sendVerificationEmail(c, users, mailer, ttl, publicURL, user); err != nil {
+			c.Error(fmt.Errorf("failed to send verification email: %w", err))
+			return
+		}
+
+		response.Message(c, 202, "Verification email sent", nil)
+	}
+}
+
+// sendVerificationEmail membuat token verifikasi baru dan mengirim tautannya ke email akun.
+// Tautan dibuat dari PUBLIC_URL, bukan header Host yang bisa diisi bebas oleh klien.
+func sendVerificationEmail(c *gin.Context, users repository.UserRepository, mailer mail.Mailer, ttl time.Duration, publicURL string, user model.User) error {
+	token, err := createUserToken(users, user.ID, model.TokenPurposeEmailVerification, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := withToken(publicURL+"/api/v1/email/verify", token)
+	body := fmt.Sprintf("Hi %s,\n\nOpen this link to verify your email address:\n%s\n\nThe link expires in %s.\n", user.Name, link, ttl)
+	return mailer.Send(c.Request.Context(), mail.Message{To: user.Email, Subject: "Verify your email address", Body: body})
+}
+
+// createUserToken menyimpan hash token email baru dan mengembalikan token aslinya
+func createUserToken(users repository.UserRepository, userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := middleware.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	userToken := model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: middleware.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := users.CreateToken(&userToken); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findUserToken mencari token email yang masih berlaku
+func findUserToken(c *gin.Context, users repository.UserRepository, purpose, token string) (model.UserToken, bool) {
+	if token == "" {
+		c.Error(apperror.New(400, apperror.CodeInvalidToken, "Token is required"))
+		return model.UserToken{}, false
+	}
+
+	userToken, err := users.FindToken(purpose, middleware.HashToken(token))
+	if err == nil && (userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt)) {
+		err = repository.ErrTokenUsed
+	}
+	if err != nil {
+		c.Error(userTokenError(err))
+		return model.UserToken{}, false
+	}
+	return userToken, true
+}
+
+// userTokenError menerjemahkan token yang tidak ada, kedaluwarsa atau sudah dipakai menjadi 400
+func userTokenError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrTokenUsed) {
+		return apperror.New(400, apperror.CodeInvalidToken, "Token is invalid, has expired or has already been used")
+	}
+	return err
+}
+
+// withToken menambahkan query token ke link
+func withToken(link, token string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -1,159 +1,287 @@
 package controller
 
 import (
-	"Gin-Inventory/config"
+	"Gin-Inventory/apperror"
 	"Gin-Inventory/helper"
 	"Gin-Inventory/middleware"
 	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateAdminHandler(c *gin.Context) {
-	// Memvalidasi input dengan Middleware ValidateInput.
-	adminData, valid := helper.ValidationHelper(c, middleware.UserSchema{})
-	if !valid {
-		return
+// CreateAdminHandler hanya dipakai untuk membuat admin pertama (bootstrap) dengan setup token
+// dari env ADMIN_SETUP_TOKEN. Setelah ada admin, admin baru hanya bisa dibuat lewat undangan.
+func CreateAdminHandler(users repository.UserRepository, setupToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		headerToken := c.GetHeader("X-Setup-Token")
+		if setupToken == "" || subtle.ConstantTimeCompare([]byte(headerToken), []byte(setupToken)) != 1 {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: Admin accounts can only be created through an invitation"))
+			return
+		}
+
+		// Memvalidasi input dengan Middleware ValidateInput.
+		adminData, valid := helper.ValidationHelper(c, middleware.UserSchema{})
+		if !valid {
+			return
+		}
+
+		newAdmin, err := CreateAdminAccount(users, adminData)
+		if errors.Is(err, repository.ErrSetupCompleted) {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: Setup has already been completed"))
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 201, "Admin created successfully", newAdmin.ToMap())
+	}
+}
+
+// CreateAdminAccount membuat admin pertama, dipakai oleh setup token dan CLI. Gagal dengan
+// repository.ErrSetupCompleted jika sudah ada admin.
+func CreateAdminAccount(users repository.UserRepository, adminData middleware.UserSchema) (model.User, error) {
+	newAdmin, err := newAdminAccount(adminData)
+	if err != nil {
+		return model.User{}, err
 	}
 
+	if err := users.CreateFirstAdmin(&newAdmin); err != nil {
+		return model.User{}, err
+	}
+
+	return newAdmin, nil
+}
+
+// newAdminAccount menyiapkan akun admin dengan password yang sudah di-hash. Email admin dianggap
+// terverifikasi karena dibuat lewat setup token, CLI atau undangan yang dikirim ke email tersebut.
+func newAdminAccount(adminData middleware.UserSchema) (model.User, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(adminData.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to hash password"})
-		return
+		return model.User{}, fmt.Errorf("failed to hash password")
 	}
 
-	adminData.Password = string(hashedPassword)
-
-	newAdmin := model.Admin{
+	newAdmin := model.User{
 		Name:     adminData.Name,
 		Email:    adminData.Email,
-		Password: adminData.Password,
-		Role:     "admin", // Atur default role
-	}
-
-	if err := config.DB.Create(&newAdmin).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		Password: string(hashedPassword),
+		Role:     model.RoleAdmin, // Atur role admin
 	}
+	now := time.Now()
+	newAdmin.EmailVerifiedAt = &now
 
-	c.JSON(201, gin.H{"message": "Admin created successfully", "admin": newAdmin.ToMap()})
+	return newAdmin, nil
 }
 
-func GetAllAdminHandler(c *gin.Context) {
-	var admin []model.Admin
-	if err := config.DB.Find(&admin).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+// CreateAdminInvitationHandler membuat tautan undangan admin yang kedaluwarsa
+func CreateAdminInvitationHandler(users repository.UserRepository, ttl time.Duration, publicURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
+
+		// Memvalidasi input dengan Middleware ValidateInput.
+		invitationData, valid := helper.ValidationHelper(c, middleware.InvitationSchema{})
+		if !valid {
+			return
+		}
+
+		// Pastikan email belum terdaftar
+		registered, err := users.EmailExists(invitationData.Email)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to check email: %w", err))
+			return
+		}
+		if registered {
+			c.Error(apperror.New(409, apperror.CodeEmailTaken, "Email is already registered"))
+			return
+		}
+
+		token, err := middleware.GenerateRandomToken(32)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate invitation token: %w", err))
+			return
+		}
+
+		invitation := model.AdminInvitation{
+			Email:       invitationData.Email,
+			TokenHash:   middleware.HashToken(token),
+			InvitedByID: currentUserID,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		if err := users.CreateInvitation(&invitation); err != nil {
+			c.Error(err)
+			return
+		}
+
+		link := fmt.Sprintf("%s/api/v1/admin/invitation/%s", publicURL, token)
+
+		response.Message(c, 201, "Invitation created successfully", gin.H{
+			"invitation": invitation.ToMap(),
+			"token":      token,
+			"link":       link,
+		})
 	}
-	c.JSON(200, gin.H{"admin": model.AdminsToMap(admin)})
 }
 
-func GetAdminHandler(c *gin.Context) {
-	id := c.Param("id")
+// AcceptAdminInvitationHandler membuat akun admin dari undangan yang masih berlaku
+func AcceptAdminInvitationHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invitation, err := users.FindInvitation(middleware.HashToken(c.Param("token")))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeInvitationMissing, "Invitation not found"))
+			return
+		}
 
-	// handle role
-	currentUserID, _, valid := helper.CheckUserRoleAndID(c, "admin")
-	if !valid {
-		return
-	}
+		if invitation.UsedAt != nil || time.Now().After(invitation.ExpiresAt) {
+			c.Error(apperror.New(400, apperror.CodeInvitationInvalid, "Invitation has expired or has already been used"))
+			return
+		}
 
-	// Pastikan admin ada
-	var admin model.Admin
-	if err := config.DB.First(&admin, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Admin not found"})
-		return
-	}
+		// Memvalidasi input dengan Middleware ValidateInput.
+		adminData, valid := helper.ValidationHelper(c, middleware.UserSchema{})
+		if !valid {
+			return
+		}
 
-	// Pastikan admin hanya bisa mengakses datanya sendiri
-	if fmt.Sprintf("%v", currentUserID) != id {
-		c.JSON(403, gin.H{"error": "Forbidden: You can only access your own data"})
-		return
-	}
+		if !strings.EqualFold(adminData.Email, invitation.Email) {
+			c.Error(apperror.New(400, apperror.CodeBadRequest, "Email does not match the invitation"))
+			return
+		}
+
+		newAdmin, err := newAdminAccount(adminData)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		err = users.AcceptInvitation(invitation, &newAdmin)
+		if errors.Is(err, repository.ErrInvitationUsed) {
+			c.Error(apperror.New(400, apperror.CodeInvitationInvalid, "Invitation has expired or has already been used"))
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-	c.JSON(200, admin.ToMap())
+		response.Message(c, 201, "Admin created successfully", newAdmin.ToMap())
+	}
 }
 
-func UpdateAdminHandler(c *gin.Context) {
-	id := c.Param("id")
+func GetAllAdminHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := helper.ParseList(c, repository.UserList)
+		if !ok {
+			return
+		}
+		admins, total, err := users.List(model.RoleAdmin, query)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(200, helper.NewListResult(c, query, total).Response(model.UsersToMap(admins)))
+	}
+}
 
-	// handle role
-	currentUserID, _, valid := helper.CheckUserRoleAndID(c, "admin")
+// findOwnAdmin memastikan admin :id ada dan merupakan akun yang sedang login
+func findOwnAdmin(c *gin.Context, users repository.UserRepository) (model.User, bool) {
+	currentUserID, valid := helper.CurrentUserID(c)
 	if !valid {
-		return
+		return model.User{}, false
 	}
 
 	// Pastikan admin ada
-	var admin model.Admin
-	if err := config.DB.First(&admin, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Admin not found"})
-		return
+	admin, err := users.Find(helper.ParamID(c, "id"))
+	if err == nil && admin.Role != model.RoleAdmin {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		c.Error(apperror.NotFound(err, apperror.CodeAdminNotFound, "Admin not found"))
+		return model.User{}, false
 	}
 
 	// Pastikan admin hanya bisa mengakses datanya sendiri
-	if fmt.Sprintf("%v", currentUserID) != id {
-		c.JSON(403, gin.H{"error": "Forbidden: You can only access your own data"})
-		return
+	if admin.ID != currentUserID {
+		c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: You can only access your own data"))
+		return model.User{}, false
 	}
 
-	// Memvalidasi input dengan Middleware ValidateInput.
-	updatedData, valid := helper.ValidationHelper(c, middleware.UpdateSchema{})
-	if !valid {
-		return
-	}
+	return admin, true
+}
 
-	// Perbarui field yang diberikan
-	if updatedData.Name != "" {
-		admin.Name = updatedData.Name
-	}
-	if updatedData.Email != "" {
-		admin.Email = updatedData.Email
-	}
-	// Jika password diperbarui, hash terlebih dahulu
-	if updatedData.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedData.Password), bcrypt.DefaultCost)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to hash password"})
+func GetAdminHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, ok := findOwnAdmin(c, users)
+		if !ok {
 			return
 		}
-		admin.Password = string(hashedPassword)
-	}
 
-	if err := config.DB.Save(&admin).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		response.JSON(c, 200, admin.ToMap())
 	}
-
-	c.JSON(200, gin.H{"message": "Admin updated successfully", "admin": admin.ToMap()})
 }
 
-func DeleteAdminHandler(c *gin.Context) {
-	id := c.Param("id")
+func UpdateAdminHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, ok := findOwnAdmin(c, users)
+		if !ok {
+			return
+		}
 
-	// handle role
-	currentUserID, _, valid := helper.CheckUserRoleAndID(c, "admin")
-	if !valid {
-		return
-	}
+		// Memvalidasi input dengan Middleware ValidateInput.
+		updatedData, valid := helper.ValidationHelper(c, middleware.UpdateSchema{})
+		if !valid {
+			return
+		}
 
-	// Pastikan admin ada
-	var admin model.Admin
-	if err := config.DB.First(&admin, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Admin not found"})
-		return
-	}
+		// Perbarui field yang diberikan
+		if updatedData.Name != "" {
+			admin.Name = updatedData.Name
+		}
+		if updatedData.Email != "" {
+			admin.Email = updatedData.Email
+		}
+		// Jika password diperbarui, hash terlebih dahulu
+		if updatedData.Password != "" {
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedData.Password), bcrypt.DefaultCost)
+			if err != nil {
+				c.Error(fmt.Errorf("failed to hash password: %w", err))
+				return
+			}
+			admin.Password = string(hashedPassword)
+		}
 
-	// Pastikan admin hanya bisa mengakses datanya sendiri
-	if fmt.Sprintf("%v", currentUserID) != id {
-		c.JSON(403, gin.H{"error": "Forbidden: You can only access your own data"})
-		return
-	}
+		if err := users.Save(&admin); err != nil {
+			c.Error(err)
+			return
+		}
 
-	if err := config.DB.Unscoped().Delete(&admin).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		response.Message(c, 200, "Admin updated successfully", admin.ToMap())
 	}
+}
 
-	c.JSON(200, gin.H{"message": "Admin deleted successfully"})
+func DeleteAdminHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, ok := findOwnAdmin(c, users)
+		if !ok {
+			return
+		}
+
+		if err := users.Delete(&admin, admin.ID); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "Admin deleted successfully", nil)
+	}
 }
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/helper"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIKeysHandler menampilkan semua API key beserta waktu terakhir dipakai, tanpa key-nya
+func GetAPIKeysHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := helper.ParseList(c, repository.APIKeyList)
+		if !ok {
+			return
+		}
+		keys, total, err := users.APIKeys(query)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(200, helper.NewListResult(c, query, total).Response(model.APIKeysToMap(keys)))
+	}
+}
+
+// CreateAPIKeyHandler membuat API key milik akun yang sedang login. Scope harus termasuk permission
+// role akun tersebut, dan role yang wajib MFA tidak bisa memberi scope model.MFAOnlyPermissions
+// karena key tidak punya faktor kedua. Key lengkap hanya ditampilkan sekali di response ini.
+func CreateAPIKeyHandler(users repository.UserRepository, mfaRequiredRoles []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
+
+		// Memvalidasi input dengan Middleware ValidateInput.
+		keyData, valid := helper.ValidationHelper(c, middleware.APIKeySchema{})
+		if !valid {
+			return
+		}
+
+		owner, err := users.Find(currentUserID)
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+
+		rolePermissions, err := users.RolePermissions(owner.Role)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to load permissions: %w", err))
+			return
+		}
+		scopes := map[string]bool{}
+		for _, scope := range keyData.Scopes {
+			if !middleware.Contains(rolePermissions, scope) {
+				c.Error(apperror.New(400, apperror.CodeValidationFailed, fmt.Sprintf("Scope '%s' is not a permission of role %s", scope, owner.Role)))
+				return
+			}
+			if middleware.Contains(mfaRequiredRoles, owner.Role) && middleware.Contains(model.MFAOnlyPermissions, scope) {
+				c.Error(apperror.New(400, apperror.CodeValidationFailed, fmt.Sprintf("Scope '%s' requires two-factor authentication and cannot be granted to an API key of role %s", scope, owner.Role)))
+				return
+			}
+			scopes[scope] = true
+		}
+		scopeList := make([]string, 0, len(scopes))
+		for scope := range scopes {
+			scopeList = append(scopeList, scope)
+		}
+		sort.Strings(scopeList)
+
+		if keyData.ExpiresAt != nil && !keyData.ExpiresAt.After(time.Now()) {
+			c.Error(apperror.New(400, apperror.CodeInvalidDate, "Field 'expires_at' must be in the future"))
+			return
+		}
+
+		key, prefix, err := middleware.GenerateAPIKey()
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate API key: %w", err))
+			return
+		}
+
+		apiKey := model.APIKey{
+			Name:        keyData.Name,
+			Prefix:      prefix,
+			KeyHash:     middleware.HashToken(key),
+			UserID:      owner.ID,
+			Scopes:      strings.Join(scopeList, " "),
+			CreatedByID: currentUserID,
+			ExpiresAt:   keyData.ExpiresAt,
+		}
+		if err := users.CreateAPIKey(&apiKey); err != nil {
+			c.Error(fmt.Errorf("failed to create API key: %w", err))
+			return
+		}
+
+		result := apiKey.ToMap()
+		result["key"] = key
+		response.Message(c, 201, "API key created successfully, store the key now because it will not be shown again", result)
+	}
+}
+
+// RevokeAPIKeyHandler mencabut API key sehingga langsung tidak bisa dipakai lagi
+func RevokeAPIKeyHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, err := users.FindAPIKey(helper.ParamID(c, "id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeNotFound, "API key not found"))
+			return
+		}
+
+		if err := users.RevokeAPIKey(&apiKey); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "API key revoked successfully", apiKey.ToMap())
+	}
+}
@@ -1,345 +1,319 @@
 package controller
 
 import (
-	"Gin-Inventory/config"
+	"Gin-Inventory/apperror"
 	"Gin-Inventory/helper"
+	"Gin-Inventory/loan"
 	"Gin-Inventory/middleware"
 	"Gin-Inventory/model"
-	"fmt"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateDetailHandler(c *gin.Context) {
-	// handle role
-	currentUserID, _, valid := helper.CheckUserRoleAndID(c, "user")
-	if !valid {
-		return
-	}
-
-	// Memvalidasi input dengan Middleware ValidateInput.
-	detailData, valid := helper.ValidationHelper(c, middleware.DetailSchema{})
-	if !valid {
-		return
-	}
+func CreateDetailHandler(loans repository.LoanRepository, codes loan.CodeGenerator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
 
-	// Cari transaksi milik current_user dengan status 'draft'
-	var transactions []model.Transaction
-	if err := config.DB.Where("user_id = ? AND status = ?", currentUserID, "draft").Find(&transactions).Error; err != nil || len(transactions) == 0 {
-		c.JSON(404, gin.H{"error": "No draft transaction found for the current user"})
-		return
-	}
+		// Memvalidasi input dengan Middleware ValidateInput.
+		detailData, valid := helper.ValidationHelper(c, middleware.DetailSchema{})
+		if !valid {
+			return
+		}
 
-	// Konversi string "Out" dan "Entry" ke time.Time
-	var outTime, entryTime time.Time
-	var err error
-	if detailData.Out != "" {
-		outTime, err = time.Parse("2006-01-02", detailData.Out)
+		// Cari transaksi milik current_user dengan status 'draft'
+		transactions, err := loans.DraftCarts(currentUserID)
 		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid date format for Out"})
+			c.Error(err)
+			return
+		}
+		if len(transactions) == 0 {
+			c.Error(apperror.New(404, apperror.CodeCartNotFound, "No draft transaction found for the current user"))
 			return
 		}
-	}
 
-	if detailData.Entry != "" {
-		entryTime, err = time.Parse("2006-01-02", detailData.Entry)
+		// Konversi string "Out" dan "Entry" ke time.Time
+		var outTime, entryTime time.Time
+		if detailData.Out != "" {
+			outTime, err = time.Parse("2006-01-02", detailData.Out)
+			if err != nil {
+				c.Error(apperror.New(400, apperror.CodeInvalidDate, "Invalid date format for Out"))
+				return
+			}
+		}
+
+		if detailData.Entry != "" {
+			entryTime, err = time.Parse("2006-01-02", detailData.Entry)
+			if err != nil {
+				c.Error(apperror.New(400, apperror.CodeInvalidDate, "Invalid date format for Entry"))
+				return
+			}
+		}
+
+		if !outTime.IsZero() && !entryTime.IsZero() && entryTime.Before(outTime) {
+			c.Error(apperror.New(400, apperror.CodeInvalidDate, "Entry date must not be before Out date"))
+			return
+		}
+
+		from, to := loanRange(outTime, entryTime)
+		newDetail := model.Detail{
+			Out:    outTime,
+			Entry:  entryTime,
+			Status: loan.StatusPending,
+		}
+		updatedTransactions, err := loans.Submit(transactions, &newDetail, codes, from, to)
 		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid date format for Entry"})
+			c.Error(err)
 			return
 		}
-	}
 
-	// Buat detail baru
-	autoGeneratedCode := fmt.Sprintf("ivt%s%d", time.Now().Format("0405"), currentUserID)
-	newDetail := model.Detail{
-		Code:   autoGeneratedCode,
-		Out:    outTime,
-		Entry:  entryTime,
-		Status: "pending",
+		// Kirim respons setelah semua transaksi diproses
+		response.Message(c, 201, "Detail created successfully", gin.H{
+			"detail":       newDetail.ToMap(),
+			"transactions": updatedTransactions,
+		})
 	}
+}
 
-	if err := config.DB.Create(&newDetail).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
+func GetAllDetailHandler(loans repository.LoanRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
 
-	// Loop melalui transaksi untuk memperbarui masing-masing
-	var updatedTransactions []model.Transaction
-	for _, trx := range transactions {
-		// Perbarui setiap transaksi dengan DetailID baru
-		trx.DetailID = &newDetail.ID
-		trx.Status = "pending" // Ubah status
-		if err := config.DB.Save(&trx).Error; err != nil {
-			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to update transaction ID %d", trx.ID)})
+		query, ok := helper.ParseList(c, repository.DetailList)
+		if !ok {
 			return
 		}
-		updatedTransactions = append(updatedTransactions, trx)
-	}
 
-	// Kirim respons setelah semua transaksi diproses
-	c.JSON(201, gin.H{
-		"message":      "Detail created successfully",
-		"detail":       newDetail.ToMap(),
-		"transactions": updatedTransactions,
-	})
-}
+		// Tanpa permission loan:manage, hanya tampilkan detail miliknya
+		var filter repository.DetailFilter
+		if !middleware.HasPermission(c, model.PermissionLoanManage) {
+			filter.UserID = currentUserID
+		}
 
-func GetAllDetailHandler(c *gin.Context) {
-	// handle role
-	currentUserID, role, valid := helper.CheckUserRoleAndID(c, "user", "admin")
-	if !valid {
-		return
-	}
+		// Filter ?overdue=true hanya menampilkan peminjaman yang terlambat
+		switch c.Query("overdue") {
+		case "true":
+			overdue := true
+			filter.Overdue = &overdue
+		case "false":
+			overdue := false
+			filter.Overdue = &overdue
+		}
 
-	var detail []struct {
-		ID        uint      `json:"detail_id"`
-		Code      string    `json:"code"`
-		User      string    `json:"user"`
-		Out       time.Time `json:"out"`
-		Entry     time.Time `json:"entry"`
-		Status    string    `json:"status"`
-		Quantity  int       `json:"quantity"`
-		ItemName  string    `json:"item_name"`
-		CreatedAt string    `json:"created_at"`
-		UpdatedAt string    `json:"updated_at"`
-	}
+		details, total, err := loans.Details(filter, query)
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-	query := config.DB.Table("detail").
-		Select(`user.name AS user, detail.id, detail.code, detail.out, detail.entry, detail.status, detail.created_at,
-		detail.updated_at, transaction.quantity, item.name AS item_name`).
-		Joins("LEFT JOIN transaction ON transaction.detail_id = detail.id").
-		Joins("LEFT JOIN item ON item.id = transaction.item_id").
-		Joins("LEFT JOIN user ON user.id = transaction.user_id")
-
-	if role == "user" {
-		query = query.Where("transaction.user_id = ?", currentUserID)
-	} else if role != "admin" {
-		c.JSON(403, gin.H{"error": "Forbidden: Invalid role"})
-		return
+		c.JSON(200, helper.NewListResult(c, query, total).Response(details))
 	}
+}
 
-	if err := query.Scan(&detail).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+func GetDetailHandler(loans repository.LoanRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		renderDetail(c, loans, helper.ParamID(c, "detail_id"))
 	}
+}
 
-	// Periksa apakah detail ditemukan
-	if len(detail) == 0 {
-		c.JSON(404, gin.H{"error": "Detail not found"})
-		return
-	}
+// GetDetailByCodeHandler mencari detail berdasarkan kode peminjaman, misalnya IVT-20261017-0001-5
+func GetDetailByCodeHandler(loans repository.LoanRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		detail, err := loans.FindDetailByCode(strings.TrimSpace(c.Param("code")))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeDetailNotFound, "Detail not found"))
+			return
+		}
 
-	c.JSON(200, gin.H{"detail": detail})
+		renderDetail(c, loans, detail.ID)
+	}
 }
 
-func GetDetailHandler(c *gin.Context) {
-	detail_id := c.Param("detail_id")
-
-	// handle role
-	currentUserID, role, valid := helper.CheckUserRoleAndID(c, "user", "admin")
+func renderDetail(c *gin.Context, loans repository.LoanRepository, detailID uint) {
+	currentUserID, valid := helper.CurrentUserID(c)
 	if !valid {
 		return
 	}
 
-	// Jika role adalah user, pastikan detail miliknya
-	if role == "user" {
-		var transaction model.Transaction
-		if err := config.DB.Where("detail_id = ? AND user_id = ?", detail_id, currentUserID).First(&transaction).Error; err != nil {
-			c.JSON(403, gin.H{"error": "Forbidden: You can only delete your own detail"})
+	// Tanpa permission loan:manage, pastikan detail miliknya
+	if !middleware.HasPermission(c, model.PermissionLoanManage) {
+		if owns, err := loans.OwnsDetail(detailID, currentUserID); err != nil || !owns {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: You can only view your own detail"))
 			return
 		}
 	}
 
-	// Definisikan struktur untuk hasil query
-	var detail struct {
-		Code     string    `json:"code"`
-		User     string    `json:"user"`
-		Out      time.Time `json:"out"`
-		Entry    time.Time `json:"entry"`
-		Status   string    `json:"status"`
-		Quantity int       `json:"quantity"`
-		ItemName string    `json:"item_name"`
-	}
-
-	// Query dengan join untuk mendapatkan data yang dibutuhkan
-	err := config.DB.Table("detail").
-		Select("user.name AS user, detail.code, detail.out, detail.entry, detail.status, transaction.quantity, item.name AS item_name").
-		Joins("LEFT JOIN transaction ON transaction.detail_id = detail.id").
-		Joins("LEFT JOIN item ON item.id = transaction.item_id").
-		Joins("LEFT JOIN user ON user.id = transaction.user_id").
-		Where("detail.id = ?", detail_id).
-		Scan(&detail).Error
-
 	// Periksa apakah detail ditemukan
-	if err != nil || (detail.Code == "" && detail.Status == "" && detail.ItemName == "") {
-		c.JSON(404, gin.H{"error": "Detail not found"})
+	detail, err := loans.View(detailID)
+	if err != nil {
+		c.Error(apperror.NotFound(err, apperror.CodeDetailNotFound, "Detail not found"))
 		return
 	}
 
-	c.JSON(200, gin.H{"detail": detail})
+	response.JSON(c, 200, detail)
 }
 
-func UpdateDetailHandler(c *gin.Context) {
-	detail_id := c.Param("detail_id")
-
-	// handle role
-	currentUserID, role, valid := helper.CheckUserRoleAndID(c, "user", "admin")
-	if !valid {
-		return
+// loanRange menentukan rentang yang diperiksa ketersediaannya: tanpa Out dihitung mulai hari ini,
+// tanpa Entry hanya hari Out
+func loanRange(out, entry time.Time) (time.Time, time.Time) {
+	from := out
+	if from.IsZero() {
+		from = time.Now()
+	}
+	to := entry
+	if to.IsZero() {
+		to = from
 	}
+	return from, to
+}
 
-	// Jika role adalah user, pastikan detail miliknya
-	if role == "user" {
-		var transaction model.Transaction
-		if err := config.DB.Where("detail_id = ? AND user_id = ?", detail_id, currentUserID).First(&transaction).Error; err != nil {
-			c.JSON(403, gin.H{"error": "Forbidden: You can only delete your own detail"})
+func UpdateDetailHandler(loans repository.LoanRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		detailID := helper.ParamID(c, "detail_id")
+
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
 			return
 		}
-	}
 
-	// Pastikan detail ada
-	var detail model.Detail
-	if err := config.DB.Preload("Transactions").First(&detail, detail_id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Detail not found"})
-		return
-	}
+		// Tanpa permission loan:manage, pastikan detail miliknya
+		if !middleware.HasPermission(c, model.PermissionLoanManage) {
+			if owns, err := loans.OwnsDetail(detailID, currentUserID); err != nil || !owns {
+				c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: You can only update your own detail"))
+				return
+			}
+		}
 
-	// Memvalidasi input dengan Middleware ValidateInput.
-	updatedData, valid := helper.ValidationHelper(c, middleware.DetailSchema{})
-	if !valid {
-		return
-	}
+		// Pastikan detail ada
+		detail, err := loans.FindDetail(detailID)
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeDetailNotFound, "Detail not found"))
+			return
+		}
 
-	// Menyimpan status sebelumnya
-	previousStatus := detail.Status
+		// Memvalidasi input dengan Middleware ValidateInput.
+		updatedData, valid := helper.ValidationHelper(c, middleware.DetailSchema{})
+		if !valid {
+			return
+		}
+
+		// Tanggal hanya bisa diubah selama status masih pending, status diubah lewat endpoint aksi
+		if detail.Status != loan.StatusPending {
+			c.Error(apperror.New(400, apperror.CodeInvalidStatus, "Detail can only be updated if the status is 'pending'"))
+			return
+		}
 
-	// Jika user dan status adalah pending, izinkan perubahan Out dan Entry
-	if role == "user" && detail.Status == "pending" {
 		if updatedData.Out != "" {
-			detail.Out, _ = time.Parse("2006-01-02", updatedData.Out)
+			detail.Out, err = time.Parse("2006-01-02", updatedData.Out)
+			if err != nil {
+				c.Error(apperror.New(400, apperror.CodeInvalidDate, "Invalid date format for Out"))
+				return
+			}
 		}
 		if updatedData.Entry != "" {
-			detail.Entry, _ = time.Parse("2006-01-02", updatedData.Entry)
-		}
-	}
-
-	// Jika admin, hanya bisa mengubah status
-	if role == "admin" {
-		if updatedData.Status != "" {
-			detail.Status = updatedData.Status
-		}
-
-		// Jika status berubah dari 'pending' ke 'loaned', kurangi quantity dari stok item
-		if previousStatus == "pending" && detail.Status == "loaned" {
-			for _, transaction := range detail.Transactions {
-				var item model.Item
-				if err := config.DB.First(&item, transaction.ItemID).Error; err != nil {
-					c.JSON(404, gin.H{"error": fmt.Sprintf("Item with ID %d not found", transaction.ItemID)})
-					return
-				}
-
-				// Kurangi stok jika status berubah dari pending ke loaned
-				if item.Stock >= transaction.Quantity {
-					item.Stock -= transaction.Quantity
-					if err := config.DB.Save(&item).Error; err != nil {
-						c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to update item stock for item ID %d", item.ID)})
-						return
-					}
-
-					// Update status transaksi menjadi finish
-					transaction.Status = "finish"
-					if err := config.DB.Save(&transaction).Error; err != nil {
-						c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to update transaction ID %d", transaction.ID)})
-						return
-					}
-				} else {
-					c.JSON(400, gin.H{"error": fmt.Sprintf("Not enough stock available for item %s", item.Name)})
-					return
-				}
+			detail.Entry, err = time.Parse("2006-01-02", updatedData.Entry)
+			if err != nil {
+				c.Error(apperror.New(400, apperror.CodeInvalidDate, "Invalid date format for Entry"))
+				return
 			}
 		}
 
-		// Jika status berubah dari 'loaned' ke 'return' atau 'pending', kembalikan quantity ke stok
-		if previousStatus == "loaned" && (detail.Status == "return" || detail.Status == "pending" || detail.Status == "rejected") {
-			for _, transaction := range detail.Transactions {
-				var item model.Item
-				if err := config.DB.First(&item, transaction.ItemID).Error; err != nil {
-					c.JSON(404, gin.H{"error": fmt.Sprintf("Item with ID %d not found", transaction.ItemID)})
-					return
-				}
-
-				// Kembalikan stok jika status berubah menjadi 'return' atau 'pending'
-				item.Stock += transaction.Quantity
-				if err := config.DB.Save(&item).Error; err != nil {
-					c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to update item stock for item ID %d", item.ID)})
-					return
-				}
-			}
+		if !detail.Out.IsZero() && !detail.Entry.IsZero() && detail.Entry.Before(detail.Out) {
+			c.Error(apperror.New(400, apperror.CodeInvalidDate, "Entry date must not be before Out date"))
+			return
 		}
 
-		// Hanya mengizinkan perubahan ke pending
-		if previousStatus == "rejected" && detail.Status != "pending" {
-			c.JSON(400, gin.H{"error": "rejected status can only be changed to pending"})
+		from, to := loanRange(detail.Out, detail.Entry)
+		err = loans.UpdateDates(&detail, from, to)
+		if errors.Is(err, loan.ErrConflict) {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Detail status was changed by another request, please retry"))
+			return
+		}
+		if err != nil {
+			c.Error(err)
 			return
 		}
-	}
 
-	// Jika status berubah dari 'pending' ke 'rejected', tidak ada perubahan stok
-	if previousStatus == "pending" && detail.Status == "rejected" {
-		// Tidak ada tindakan yang diperlukan, hanya mengubah status
+		response.Message(c, 200, "Detail updated successfully", detail.ToMap())
 	}
+}
 
-	// Simpan perubahan
-	if err := config.DB.Save(&detail).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
+// TransitionDetailHandler menjalankan aksi state machine (approve, reject, checkout, return, ...)
+// terhadap detail. Hak akses tiap aksi didefinisikan di loan.Transitions.
+func TransitionDetailHandler(loans repository.LoanRepository, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		detailID, err := strconv.ParseUint(c.Param("detail_id"), 10, 64)
+		if err != nil {
+			c.Error(apperror.New(404, apperror.CodeDetailNotFound, "Detail not found"))
+			return
+		}
 
-	c.JSON(200, gin.H{"message": "Detail updated successfully", "detail": detail.ToMap()})
-}
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
 
-func DeleteDetailHandler(c *gin.Context) {
-	detailID := c.Param("detail_id")
+		permissions, _ := c.Get("permissions")
+		permissionList, _ := permissions.([]string)
+		actor := loan.Actor{UserID: currentUserID, Permissions: permissionList}
 
-	// handle role
-	currentUserID, role, valid := helper.CheckUserRoleAndID(c, "user", "admin")
-	if !valid {
-		return
-	}
+		detail, err := loans.Transition(uint(detailID), action, actor)
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-	// Pastikan detail ada
-	var detail model.Detail
-	if err := config.DB.Preload("Transactions").First(&detail, detailID).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Detail not found"})
-		return
+		response.Message(c, 200, "Detail updated successfully", detail.ToMap())
 	}
+}
 
-	// Jika role adalah user, pastikan detail miliknya
-	if role == "user" {
-		var transaction model.Transaction
-		if err := config.DB.Where("detail_id = ? AND user_id = ?", detailID, currentUserID).First(&transaction).Error; err != nil {
-			c.JSON(403, gin.H{"error": "Forbidden: You can only delete your own detail"})
+func DeleteDetailHandler(loans repository.LoanRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		detailID := helper.ParamID(c, "detail_id")
+
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
 			return
 		}
-	}
 
-	// Periksa status detail
-	if detail.Status != "pending" && detail.Status != "rejected" {
-		c.JSON(400, gin.H{"error": "Cannot delete detail: Detail status must be pending or rejected"})
-		return
-	}
+		// Pastikan detail ada
+		detail, err := loans.FindDetail(detailID)
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeDetailNotFound, "Detail not found"))
+			return
+		}
 
-	// Hapus transaksi yang terhubung dengan detail_id
-	if err := config.DB.Unscoped().Where("detail_id = ?", detailID).Delete(&model.Transaction{}).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete related transactions"})
-		return
-	}
+		// Tanpa permission loan:manage, pastikan detail miliknya
+		if !middleware.HasPermission(c, model.PermissionLoanManage) {
+			if owns, err := loans.OwnsDetail(detail.ID, currentUserID); err != nil || !owns {
+				c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: You can only delete your own detail"))
+				return
+			}
+		}
 
-	if err := config.DB.Unscoped().Delete(&detail).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
+		// Periksa status detail
+		if detail.Status != loan.StatusPending && detail.Status != loan.StatusRejected && detail.Status != loan.StatusCancelled {
+			c.Error(apperror.New(400, apperror.CodeInvalidStatus, "Cannot delete detail: Detail status must be pending, rejected or cancelled"))
+			return
+		}
 
-	c.JSON(200, gin.H{"message": "Detail and related transactions deleted successfully"})
+		// Hapus detail beserta transaksi yang terhubung
+		if err := loans.DeleteDetail(&detail); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "Detail and related transactions deleted successfully", nil)
+	}
 }
@@ -0,0 +1,478 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/stock"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	// Gunakan file SQLite sementara agar beberapa koneksi bisa berjalan paralel
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db
+}
+
+func setupApproveRouter(db *gorm.DB) *gin.Engine {
+	return setupDetailRouter(db, 1, model.DefaultRolePermissions[model.RoleAdmin])
+}
+
+func setupDetailRouter(db *gorm.DB, currentID uint, permissions []string) *gin.Engine {
+	loans := repository.NewGorm(db).Loans
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("current_id", currentID)
+		c.Set("permissions", permissions)
+		c.Next()
+	})
+	for _, action := range []string{loan.ActionApprove, loan.ActionReject, loan.ActionCancel, loan.ActionCheckout, loan.ActionReturn} {
+		r.POST("/detail/:detail_id/"+action, TransitionDetailHandler(loans, action))
+	}
+	return r
+}
+
+func createPendingDetail(t *testing.T, db *gorm.DB, userID, itemID uint, quantity int) model.Detail {
+	t.Helper()
+
+	detail := model.Detail{Code: fmt.Sprintf("test%d-%d", userID, quantity), Status: "pending"}
+	if err := db.Create(&detail).Error; err != nil {
+		t.Fatalf("failed to create detail: %v", err)
+	}
+	transaction := model.Transaction{UserID: userID, ItemID: itemID, DetailID: &detail.ID, Quantity: quantity, Status: "pending"}
+	if err := db.Create(&transaction).Error; err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	return detail
+}
+
+func runAction(r *gin.Engine, detailID uint, action string) int {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/detail/%d/%s", detailID, action), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// approveAndCheckout menjalankan approve lalu checkout, mengembalikan status HTTP pertama yang gagal
+func approveAndCheckout(r *gin.Engine, detailID uint) int {
+	if code := runAction(r, detailID, loan.ActionApprove); code != 200 {
+		return code
+	}
+	return runAction(r, detailID, loan.ActionCheckout)
+}
+
+func TestParallelApprovalsDoNotOversell(t *testing.T) {
+	db := setupTestDB(t)
+	r := setupApproveRouter(db)
+
+	item := model.Item{Name: "Projector", Stock: 5}
+	db.Create(&item)
+
+	// 10 peminjam masing-masing meminta 1 unit pada hari berbeda sehingga semua bisa disetujui,
+	// tetapi stok di gudang hanya cukup untuk 5 checkout
+	var details []model.Detail
+	start := time.Now().AddDate(0, 0, 1)
+	for i := 1; i <= 10; i++ {
+		user := model.User{Name: "Borrower", Email: fmt.Sprintf("borrower%d@example.com", i), Password: "x", Role: model.RoleUser}
+		db.Create(&user)
+		detail := createPendingDetail(t, db, user.ID, item.ID, 1)
+		date := start.AddDate(0, 0, i)
+		db.Model(&detail).Updates(model.Detail{Out: date, Entry: date})
+		details = append(details, detail)
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, len(details))
+	for i, detail := range details {
+		wg.Add(1)
+		go func(i int, detailID uint) {
+			defer wg.Done()
+			codes[i] = approveAndCheckout(r, detailID)
+		}(i, detail.ID)
+	}
+	wg.Wait()
+
+	approved := 0
+	for _, code := range codes {
+		if code == 200 {
+			approved++
+		} else if code != 400 {
+			t.Errorf("unexpected status code %d", code)
+		}
+	}
+
+	db.First(&item, item.ID)
+	if approved != 5 {
+		t.Errorf("expected 5 approvals, got %d", approved)
+	}
+	if item.Stock != 0 {
+		t.Errorf("expected stock 0, got %d", item.Stock)
+	}
+
+	// Detail yang gagal harus tetap pending tanpa mengubah stok
+	var loaned int64
+	db.Model(&model.Detail{}).Where("status = ?", "loaned").Count(&loaned)
+	if loaned != int64(approved) {
+		t.Errorf("expected %d loaned details, got %d", approved, loaned)
+	}
+}
+
+func TestParallelApprovalOfSameDetailDeductsOnce(t *testing.T) {
+	db := setupTestDB(t)
+	r := setupApproveRouter(db)
+
+	item := model.Item{Name: "Camera", Stock: 10}
+	db.Create(&item)
+	user := model.User{Name: "Borrower", Email: "borrower@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&user)
+	detail := createPendingDetail(t, db, user.ID, item.ID, 3)
+	if code := runAction(r, detail.ID, loan.ActionApprove); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runAction(r, detail.ID, loan.ActionCheckout)
+		}()
+	}
+	wg.Wait()
+
+	db.First(&item, item.ID)
+	if item.Stock != 7 {
+		t.Errorf("expected stock 7, got %d", item.Stock)
+	}
+}
+
+func TestApprovalChecksCalendar(t *testing.T) {
+	db := setupTestDB(t)
+	r := setupApproveRouter(db)
+
+	item := model.Item{Name: "Projector", Stock: 1}
+	db.Create(&item)
+	book := func(n int, from, to string) model.Detail {
+		user := model.User{Name: "Borrower", Email: fmt.Sprintf("borrower%d@example.com", n), Password: "x", Role: model.RoleUser}
+		db.Create(&user)
+		detail := createPendingDetail(t, db, user.ID, item.ID, 1)
+		out, _ := time.Parse("2006-01-02", from)
+		entry, _ := time.Parse("2006-01-02", to)
+		db.Model(&detail).Updates(model.Detail{Out: out, Entry: entry})
+		return detail
+	}
+
+	first := book(1, "2030-03-10", "2030-03-12")
+	if code := runAction(r, first.ID, loan.ActionApprove); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	// Stok gudang masih 1, tetapi unitnya sudah disetujui untuk tanggal yang beririsan
+	if code := runAction(r, book(2, "2030-03-11", "2030-03-13").ID, loan.ActionApprove); code != 400 {
+		t.Errorf("expected overlapping booking to be rejected, got %d", code)
+	}
+	if code := runAction(r, first.ID, loan.ActionCheckout); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	// Detail yang tidak beririsan tetap bisa disetujui walau stok gudang sedang 0
+	if code := runAction(r, book(3, "2030-03-20", "2030-03-21").ID, loan.ActionApprove); code != 200 {
+		t.Errorf("expected booking on free dates to be approved, got %d", code)
+	}
+}
+
+func TestApprovalIsAllOrNothing(t *testing.T) {
+	db := setupTestDB(t)
+	r := setupApproveRouter(db)
+
+	enough := model.Item{Name: "Laptop", Stock: 5}
+	notEnough := model.Item{Name: "Tripod", Stock: 1}
+	db.Create(&enough)
+	db.Create(&notEnough)
+	user := model.User{Name: "Borrower", Email: "borrower@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&user)
+
+	// Satu detail berisi dua item, item kedua stoknya tidak cukup
+	detail := createPendingDetail(t, db, user.ID, enough.ID, 2)
+	db.Create(&model.Transaction{UserID: user.ID, ItemID: notEnough.ID, DetailID: &detail.ID, Quantity: 3, Status: "pending"})
+
+	db.Model(&detail).UpdateColumn("status", loan.StatusApproved)
+	if code := runAction(r, detail.ID, loan.ActionCheckout); code != 400 {
+		t.Fatalf("expected 400, got %d", code)
+	}
+
+	db.First(&enough, enough.ID)
+	if enough.Stock != 5 {
+		t.Errorf("expected stock of first item to be rolled back to 5, got %d", enough.Stock)
+	}
+	db.First(&detail, detail.ID)
+	if detail.Status != loan.StatusApproved {
+		t.Errorf("expected detail to stay approved, got %s", detail.Status)
+	}
+
+	// Pengembalian mengembalikan stok
+	db.Model(&notEnough).Update("stock", 3)
+	if code := runAction(r, detail.ID, loan.ActionCheckout); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := runAction(r, detail.ID, loan.ActionReturn); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	db.First(&enough, enough.ID)
+	db.First(&notEnough, notEnough.ID)
+	if enough.Stock != 5 || notEnough.Stock != 3 {
+		t.Errorf("expected stock to be restored to 5 and 3, got %d and %d", enough.Stock, notEnough.Stock)
+	}
+
+	// Setiap checkout dan pengembalian tercatat di ledger
+	var movements []model.StockMovement
+	db.Where("detail_id = ?", detail.ID).Order("id").Find(&movements)
+	if len(movements) != 4 {
+		t.Fatalf("expected 4 stock movements, got %d", len(movements))
+	}
+	if movements[0].Reason != stock.ReasonLoan || movements[0].Delta != -2 || movements[3].Reason != stock.ReasonReturn || movements[3].Delta != 3 {
+		t.Errorf("unexpected stock movements: %+v", movements)
+	}
+}
+
+func TestTransitionRules(t *testing.T) {
+	db := setupTestDB(t)
+	admin := setupApproveRouter(db)
+
+	item := model.Item{Name: "Speaker", Stock: 4}
+	db.Create(&item)
+	owner := model.User{Name: "Owner", Email: "owner@example.com", Password: "x", Role: model.RoleUser}
+	other := model.User{Name: "Other", Email: "other@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&owner)
+	db.Create(&other)
+	detail := createPendingDetail(t, db, owner.ID, item.ID, 1)
+
+	userPermissions := model.DefaultRolePermissions[model.RoleUser]
+	ownerRouter := setupDetailRouter(db, owner.ID, userPermissions)
+	otherRouter := setupDetailRouter(db, other.ID, userPermissions)
+
+	// Checkout langsung dari pending tidak diizinkan
+	if code := runAction(admin, detail.ID, loan.ActionCheckout); code != 400 {
+		t.Errorf("expected 400 for pending -> loaned, got %d", code)
+	}
+	// Peminjam tidak boleh menyetujui detailnya sendiri
+	if code := runAction(ownerRouter, detail.ID, loan.ActionApprove); code != 403 {
+		t.Errorf("expected 403 for owner approval, got %d", code)
+	}
+	// Peminjam lain tidak boleh membatalkan detail orang lain
+	if code := runAction(otherRouter, detail.ID, loan.ActionCancel); code != 403 {
+		t.Errorf("expected 403 for cancel by other user, got %d", code)
+	}
+	if code := runAction(ownerRouter, detail.ID, loan.ActionCancel); code != 200 {
+		t.Errorf("expected 200 for cancel by owner, got %d", code)
+	}
+	if code := runAction(admin, detail.ID, loan.ActionApprove); code != 400 {
+		t.Errorf("expected 400 for cancelled -> approved, got %d", code)
+	}
+
+	db.First(&item, item.ID)
+	if item.Stock != 4 {
+		t.Errorf("expected stock to stay 4, got %d", item.Stock)
+	}
+}
+
+func TestCreateDetailRejectsUnavailableDates(t *testing.T) {
+	db := setupTestDB(t)
+
+	item := model.Item{Name: "Microphone", Stock: 2}
+	db.Create(&item)
+	borrower := model.User{Name: "Borrower", Email: "borrower@example.com", Password: "x", Role: model.RoleUser}
+	other := model.User{Name: "Other", Email: "other@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&borrower)
+	db.Create(&other)
+
+	// Dua unit sudah disetujui untuk 10-12 Maret
+	booked := model.Detail{Code: "booked", Status: loan.StatusApproved, Out: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), Entry: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)}
+	db.Create(&booked)
+	db.Create(&model.Transaction{UserID: other.ID, ItemID: item.ID, DetailID: &booked.ID, Quantity: 2, Status: "pending"})
+	db.Create(&model.Transaction{UserID: borrower.ID, ItemID: item.ID, Quantity: 1, Status: "draft"})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("current_id", borrower.ID)
+		c.Next()
+	})
+	r.POST("/detail", CreateDetailHandler(repository.NewGorm(db).Loans, loan.CodeGenerator{Prefix: "IVT", DateFormat: "20060102"}))
+
+	submit := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/detail", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := submit(`{"out": "2026-03-12", "entry": "2026-03-14"}`); code != 400 {
+		t.Errorf("expected 400 for overlapping dates, got %d", code)
+	}
+	if code := submit(`{"out": "2026-03-13", "entry": "2026-03-14"}`); code != 201 {
+		t.Errorf("expected 201 for free dates, got %d", code)
+	}
+}
+
+func TestListQueriesQuoteReservedTables(t *testing.T) {
+	db := setupTestDB(t)
+
+	item := model.Item{Name: "Cable", Stock: 3}
+	db.Create(&item)
+	user := model.User{Name: "Borrower", Email: "borrower@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&user)
+	detail := createPendingDetail(t, db, user.ID, item.ID, 1)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("current_id", uint(1))
+		c.Set("permissions", model.DefaultRolePermissions[model.RoleAdmin])
+		c.Next()
+	})
+	loans := repository.NewGorm(db).Loans
+	r.GET("/chart", GetTransactionsHandler(loans))
+	r.GET("/detail", GetAllDetailHandler(loans))
+	r.GET("/detail/:detail_id", GetDetailHandler(loans))
+
+	// "transaction" dan "user" adalah kata kunci di SQLite dan PostgreSQL
+	for _, url := range []string{"/chart", "/detail", fmt.Sprintf("/detail/%d", detail.ID)} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != 200 || !strings.Contains(w.Body.String(), `"user":"Borrower"`) {
+			t.Errorf("%s: expected 200 with borrower name, got %d %s", url, w.Code, w.Body.String())
+		}
+	}
+}
+
+// serve menjalankan satu request JSON terhadap router
+func serve(r *gin.Engine, method, url, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// setupLoanRouter memasang handler keranjang dan detail di atas database SQLite di memori
+func setupLoanRouter(t *testing.T, currentID uint, permissions []string) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+
+	db, err := repository.OpenSQLite("")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	repos := repository.NewGorm(db)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("current_id", currentID)
+		c.Set("permissions", permissions)
+		c.Next()
+	})
+	r.POST("/chart", CreateTransactionHandler(repos.Items, repos.Loans))
+	r.POST("/detail", CreateDetailHandler(repos.Loans, loan.CodeGenerator{Prefix: "IVT", DateFormat: "20060102"}))
+	r.PUT("/detail/:detail_id", UpdateDetailHandler(repos.Loans))
+	r.DELETE("/detail/:detail_id", DeleteDetailHandler(repos.Loans))
+	return r, db
+}
+
+func TestUpdateDetailRules(t *testing.T) {
+	r, db := setupLoanRouter(t, 1, model.DefaultRolePermissions[model.RoleUser])
+
+	owner := model.User{Name: "Owner", Email: "owner@example.com", Password: "x", Role: model.RoleUser}
+	other := model.User{Name: "Other", Email: "other@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&owner)
+	db.Create(&other)
+	item := model.Item{Name: "Lamp", Stock: 2}
+	db.Create(&item)
+	mine := createPendingDetail(t, db, owner.ID, item.ID, 1)
+	theirs := createPendingDetail(t, db, other.ID, item.ID, 2)
+
+	if w := serve(r, http.MethodPut, fmt.Sprintf("/detail/%d", mine.ID), `{"out": "2026-05-01", "entry": "2026-05-03"}`); w.Code != 200 {
+		t.Errorf("expected 200 for own pending detail, got %d %s", w.Code, w.Body.String())
+	}
+	db.First(&mine, mine.ID)
+	if mine.Entry.Format("2006-01-02") != "2026-05-03" {
+		t.Errorf("expected entry date to be saved, got %s", mine.Entry)
+	}
+
+	if w := serve(r, http.MethodPut, fmt.Sprintf("/detail/%d", theirs.ID), `{"out": "2026-05-01"}`); w.Code != 403 {
+		t.Errorf("expected 403 for someone else's detail, got %d", w.Code)
+	}
+
+	// Setelah disetujui, tanggal tidak bisa diubah dan detail tidak bisa dihapus
+	db.Model(&mine).UpdateColumn("status", loan.StatusApproved)
+	if w := serve(r, http.MethodPut, fmt.Sprintf("/detail/%d", mine.ID), `{"out": "2026-05-02"}`); w.Code != 400 {
+		t.Errorf("expected 400 for approved detail, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodDelete, fmt.Sprintf("/detail/%d", mine.ID), ""); w.Code != 400 {
+		t.Errorf("expected 400 when deleting approved detail, got %d", w.Code)
+	}
+}
+
+func TestCreateDetailRules(t *testing.T) {
+	r, db := setupLoanRouter(t, 1, model.DefaultRolePermissions[model.RoleUser])
+
+	borrower := model.User{Name: "Borrower", Email: "borrower@example.com", Password: "x", Role: model.RoleUser}
+	db.Create(&borrower)
+	item := model.Item{Name: "Tent", Stock: 3}
+	db.Create(&item)
+
+	if w := serve(r, http.MethodPost, "/detail", `{"out": "2026-06-01"}`); w.Code != 404 {
+		t.Errorf("expected 404 without draft carts, got %d", w.Code)
+	}
+
+	// Jumlah di keranjang tidak boleh melebihi unit yang dimiliki, termasuk saat ditambahkan
+	if w := serve(r, http.MethodPost, "/chart", fmt.Sprintf(`{"item_id": %d, "quantity": 4}`, item.ID)); w.Code != 400 {
+		t.Errorf("expected 400 for quantity above capacity, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodPost, "/chart", fmt.Sprintf(`{"item_id": %d, "quantity": 2}`, item.ID)); w.Code != 201 {
+		t.Fatalf("expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(r, http.MethodPost, "/chart", fmt.Sprintf(`{"item_id": %d, "quantity": 2}`, item.ID)); w.Code != 400 {
+		t.Errorf("expected 400 when the draft would exceed capacity, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodPost, "/chart", fmt.Sprintf(`{"item_id": %d, "quantity": 1}`, item.ID)); w.Code != 200 {
+		t.Errorf("expected 200 when adding to the draft, got %d", w.Code)
+	}
+
+	if w := serve(r, http.MethodPost, "/detail", `{"out": "2026-06-03", "entry": "2026-06-01"}`); w.Code != 400 {
+		t.Errorf("expected 400 for entry before out, got %d", w.Code)
+	}
+	w := serve(r, http.MethodPost, "/detail", `{"out": "2026-06-01", "entry": "2026-06-03"}`)
+	if w.Code != 201 || !strings.Contains(w.Body.String(), `"code":"IVT-`) {
+		t.Fatalf("expected 201 with loan code, got %d %s", w.Code, w.Body.String())
+	}
+
+	var draft int64
+	db.Model(&model.Transaction{}).Where("status = ?", "draft").Count(&draft)
+	if draft != 0 {
+		t.Errorf("expected submitted carts to leave draft, %d remain", draft)
+	}
+}
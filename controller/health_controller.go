@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/migration"
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HealthzHandler adalah pemeriksaan liveness: proses masih hidup dan bisa melayani request
+func HealthzHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// ReadyzHandler adalah pemeriksaan readiness: database bisa dihubungi dan versi skemanya sama
+// dengan binary ini. Endpoint ini publik, jadi detail error hanya ditulis ke log.
+func ReadyzHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			middleware.Logger(c).Error("Readiness check failed", "check", "database", "error", err.Error())
+			c.JSON(503, gin.H{"status": "unavailable", "database": "unavailable"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		if err := sqlDB.PingContext(ctx); err != nil {
+			middleware.Logger(c).Error("Readiness check failed", "check", "database", "error", err.Error())
+			c.JSON(503, gin.H{"status": "unavailable", "database": "unavailable"})
+			return
+		}
+
+		version, err := migration.Version(db.WithContext(ctx))
+		if err != nil {
+			middleware.Logger(c).Error("Readiness check failed", "check", "migration", "error", err.Error())
+			c.JSON(503, gin.H{"status": "unavailable", "database": "ok", "migration": "unavailable"})
+			return
+		}
+		status := gin.H{"database": "ok", "schema_version": version, "expected_version": migration.Latest()}
+		if version != migration.Latest() {
+			status["status"] = "unavailable"
+			c.JSON(503, status)
+			return
+		}
+
+		status["status"] = "ok"
+		c.JSON(200, status)
+	}
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Gin-Inventory/config"
+	"Gin-Inventory/migration"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadyzChecksSchemaVersion(t *testing.T) {
+	db, err := config.OpenDatabase("sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", HealthzHandler)
+	r.GET("/readyz", ReadyzHandler(db))
+
+	var body string
+	get := func(url string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		body = w.Body.String()
+		return w.Code
+	}
+
+	if code := get("/healthz"); code != 200 {
+		t.Errorf("expected healthz 200, got %d", code)
+	}
+	// Database bisa dihubungi tetapi belum dimigrasi
+	if code := get("/readyz"); code != 503 {
+		t.Errorf("expected readyz 503 before migration, got %d", code)
+	}
+	if _, err := migration.Up(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if code := get("/readyz"); code != 200 {
+		t.Errorf("expected readyz 200 after migration, got %d", code)
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if code := get("/readyz"); code != 503 {
+		t.Errorf("expected readyz 503 when the database is closed, got %d", code)
+	}
+	// Pesan error database tidak dikirim ke klien
+	if body != `{"database":"unavailable","status":"unavailable"}` {
+		t.Errorf("expected no error details in the response, got %s", body)
+	}
+}
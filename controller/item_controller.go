@@ -1,131 +1,256 @@
 package controller
 
 import (
-	"Gin-Inventory/config"
+	"Gin-Inventory/apperror"
 	"Gin-Inventory/helper"
 	"Gin-Inventory/middleware"
 	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+	"Gin-Inventory/stock"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateItemHandler(c *gin.Context) {
-	// handle role
-	_, _, valid := helper.CheckUserRoleAndID(c, "admin")
-	if !valid {
-		return
-	}
+func CreateItemHandler(items repository.ItemRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Memvalidasi input dengan Middleware ValidateInput.
+		itemData, valid := helper.ValidationHelper(c, middleware.ItemSchema{})
+		if !valid {
+			return
+		}
 
-	// Memvalidasi input dengan Middleware ValidateInput.
-	itemData, valid := helper.ValidationHelper(c, middleware.ItemSchema{})
-	if !valid {
-		return
-	}
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
 
-	newItem := model.Item{
-		Name:  itemData.Name,
-		Stock: itemData.Stock,
-	}
+		// Item dibuat dengan stok 0, stok awal dicatat sebagai pembelian di ledger
+		newItem := model.Item{
+			Name:  itemData.Name,
+			Stock: 0,
+		}
+		initial := model.StockMovement{
+			Delta:   itemData.Stock,
+			Reason:  stock.ReasonPurchase,
+			ActorID: &currentUserID,
+			Note:    "initial stock",
+		}
+		if err := items.Create(&newItem, initial); err != nil {
+			c.Error(err)
+			return
+		}
 
-	if err := config.DB.Create(&newItem).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		response.Message(c, 201, "Item created successfully", newItem.ToMap())
 	}
-
-	c.JSON(201, gin.H{"message": "Item created successfully", "item": newItem.ToMap()})
 }
 
-func GetAllItemHandler(c *gin.Context) {
-	var item []model.Item
-	if err := config.DB.Find(&item).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+func GetAllItemHandler(items repository.ItemRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := helper.ParseList(c, repository.ItemList)
+		if !ok {
+			return
+		}
+		result, total, err := items.List(query)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(200, helper.NewListResult(c, query, total).Response(model.ItemsToMap(result)))
 	}
-	c.JSON(200, gin.H{"item": model.ItemsToMap(item)})
 }
 
-func GetItemHandler(c *gin.Context) {
-	item_id := c.Param("item_id")
+func GetItemHandler(items repository.ItemRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Pastikan item ada
+		item, err := items.Find(helper.ParamID(c, "item_id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeItemNotFound, "Item not found"))
+			return
+		}
 
-	// Pastikan item ada
-	var item model.Item
-	if err := config.DB.First(&item, item_id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Item not found"})
-		return
+		response.JSON(c, 200, item.ToMap())
 	}
-
-	c.JSON(200, item.ToMap())
 }
 
-func UpdateItemHandler(c *gin.Context) {
-	item_id := c.Param("item_id")
+func UpdateItemHandler(items repository.ItemRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
 
-	// handle role
-	_, _, valid := helper.CheckUserRoleAndID(c, "admin")
-	if !valid {
-		return
-	}
+		// Pastikan item ada
+		item, err := items.Find(helper.ParamID(c, "item_id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeItemNotFound, "Item not found"))
+			return
+		}
 
-	// Pastikan item ada
-	var item model.Item
-	if err := config.DB.First(&item, item_id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Item not found"})
-		return
-	}
+		// Memvalidasi input dengan Middleware ValidateInput.
+		updatedData, valid := helper.ValidationHelper(c, middleware.UpdateItemSchema{})
+		if !valid {
+			return
+		}
 
-	// Memvalidasi input dengan Middleware ValidateInput.
-	updatedData, valid := helper.ValidationHelper(c, middleware.ItemSchema{})
-	if !valid {
-		return
-	}
+		// Perbarui field yang diberikan
+		if updatedData.Name != "" {
+			item.Name = updatedData.Name
+		}
 
-	// Perbarui field yang diberikan
-	if updatedData.Name != "" {
-		item.Name = updatedData.Name
-	}
-	if updatedData.Stock != 0 {
-		item.Stock = updatedData.Stock
-	}
-	if err := config.DB.Save(&item).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
+		// Perubahan stok dicatat sebagai penyesuaian di ledger
+		target := updatedData.Stock
+		movement := model.StockMovement{
+			Reason:  stock.ReasonAdjustment,
+			ActorID: &currentUserID,
+			Note:    "stock updated",
+		}
+
+		err = items.Update(&item, target, movement)
+		if errors.Is(err, stock.ErrConflict) {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Item stock was changed by another request, please retry"))
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-	c.JSON(200, gin.H{"message": "Item updated successfully", "item": item.ToMap()})
+		response.Message(c, 200, "Item updated successfully", item.ToMap())
+	}
 }
 
-func DeleteItemHandler(c *gin.Context) {
-	item_id := c.Param("item_id")
+func DeleteItemHandler(items repository.ItemRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Pastikan item ada
+		item, err := items.Find(helper.ParamID(c, "item_id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeItemNotFound, "Item not found"))
+			return
+		}
 
-	// handle role
-	_, _, valid := helper.CheckUserRoleAndID(c, "admin")
-	if !valid {
-		return
-	}
+		// Periksa apakah item digunakan dalam transaksi
+		inUse, err := items.InUse(item.ID)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to check item usage in transactions: %w", err))
+			return
+		}
 
-	// Pastikan item ada
-	var item model.Item
-	if err := config.DB.First(&item, item_id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "Item not found"})
-		return
-	}
+		if inUse {
+			c.Error(apperror.New(400, apperror.CodeItemInUse, "Cannot delete item: Item is used in transactions"))
+			return
+		}
 
-	// Periksa apakah item digunakan dalam transaksi
-	var transactionCount int64
-	if err := config.DB.Model(&model.Transaction{}).Where("item_id = ?", item_id).Count(&transactionCount).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to check item usage in transactions"})
-		return
+		if err := items.Delete(&item); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "Item deleted successfully", nil)
 	}
+}
+
+// GetItemAvailabilityHandler menampilkan jumlah unit yang tersedia per hari, ?from= dan ?to=
+// berformat YYYY-MM-DD, default 30 hari mulai hari ini
+func GetItemAvailabilityHandler(loans repository.LoanRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+		if err != nil {
+			c.Error(apperror.New(404, apperror.CodeItemNotFound, "Item not found"))
+			return
+		}
+
+		from := time.Now()
+		if value := c.Query("from"); value != "" {
+			if from, err = time.Parse("2006-01-02", value); err != nil {
+				c.Error(apperror.New(400, apperror.CodeInvalidDate, "Invalid date format for from"))
+				return
+			}
+		}
+		to := from.AddDate(0, 0, 29)
+		if value := c.Query("to"); value != "" {
+			if to, err = time.Parse("2006-01-02", value); err != nil {
+				c.Error(apperror.New(400, apperror.CodeInvalidDate, "Invalid date format for to"))
+				return
+			}
+		}
 
-	if transactionCount > 0 {
-		c.JSON(400, gin.H{"error": "Cannot delete item: Item is used in transactions"})
-		return
+		calendar, err := loans.Calendar(uint(itemID), from, to)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.JSON(c, 200, calendar)
 	}
+}
+
+// GetItemMovementsHandler menampilkan riwayat pergerakan stok sebuah item
+func GetItemMovementsHandler(items repository.ItemRepository, stocks repository.StockRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Pastikan item ada
+		item, err := items.Find(helper.ParamID(c, "item_id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeItemNotFound, "Item not found"))
+			return
+		}
 
-	if err := config.DB.Unscoped().Delete(&item).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		query, ok := helper.ParseList(c, repository.MovementList)
+		if !ok {
+			return
+		}
+		movements, total, err := stocks.Movements(item.ID, query)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(200, helper.NewListResult(c, query, total).Response(model.StockMovementsToMap(movements)))
 	}
+}
+
+// CreateItemMovementHandler mencatat pergerakan stok manual: pembelian, penyesuaian atau kehilangan
+func CreateItemMovementHandler(items repository.ItemRepository, stocks repository.StockRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
+
+		// Pastikan item ada
+		item, err := items.Find(helper.ParamID(c, "item_id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeItemNotFound, "Item not found"))
+			return
+		}
 
-	c.JSON(200, gin.H{"message": "Item deleted successfully"})
+		// Memvalidasi input dengan Middleware ValidateInput.
+		movementData, valid := helper.ValidationHelper(c, middleware.StockMovementSchema{})
+		if !valid {
+			return
+		}
+
+		movement := model.StockMovement{
+			ItemID:  item.ID,
+			Delta:   movementData.Delta,
+			Reason:  movementData.Reason,
+			ActorID: &currentUserID,
+			Note:    movementData.Note,
+		}
+		err = stocks.Move(&movement)
+		if errors.Is(err, stock.ErrInsufficientStock) {
+			c.Error(apperror.New(400, apperror.CodeInsufficientStock, fmt.Sprintf("Not enough stock available for item %s", item.Name)))
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 201, "Stock movement recorded successfully", movement.ToMap())
+	}
 }
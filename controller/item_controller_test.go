@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeItems adalah ItemRepository tanpa database. Method yang tidak dipakai tes akan panic
+// karena interface yang di-embed bernilai nil.
+type fakeItems struct {
+	repository.ItemRepository
+	items []model.Item
+	err   error
+	query repository.ListQuery
+}
+
+func (f *fakeItems) List(query repository.ListQuery) ([]model.Item, int64, error) {
+	f.query = query
+	return f.items, int64(len(f.items)) + 10, f.err
+}
+
+func TestGetAllItemHandlerWithFakeRepository(t *testing.T) {
+	items := &fakeItems{items: []model.Item{{Name: "Drill", Stock: 2}}}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.GET("/item", GetAllItemHandler(items))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item?name=Drill&sort=-stock&per_page=5", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if items.query.Filters["name"] != "Drill" || items.query.PerPage != 5 || len(items.query.Sorts) != 1 || !items.query.Sorts[0].Desc {
+		t.Errorf("unexpected query passed to repository: %+v", items.query)
+	}
+
+	var body struct {
+		Data []map[string]interface{} `json:"data"`
+		Meta map[string]interface{}   `json:"meta"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Data) != 1 || body.Data[0]["name"] != "Drill" || body.Meta["total"].(float64) != 11 || body.Meta["total_pages"].(float64) != 3 {
+		t.Errorf("unexpected response: %s", w.Body.String())
+	}
+
+	// Query tidak valid ditolak sebelum repository dipanggil
+	items.query = repository.ListQuery{}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item?sort=password", nil))
+	if w.Code != 400 || items.query.PerPage != 0 {
+		t.Errorf("expected 400 without calling the repository, got %d (%+v)", w.Code, items.query)
+	}
+
+	items.err = errors.New("connection refused")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item", nil))
+	if w.Code != 500 {
+		t.Errorf("expected 500 on repository error, got %d", w.Code)
+	}
+}
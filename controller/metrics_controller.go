@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"crypto/subtle"
+
+	"Gin-Inventory/apperror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler menampilkan metrik dari registry dalam format Prometheus. Jika token diisi,
+// scraper harus mengirim header Authorization: Bearer <token>.
+func MetricsHandler(registry *prometheus.Registry, token string) gin.HandlerFunc {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		if token != "" {
+			expected := []byte("Bearer " + token)
+			if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+				c.Error(apperror.New(401, apperror.CodeUnauthorized, "Unauthorized: Invalid metrics token"))
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/helper"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+	"Gin-Inventory/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLockoutsHandler menampilkan akun dan alamat IP yang sedang dikunci karena login gagal
+func GetLockoutsHandler(attempts store.LoginAttemptStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locked, err := attempts.Locked(time.Now())
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		result := []map[string]interface{}{}
+		for _, attempt := range locked {
+			result = append(result, attempt.ToMap())
+		}
+		response.JSON(c, 200, result)
+	}
+}
+
+// ClearLockoutHandler membuka kunci dan menghapus hitungan login gagal sebuah subject,
+// misalnya /lockouts/account:user@example.com atau /lockouts/ip:203.0.113.7
+func ClearLockoutHandler(attempts store.LoginAttemptStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := c.Param("subject")
+
+		attempt, err := attempts.Get(subject)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if attempt.Failures == 0 && attempt.LockedUntil == nil {
+			c.Error(apperror.New(404, apperror.CodeNotFound, "No failed logins recorded for "+subject))
+			return
+		}
+
+		if err := attempts.Clear(subject); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "Lockout cleared successfully", nil)
+	}
+}
+
+// SetPasswordLoginHandler menyalakan atau mematikan login password akun, misalnya untuk staf yang
+// wajib login lewat SSO. Akun admin hanya bisa diubah oleh pemilik permission admin:manage.
+func SetPasswordLoginHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := users.Find(helper.ParamID(c, "id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeUserNotFound, "User not found"))
+			return
+		}
+		if user.Role != model.RoleUser && !middleware.HasPermission(c, model.PermissionAdminManage) {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: Missing permission "+model.PermissionAdminManage))
+			return
+		}
+
+		// Memvalidasi input dengan Middleware ValidateInput.
+		loginData, valid := helper.ValidationHelper(c, middleware.PasswordLoginSchema{})
+		if !valid {
+			return
+		}
+
+		user.PasswordLoginDisabled = !*loginData.Enabled
+		if err := users.Save(&user); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "Password login updated successfully", user.ToMap())
+	}
+}
+
+// GetLoginFailuresHandler menampilkan catatan audit login gagal
+func GetLoginFailuresHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := helper.ParseList(c, repository.LoginFailureList)
+		if !ok {
+			return
+		}
+		failures, total, err := users.LoginFailures(query)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(200, helper.NewListResult(c, query, total).Response(model.LoginFailuresToMap(failures)))
+	}
+}
@@ -1,233 +1,181 @@
 package controller
 
 import (
-	"Gin-Inventory/config"
+	"Gin-Inventory/apperror"
 	"Gin-Inventory/helper"
+	"Gin-Inventory/mail"
 	"Gin-Inventory/middleware"
 	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateUserHandler(c *gin.Context) {
-	// Memvalidasi input dengan Middleware ValidateInput.
-	userData, valid := helper.ValidationHelper(c, middleware.UserSchema{})
-	if !valid {
-		return
-	}
+// CreateUserHandler mendaftarkan akun user baru dan mengirim tautan verifikasi email.
+// Akun belum bisa mengajukan peminjaman sebelum email-nya diverifikasi.
+func CreateUserHandler(users repository.UserRepository, mailer mail.Mailer, verificationTTL time.Duration, publicURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Memvalidasi input dengan Middleware ValidateInput.
+		userData, valid := helper.ValidationHelper(c, middleware.UserSchema{})
+		if !valid {
+			return
+		}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userData.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to hash password"})
-		return
-	}
+		// Hash password
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userData.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to hash password: %w", err))
+			return
+		}
 
-	userData.Password = string(hashedPassword)
+		userData.Password = string(hashedPassword)
 
-	newUser := model.User{
-		Name:     userData.Name,
-		Email:    userData.Email,
-		Password: userData.Password,
-		Role:     "user", // Atur default role
-	}
+		newUser := model.User{
+			Name:     userData.Name,
+			Email:    userData.Email,
+			Password: userData.Password,
+			Role:     model.RoleUser, // Atur default role
+		}
 
-	if err := config.DB.Create(&newUser).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
+		if err := users.Create(&newUser); err != nil {
+			c.Error(err)
+			return
+		}
 
-	c.JSON(201, gin.H{"message": "User created successfully", "user": newUser.ToMap()})
-}
+		// Akun tetap dibuat meski email gagal terkirim, tautan bisa diminta ulang setelah login
+		if err := sendVerificationEmail(c, users, mailer, verificationTTL, publicURL, newUser); err != nil {
+			middleware.Logger(c).Error("Failed to send verification email", "user_id", newUser.ID, "error", err.Error())
+		}
 
-func GetAllUserHandler(c *gin.Context) {
-	var user []model.User
-	if err := config.DB.Find(&user).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+		response.Message(c, 201, "User created successfully", newUser.ToMap())
 	}
-	c.JSON(200, gin.H{"user": model.UsersToMap(user)})
 }
 
-func GetUserHandler(c *gin.Context) {
-	id := c.Param("id")
-
-	// handle role
-	currentUserID, role, valid := helper.CheckUserRoleAndID(c, "user", "admin")
-	if !valid {
-		return
-	}
-
-	// Pastikan user ada
-	var user model.User
-	if err := config.DB.First(&user, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User not found"})
-		return
-	}
-
-	// Periksa akses berdasarkan role
-	if role == "user" {
-		// Pastikan user hanya bisa mengakses datanya sendiri
-		if fmt.Sprintf("%v", currentUserID) != id {
-			c.JSON(403, gin.H{"error": "Forbidden: You can only access your own data"})
+func GetAllUserHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := helper.ParseList(c, repository.UserList)
+		if !ok {
+			return
+		}
+		result, total, err := users.List(model.RoleUser, query)
+		if err != nil {
+			c.Error(err)
 			return
 		}
+		c.JSON(200, helper.NewListResult(c, query, total).Response(model.UsersToMap(result)))
 	}
-
-	c.JSON(200, user.ToMap())
 }
 
-func UpdateUserHandler(c *gin.Context) {
-	id := c.Param("id")
-
-	// handle role
-	currentUserID, role, valid := helper.CheckUserRoleAndID(c, "user", "admin")
-	if !valid {
-		return
-	}
-
-	// Pastikan user ada
-	var user model.User
-	if err := config.DB.First(&user, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User not found"})
-		return
-	}
-
-	// Periksa akses berdasarkan role
-	if role == "user" {
-		// Pastikan user hanya bisa mengakses datanya sendiri
-		if fmt.Sprintf("%v", currentUserID) != id {
-			c.JSON(403, gin.H{"error": "Forbidden: You can only access your own data"})
+func GetUserHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
 			return
 		}
-	}
-
-	// Memvalidasi input dengan Middleware ValidateInput.
-	updatedData, valid := helper.ValidationHelper(c, middleware.UpdateSchema{})
-	if !valid {
-		return
-	}
 
-	// Perbarui field yang diberikan
-	if updatedData.Name != "" {
-		user.Name = updatedData.Name
-	}
-	if updatedData.Email != "" {
-		user.Email = updatedData.Email
-	}
-	// Jika password diperbarui, hash terlebih dahulu
-	if updatedData.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedData.Password), bcrypt.DefaultCost)
+		// Pastikan user ada
+		user, err := users.Find(helper.ParamID(c, "id"))
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to hash password"})
+			c.Error(apperror.NotFound(err, apperror.CodeUserNotFound, "User not found"))
 			return
 		}
-		user.Password = string(hashedPassword)
-	}
 
-	if err := config.DB.Save(&user).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(200, gin.H{"message": "User updated successfully", "user": user.ToMap()})
-}
-
-func DeleteUserHandler(c *gin.Context) {
-	id := c.Param("id")
+		// Tanpa permission user:manage, akun hanya bisa mengakses datanya sendiri
+		if !middleware.HasPermission(c, model.PermissionUserManage) && user.ID != currentUserID {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: You can only access your own data"))
+			return
+		}
 
-	// handle role
-	_, _, valid := helper.CheckUserRoleAndID(c, "admin")
-	if !valid {
-		return
+		response.JSON(c, 200, user.ToMap())
 	}
+}
 
-	// Pastikan user ada
-	var user model.User
-	if err := config.DB.First(&user, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User not found"})
-		return
-	}
+// UpdateUserHandler memperbarui akun. Mengganti email membatalkan verifikasinya dan mengirim
+// tautan verifikasi ke email yang baru.
+func UpdateUserHandler(users repository.UserRepository, mailer mail.Mailer, verificationTTL time.Duration, publicURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
+		}
 
-	// Mulai transaksi database
-	tx := config.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			c.JSON(500, gin.H{"error": "Failed to delete user and related data"})
-		}
-	}()
-
-	// Cari semua transaksi milik user
-	var transactions []model.Transaction
-	if err := tx.Where("user_id = ?", id).Find(&transactions).Error; err != nil {
-		tx.Rollback()
-		c.JSON(500, gin.H{"error": "Failed to find user transactions"})
-		return
-	}
+		// Pastikan user ada
+		user, err := users.Find(helper.ParamID(c, "id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeUserNotFound, "User not found"))
+			return
+		}
 
-	// Proses jika status detail adalah "loaned", kembalikan stok item
-	for _, transaction := range transactions {
-		if transaction.DetailID == nil {
-			continue
+		// Tanpa permission user:manage, akun hanya bisa mengakses datanya sendiri
+		if !middleware.HasPermission(c, model.PermissionUserManage) && user.ID != currentUserID {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: You can only access your own data"))
+			return
 		}
 
-		var detail model.Detail
-		if err := tx.First(&detail, *transaction.DetailID).Error; err != nil {
-			tx.Rollback()
-			c.JSON(404, gin.H{"error": "Detail not found"})
+		// Memvalidasi input dengan Middleware ValidateInput.
+		updatedData, valid := helper.ValidationHelper(c, middleware.UpdateSchema{})
+		if !valid {
 			return
 		}
 
-		if detail.Status == "loaned" {
-			var item model.Item
-			if err := tx.First(&item, transaction.ItemID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(404, gin.H{"error": "Item not found"})
+		// Perbarui field yang diberikan
+		if updatedData.Name != "" {
+			user.Name = updatedData.Name
+		}
+		emailChanged := updatedData.Email != "" && updatedData.Email != user.Email
+		if emailChanged {
+			user.Email = updatedData.Email
+			user.EmailVerifiedAt = nil
+		}
+		// Jika password diperbarui, hash terlebih dahulu
+		if updatedData.Password != "" {
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedData.Password), bcrypt.DefaultCost)
+			if err != nil {
+				c.Error(fmt.Errorf("failed to hash password: %w", err))
 				return
 			}
+			user.Password = string(hashedPassword)
+		}
 
-			item.Stock += transaction.Quantity
-			if err := tx.Save(&item).Error; err != nil {
-				tx.Rollback()
-				c.JSON(500, gin.H{"error": "Failed to update item stock"})
-				return
+		if err := users.Save(&user); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if emailChanged {
+			if err := sendVerificationEmail(c, users, mailer, verificationTTL, publicURL, user); err != nil {
+				middleware.Logger(c).Error("Failed to send verification email", "user_id", user.ID, "error", err.Error())
 			}
 		}
+
+		response.Message(c, 200, "User updated successfully", user.ToMap())
 	}
+}
 
-	// Hapus detail terkait
-	var detailIDs []uint
-	for _, transaction := range transactions {
-		if transaction.DetailID != nil {
-			detailIDs = append(detailIDs, *transaction.DetailID)
+func DeleteUserHandler(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUserID, valid := helper.CurrentUserID(c)
+		if !valid {
+			return
 		}
-	}
-	if len(detailIDs) > 0 {
-		if err := tx.Unscoped().Where("id IN (?)", detailIDs).Delete(&model.Detail{}).Error; err != nil {
-			tx.Rollback()
-			c.JSON(500, gin.H{"error": "Failed to delete user details"})
+
+		// Pastikan user ada
+		user, err := users.Find(helper.ParamID(c, "id"))
+		if err != nil {
+			c.Error(apperror.NotFound(err, apperror.CodeUserNotFound, "User not found"))
 			return
 		}
-	}
 
-	// Hapus transaksi terkait
-	if err := tx.Unscoped().Where("user_id = ?", id).Delete(&model.Transaction{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(500, gin.H{"error": "Failed to delete user transactions"})
-		return
-	}
+		// Hapus user beserta keranjang dan detailnya, barang yang masih dipinjam dikembalikan ke stok
+		if err := users.Delete(&user, currentUserID); err != nil {
+			c.Error(err)
+			return
+		}
 
-	// Hapus user
-	if err := tx.Unscoped().Delete(&user).Error; err != nil {
-		tx.Rollback()
-		c.JSON(500, gin.H{"error": "Failed to delete user"})
-		return
+		response.Message(c, 200, "User and related data deleted successfully", nil)
 	}
-
-	tx.Commit()
-
-	c.JSON(200, gin.H{"message": "User and related data deleted successfully"})
 }
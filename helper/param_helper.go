@@ -0,0 +1,17 @@
+package helper
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParamID membaca parameter path berupa ID. Nilai yang bukan angka dianggap 0 sehingga
+// pencarian berikutnya berakhir not found, sama seperti ID yang tidak ada.
+func ParamID(c *gin.Context, name string) uint {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
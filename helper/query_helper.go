@@ -0,0 +1,158 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// ListMeta berisi informasi pagination untuk response envelope
+type ListMeta struct {
+	Page       int   `json:"page"`
+	PerPage    int   `json:"per_page"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// ListLinks berisi tautan ke halaman lain, nil jika tidak ada
+type ListLinks struct {
+	Self string  `json:"self"`
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
+}
+
+// ListResult adalah meta dan links sebuah halaman yang dikirim bersama data
+type ListResult struct {
+	Meta  ListMeta  `json:"meta"`
+	Links ListLinks `json:"links"`
+}
+
+// Response membungkus data list dalam envelope {"data", "meta", "links"}
+func (r ListResult) Response(data interface{}) response.Envelope {
+	return response.Envelope{Data: data, Meta: r.Meta, Links: r.Links}
+}
+
+// ParseList membaca ?page, ?per_page, filter, rentang waktu dan ?sort sesuai spec. Parameter
+// yang tidak ada di spec diabaikan, kecuali kunci sort yang tidak dikenal. Jika ada parameter
+// yang tidak valid, response 400 dikirim dan ok bernilai false.
+func ParseList(c *gin.Context, spec repository.ListSpec) (repository.ListQuery, bool) {
+	query, err := parseList(c, spec)
+	if err != nil {
+		c.Error(apperror.New(400, apperror.CodeInvalidQuery, err.Error()))
+		return query, false
+	}
+	return query, true
+}
+
+func parseList(c *gin.Context, spec repository.ListSpec) (repository.ListQuery, error) {
+	query := repository.ListQuery{Filters: map[string]string{}, Ranges: map[string]repository.TimeRange{}}
+
+	var err error
+	query.Page, query.PerPage, err = parsePage(c)
+	if err != nil {
+		return query, err
+	}
+
+	for param := range spec.Filters {
+		if value, ok := c.GetQuery(param); ok && value != "" {
+			query.Filters[param] = value
+		}
+	}
+
+	for prefix := range spec.TimeFilters {
+		var r repository.TimeRange
+		if value := c.Query(prefix + "_after"); value != "" {
+			if r.After, err = parseTime(value); err != nil {
+				return query, fmt.Errorf("Query '%s_after' must be a date (YYYY-MM-DD) or RFC3339 time.", prefix)
+			}
+		}
+		if value := c.Query(prefix + "_before"); value != "" {
+			if r.Before, err = parseTime(value); err != nil {
+				return query, fmt.Errorf("Query '%s_before' must be a date (YYYY-MM-DD) or RFC3339 time.", prefix)
+			}
+		}
+		if !r.After.IsZero() || !r.Before.IsZero() {
+			query.Ranges[prefix] = r
+		}
+	}
+
+	query.Sorts, err = parseSort(c.DefaultQuery("sort", spec.DefaultSort), spec)
+	return query, err
+}
+
+// NewListResult membuat meta dan links untuk halaman query dari total data
+func NewListResult(c *gin.Context, query repository.ListQuery, total int64) ListResult {
+	var result ListResult
+
+	totalPages := int(math.Ceil(float64(total) / float64(query.PerPage)))
+	result.Meta = ListMeta{Page: query.Page, PerPage: query.PerPage, Total: total, TotalPages: totalPages}
+	result.Links.Self = pageLink(c, query.Page)
+	if query.Page < totalPages {
+		next := pageLink(c, query.Page+1)
+		result.Links.Next = &next
+	}
+	if query.Page > 1 {
+		prev := pageLink(c, query.Page-1)
+		result.Links.Prev = &prev
+	}
+	return result
+}
+
+func parsePage(c *gin.Context) (int, int, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return 0, 0, fmt.Errorf("Query 'page' must be a positive number.")
+	}
+
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(defaultPerPage)))
+	if err != nil || perPage < 1 || perPage > maxPerPage {
+		return 0, 0, fmt.Errorf("Query 'per_page' must be between 1 and %d.", maxPerPage)
+	}
+	return page, perPage, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func parseSort(sort string, spec repository.ListSpec) ([]repository.Sort, error) {
+	var sorts []repository.Sort
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(key, "-")
+		key = strings.TrimPrefix(key, "-")
+		if _, ok := spec.Sorts[key]; !ok {
+			return nil, fmt.Errorf("Query 'sort' has unknown key '%s'.", key)
+		}
+		sorts = append(sorts, repository.Sort{Key: key, Desc: desc})
+	}
+	return sorts, nil
+}
+
+func pageLink(c *gin.Context, page int) string {
+	u := *c.Request.URL
+	values := u.Query()
+	values.Set("page", strconv.Itoa(page))
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,112 @@
+package helper
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testSpec = repository.ListSpec{
+	Filters:     map[string]string{"stock": "stock"},
+	TimeFilters: map[string]string{"created": "created_at"},
+	Sorts:       map[string]string{"id": "id", "name": "name", "stock": "stock"},
+	DefaultSort: "id",
+}
+
+func TestParseList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var parsed repository.ListQuery
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.GET("/item", func(c *gin.Context) {
+		query, ok := ParseList(c, testSpec)
+		if !ok {
+			return
+		}
+		parsed = query
+		// Total dari repository, di sini dianggap ada 3 baris yang cocok
+		c.JSON(200, NewListResult(c, query, 3).Response([]string{}))
+	})
+
+	get := func(url string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	code, body := get("/item?stock=1&sort=-name&per_page=2&created_after=2026-01-01&ignored=x")
+	if code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	want := repository.ListQuery{
+		Filters: map[string]string{"stock": "1"},
+		Ranges: map[string]repository.TimeRange{
+			"created": {After: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Sorts:   []repository.Sort{{Key: "name", Desc: true}},
+		Page:    1,
+		PerPage: 2,
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("unexpected query:\n got %+v\nwant %+v", parsed, want)
+	}
+
+	meta := body["meta"].(map[string]interface{})
+	links := body["links"].(map[string]interface{})
+	if meta["total"].(float64) != 3 || meta["total_pages"].(float64) != 2 {
+		t.Errorf("unexpected meta: %v", meta)
+	}
+	if links["next"] != "/item?created_after=2026-01-01&ignored=x&page=2&per_page=2&sort=-name&stock=1" || links["prev"] != nil {
+		t.Errorf("unexpected links: %v", links)
+	}
+
+	_, body = get("/item?per_page=2&page=2")
+	links = body["links"].(map[string]interface{})
+	if links["next"] != nil || links["prev"] != "/item?page=1&per_page=2" {
+		t.Errorf("unexpected links on last page: %v", links)
+	}
+	if !reflect.DeepEqual(parsed.Sorts, []repository.Sort{{Key: "id"}}) {
+		t.Errorf("expected default sort, got %+v", parsed.Sorts)
+	}
+
+	for _, url := range []string{"/item?sort=password", "/item?per_page=500", "/item?page=0", "/item?created_before=yesterday"} {
+		if code, _ := get(url); code != 400 {
+			t.Errorf("expected 400 for %s, got %d", url, code)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	cases := []struct {
+		sort string
+		want []repository.Sort
+	}{
+		{"name", []repository.Sort{{Key: "name"}}},
+		{"-stock, name", []repository.Sort{{Key: "stock", Desc: true}, {Key: "name"}}},
+		{"", nil},
+	}
+	for _, tc := range cases {
+		got, err := parseSort(tc.sort, testSpec)
+		if err != nil {
+			t.Errorf("parseSort(%q): unexpected error %v", tc.sort, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseSort(%q) = %+v, want %+v", tc.sort, got, tc.want)
+		}
+	}
+
+	if _, err := parseSort("password", testSpec); err == nil {
+		t.Error("expected error for unknown sort key")
+	}
+}
@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"Gin-Inventory/apperror"
 	"Gin-Inventory/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -10,14 +11,13 @@ import (
 func ValidationHelper[T any](c *gin.Context, schema T) (T, bool) {
 	// Bind JSON ke struct
 	if err := c.ShouldBindJSON(&schema); err != nil {
-		errors := middleware.FormatValidationErrors(err)
-		c.JSON(400, gin.H{"errors": errors})
+		c.Error(apperror.Validation(middleware.FormatValidationErrors(err)))
 		return schema, false
 	}
 
 	// Validasi menggunakan ValidateInput
 	if validationErrors := middleware.ValidateInput(schema); validationErrors != nil {
-		c.JSON(400, gin.H{"errors": validationErrors})
+		c.Error(apperror.Validation(validationErrors))
 		return schema, false
 	}
 
@@ -0,0 +1,171 @@
+package loan
+
+import (
+	"errors"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxCalendarDays membatasi panjang rentang tanggal yang dihitung sekaligus
+const MaxCalendarDays = 366
+
+// ErrInvalidRange dikembalikan jika rentang tanggal terbalik atau terlalu panjang
+var ErrInvalidRange = errors.New("invalid date range")
+
+// ReservingStatuses adalah status detail yang memakai unit item selama rentang Out sampai Entry
+var ReservingStatuses = []string{StatusPending, StatusApproved, StatusLoaned, StatusOverdue}
+
+// DayAvailability adalah jumlah unit yang dipesan dan yang masih tersedia pada satu hari
+type DayAvailability struct {
+	Date      string `json:"date"`
+	Reserved  int    `json:"reserved"`
+	Available int    `json:"available"`
+}
+
+// Calendar adalah ketersediaan sebuah item per hari
+type Calendar struct {
+	ItemID   uint              `json:"item_id"`
+	ItemName string            `json:"item_name"`
+	Capacity int               `json:"capacity"`
+	Days     []DayAvailability `json:"days"`
+}
+
+// reservation adalah satu baris transaksi yang memakai unit item
+type reservation struct {
+	Out       time.Time
+	Entry     time.Time
+	Status    string
+	CreatedAt time.Time
+	Quantity  int
+}
+
+// Capacity menghitung jumlah unit yang dimiliki: stok di gudang ditambah unit yang sedang dipinjam.
+// Item.Stock sudah dikurangi saat checkout, jadi unit yang keluar harus dihitung kembali.
+func Capacity(db *gorm.DB, item model.Item) (int, error) {
+	var out int
+	err := reservationQuery(db, item.ID, []string{StatusLoaned, StatusOverdue}).
+		Select("COALESCE(SUM(t.quantity), 0)").
+		Scan(&out).Error
+	return item.Stock + out, err
+}
+
+// ItemCalendar menghitung ketersediaan item untuk setiap hari dari from sampai to (inklusif)
+// berdasarkan detail pending, approved, loaned dan overdue yang rentang tanggalnya beririsan.
+func ItemCalendar(db *gorm.DB, itemID uint, from, to time.Time) (Calendar, error) {
+	return itemCalendar(db, itemID, from, to, 0)
+}
+
+// itemCalendar menghitung ItemCalendar tanpa reservasi detail excludeDetailID (0 berarti semua dihitung)
+func itemCalendar(db *gorm.DB, itemID uint, from, to time.Time, excludeDetailID uint) (Calendar, error) {
+	var calendar Calendar
+
+	from, to = day(from), day(to)
+	if to.Before(from) {
+		return calendar, newError(ErrInvalidRange, "Date 'to' must not be before 'from'")
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > MaxCalendarDays {
+		return calendar, newError(ErrInvalidRange, "Date range must not exceed %d days", MaxCalendarDays)
+	}
+
+	var item model.Item
+	if err := db.First(&item, itemID).Error; err != nil {
+		return calendar, newError(ErrItemNotFound, "Item with ID %d not found", itemID)
+	}
+
+	capacity, err := Capacity(db, item)
+	if err != nil {
+		return calendar, err
+	}
+
+	var reservations []reservation
+	query := reservationQuery(db, itemID, ReservingStatuses)
+	if excludeDetailID != 0 {
+		query = query.Where("d.id <> ?", excludeDetailID)
+	}
+	err = query.Select("d.out, d.entry, d.status, d.created_at, t.quantity").Scan(&reservations).Error
+	if err != nil {
+		return calendar, err
+	}
+
+	calendar = Calendar{ItemID: item.ID, ItemName: item.Name, Capacity: capacity}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		reserved := 0
+		for _, r := range reservations {
+			if r.covers(date) {
+				reserved += r.Quantity
+			}
+		}
+		calendar.Days = append(calendar.Days, DayAvailability{
+			Date:      date.Format("2006-01-02"),
+			Reserved:  reserved,
+			Available: capacity - reserved,
+		})
+	}
+	return calendar, nil
+}
+
+// reservationQuery memilih transaksi item yang detailnya berstatus salah satu dari statuses.
+// Tabel diberi alias karena "transaction" adalah kata kunci di beberapa database.
+func reservationQuery(db *gorm.DB, itemID uint, statuses []string) *gorm.DB {
+	return db.Table("?", clause.Table{Name: "transaction", Alias: "t"}).
+		Joins("JOIN ? ON d.id = t.detail_id AND d.deleted_at IS NULL", clause.Table{Name: "detail", Alias: "d"}).
+		Where("t.item_id = ? AND t.deleted_at IS NULL AND d.status IN ?", itemID, statuses)
+}
+
+// CheckAvailability memastikan setiap item pada quantities (item_id -> jumlah) masih punya
+// unit yang cukup di setiap hari dari from sampai to.
+func CheckAvailability(db *gorm.DB, quantities map[uint]int, from, to time.Time) error {
+	return CheckAvailabilityExcept(db, quantities, from, to, 0)
+}
+
+// CheckAvailabilityExcept sama dengan CheckAvailability tetapi tidak menghitung reservasi detail
+// excludeDetailID, dipakai saat tanggal detail yang sudah diajukan diubah
+func CheckAvailabilityExcept(db *gorm.DB, quantities map[uint]int, from, to time.Time, excludeDetailID uint) error {
+	for itemID, quantity := range quantities {
+		calendar, err := itemCalendar(db, itemID, from, to, excludeDetailID)
+		if err != nil {
+			return err
+		}
+		for _, d := range calendar.Days {
+			if d.Available < quantity {
+				return newError(ErrInsufficientStock, "Item %s only has %d unit(s) available on %s. Requested: %d",
+					calendar.ItemName, available(d), d.Date, quantity)
+			}
+		}
+	}
+	return nil
+}
+
+// covers menentukan apakah reservasi memakai unit pada hari date. Tanggal Out kosong dihitung
+// sejak detail dibuat, tanggal Entry kosong berarti belum ada tanggal kembali. Barang overdue
+// tetap dihitung keluar sampai benar-benar dikembalikan.
+func (r reservation) covers(date time.Time) bool {
+	start := day(r.Out)
+	if r.Out.IsZero() {
+		start = day(r.CreatedAt)
+	}
+	if date.Before(start) {
+		return false
+	}
+	if r.Entry.IsZero() || r.Status == StatusOverdue {
+		return true
+	}
+	return !date.After(day(r.Entry))
+}
+
+func available(d DayAvailability) int {
+	if d.Available < 0 {
+		return 0
+	}
+	return d.Available
+}
+
+// day memotong waktu ke awal hari kalender tanpa mengubah tanggalnya
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
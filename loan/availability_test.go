@@ -0,0 +1,63 @@
+package loan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestItemCalendar(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Item{}, &model.Detail{}, &model.Transaction{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	date := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	// Satu unit sedang dipinjam sehingga stok di gudang tinggal 2, total unit 3
+	item := model.Item{Name: "Projector", Stock: 2}
+	db.Create(&item)
+	reserve := func(status string, out, entry time.Time, quantity int) {
+		detail := model.Detail{Code: status, Status: status, Out: out, Entry: entry}
+		db.Create(&detail)
+		db.Create(&model.Transaction{UserID: 1, ItemID: item.ID, DetailID: &detail.ID, Quantity: quantity, Status: "pending"})
+	}
+	reserve(StatusLoaned, date(1), date(5), 1)
+	reserve(StatusApproved, date(4), date(6), 2)
+	reserve(StatusRejected, date(4), date(4), 3)
+
+	calendar, err := ItemCalendar(db, item.ID, date(3), date(7))
+	if err != nil {
+		t.Fatalf("ItemCalendar failed: %v", err)
+	}
+	if calendar.Capacity != 3 {
+		t.Errorf("expected capacity 3, got %d", calendar.Capacity)
+	}
+	expected := []int{2, 0, 0, 1, 3}
+	for i, d := range calendar.Days {
+		if d.Available != expected[i] {
+			t.Errorf("expected %d available on %s, got %d", expected[i], d.Date, d.Available)
+		}
+	}
+
+	if err := CheckAvailability(db, map[uint]int{item.ID: 1}, date(6), date(7)); err != nil {
+		t.Errorf("expected 6-7 March to be available, got %v", err)
+	}
+	if err := CheckAvailability(db, map[uint]int{item.ID: 1}, date(5), date(6)); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("expected ErrInsufficientStock, got %v", err)
+	}
+	if _, err := ItemCalendar(db, item.ID, date(7), date(3)); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+	if _, err := ItemCalendar(db, 999, date(3), date(7)); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package loan
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+)
+
+// maxCodeAttempts adalah batas percobaan ulang jika kode bentrok dengan pengajuan paralel
+const maxCodeAttempts = 5
+
+// CodeGenerator membuat kode peminjaman berformat PREFIX-TANGGAL-URUTAN-CEK, misalnya
+// IVT-20261017-0001-5. Urutan dimulai dari 1 setiap hari dan digit cek (Luhn) dihitung
+// dari semua angka setelah prefix sehingga salah ketik satu digit langsung terdeteksi.
+type CodeGenerator struct {
+	Prefix string
+	// DateFormat adalah layout tanggal Go, kosong berarti kode tanpa bagian tanggal
+	DateFormat string
+	// Digits adalah lebar minimum nomor urut, default 4
+	Digits int
+}
+
+// Next menghasilkan kode berikutnya untuk hari now. Kode unik dijamin oleh unique index
+// pada Detail.Code, pemanggil harus mengulang jika terjadi bentrok (lihat CreateWithCode).
+func (g CodeGenerator) Next(tx *gorm.DB, now time.Time) (string, error) {
+	base := g.base(now)
+
+	// Detail yang dihapus tetap dihitung karena kodenya masih tercatat di unique index
+	var codes []string
+	if err := tx.Unscoped().Model(&model.Detail{}).Where("code LIKE ?", base+"%").Pluck("code", &codes).Error; err != nil {
+		return "", err
+	}
+
+	sequence := 0
+	for _, code := range codes {
+		if !strings.HasPrefix(code, base) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(code, base), "-")
+		if len(parts) != 2 {
+			continue
+		}
+		if n, err := strconv.Atoi(parts[0]); err == nil && n > sequence {
+			sequence = n
+		}
+	}
+
+	digits := g.Digits
+	if digits <= 0 {
+		digits = 4
+	}
+	body := base + fmt.Sprintf("%0*d", digits, sequence+1)
+	return fmt.Sprintf("%s-%d", body, luhn(strings.TrimPrefix(body, g.Prefix))), nil
+}
+
+// Valid memeriksa digit cek pada kode yang dibuat generator ini
+func (g CodeGenerator) Valid(code string) bool {
+	i := strings.LastIndex(code, "-")
+	if i < 0 || !strings.HasPrefix(code, g.Prefix) {
+		return false
+	}
+	check, err := strconv.Atoi(code[i+1:])
+	return err == nil && check == luhn(strings.TrimPrefix(code[:i], g.Prefix))
+}
+
+func (g CodeGenerator) base(now time.Time) string {
+	base := g.Prefix + "-"
+	if g.DateFormat != "" {
+		base += now.Format(g.DateFormat) + "-"
+	}
+	return base
+}
+
+// CreateWithCode menjalankan create dalam satu transaksi dengan kode baru, dan mengulang
+// dengan kode berikutnya jika kode sudah dipakai oleh pengajuan lain.
+func CreateWithCode(db *gorm.DB, g CodeGenerator, now time.Time, create func(tx *gorm.DB, code string) error) error {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			code, err := g.Next(tx, now)
+			if err != nil {
+				return err
+			}
+			return create(tx, code)
+		})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+	}
+	return newError(ErrConflict, "Could not generate a unique loan code, please retry")
+}
+
+// luhn menghitung digit cek Luhn dari semua angka pada s, karakter lain diabaikan
+func luhn(s string) int {
+	sum := 0
+	double := true
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,69 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCodeGenerator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Detail{}, &model.Transaction{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	g := CodeGenerator{Prefix: "IVT", DateFormat: "20060102"}
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+
+	create := func() string {
+		var code string
+		err := CreateWithCode(db, g, now, func(tx *gorm.DB, c string) error {
+			code = c
+			return tx.Create(&model.Detail{Code: c, Status: StatusPending}).Error
+		})
+		if err != nil {
+			t.Fatalf("CreateWithCode failed: %v", err)
+		}
+		return code
+	}
+
+	first, second := create(), create()
+	if first != "IVT-20261017-0001-4" || second != "IVT-20261017-0002-2" {
+		t.Errorf("unexpected codes %s, %s", first, second)
+	}
+	if luhn("7992739871") != 3 {
+		t.Errorf("expected Luhn check digit 3, got %d", luhn("7992739871"))
+	}
+	if !g.Valid(first) || g.Valid("IVT-20261017-0001-3") || g.Valid("IVT-20261017-0010-4") {
+		t.Errorf("check digit validation failed")
+	}
+
+	// Kode yang bentrok membuat seluruh transaksi diulang dengan kode berikutnya
+	attempts := 0
+	var code string
+	err = CreateWithCode(db, g, now, func(tx *gorm.DB, c string) error {
+		attempts++
+		code = c
+		if attempts == 1 {
+			if err := tx.Create(&model.Detail{Code: c, Status: StatusPending}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&model.Detail{Code: c, Status: StatusPending}).Error
+	})
+	if err != nil || attempts != 2 || code != "IVT-20261017-0003-0" {
+		t.Errorf("expected retry to succeed with the same next code, got %s after %d attempts (%v)", code, attempts, err)
+	}
+
+	// Urutan dimulai lagi dari 1 keesokan harinya
+	if code := g.base(now.AddDate(0, 0, 1)); code != "IVT-20261018-" {
+		t.Errorf("unexpected base %s", code)
+	}
+}
@@ -0,0 +1,36 @@
+package loan
+
+import (
+	"errors"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+)
+
+// MarkOverdue menandai semua peminjaman berstatus loaned yang melewati tanggal Entry sebagai overdue.
+// Entry adalah tanggal kembali, jadi peminjaman baru terlambat setelah hari Entry berakhir.
+// Mengembalikan jumlah detail yang ditandai.
+func MarkOverdue(db *gorm.DB, now time.Time) (int, error) {
+	var details []model.Detail
+	err := db.Where("status = ? AND entry > ? AND entry <= ?", StatusLoaned, time.Time{}, now.Add(-24*time.Hour)).
+		Find(&details).Error
+	if err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, detail := range details {
+		_, err := Apply(db, detail.ID, ActionOverdue, Actor{System: true, Time: now})
+		// Detail yang sudah dikembalikan di antara query dan Apply dilewati
+		if errors.Is(err, ErrConflict) || errors.Is(err, ErrInvalidTransition) {
+			continue
+		}
+		if err != nil {
+			return marked, err
+		}
+		marked++
+	}
+	return marked, nil
+}
@@ -0,0 +1,52 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMarkOverdue(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Detail{}, &model.Transaction{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	late := model.Detail{Code: "late", Status: StatusLoaned, Entry: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)}
+	dueToday := model.Detail{Code: "due-today", Status: StatusLoaned, Entry: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)}
+	noEntry := model.Detail{Code: "no-entry", Status: StatusLoaned}
+	returned := model.Detail{Code: "returned", Status: StatusReturned, Entry: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	for _, detail := range []*model.Detail{&late, &dueToday, &noEntry, &returned} {
+		db.Create(detail)
+	}
+
+	marked, err := MarkOverdue(db, now)
+	if err != nil {
+		t.Fatalf("MarkOverdue failed: %v", err)
+	}
+	if marked != 1 {
+		t.Fatalf("expected 1 overdue detail, got %d", marked)
+	}
+
+	db.First(&late, late.ID)
+	if late.Status != StatusOverdue || late.OverdueAt == nil || !late.OverdueAt.Equal(now) {
+		t.Errorf("expected late detail to be overdue at %v, got %s at %v", now, late.Status, late.OverdueAt)
+	}
+	db.First(&dueToday, dueToday.ID)
+	if dueToday.Status != StatusLoaned {
+		t.Errorf("expected detail due today to stay loaned, got %s", dueToday.Status)
+	}
+
+	// Pemeriksaan berikutnya tidak menandai ulang detail yang sama
+	if marked, _ := MarkOverdue(db, now.Add(time.Hour)); marked != 0 {
+		t.Errorf("expected no new overdue details, got %d", marked)
+	}
+}
@@ -0,0 +1,313 @@
+package loan
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"Gin-Inventory/model"
+	"Gin-Inventory/stock"
+
+	"gorm.io/gorm"
+)
+
+// Daftar status Detail (peminjaman)
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusLoaned    = "loaned"
+	StatusReturned  = "returned"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusOverdue   = "overdue"
+	StatusLost      = "lost"
+)
+
+// Daftar aksi yang bisa dijalankan terhadap Detail
+const (
+	ActionApprove  = "approve"
+	ActionReject   = "reject"
+	ActionCancel   = "cancel"
+	ActionResubmit = "resubmit"
+	ActionCheckout = "checkout"
+	ActionReturn   = "return"
+	ActionOverdue  = "overdue"
+	ActionLose     = "lose"
+)
+
+// Jenis error yang dikembalikan state machine, dipakai controller untuk menentukan status HTTP
+var (
+	ErrUnknownAction     = errors.New("unknown action")
+	ErrDetailNotFound    = errors.New("detail not found")
+	ErrInvalidTransition = errors.New("invalid transition")
+	ErrForbidden         = errors.New("forbidden")
+	ErrConflict          = errors.New("conflict")
+	ErrItemNotFound      = errors.New("item not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// Error membawa pesan yang bisa ditampilkan ke klien beserta jenis error-nya
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func newError(kind error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Actor adalah pihak yang menjalankan aksi: akun yang login atau sistem (job terjadwal)
+type Actor struct {
+	UserID      uint
+	Permissions []string
+	System      bool
+	// Time adalah waktu aksi dijalankan, default time.Now()
+	Time time.Time
+}
+
+func (a Actor) now() time.Time {
+	if a.Time.IsZero() {
+		return time.Now()
+	}
+	return a.Time
+}
+
+func (a Actor) hasPermission(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition mendefinisikan satu perpindahan status beserta syarat dan efek sampingnya
+type Transition struct {
+	From []string
+	To   string
+	// Permission yang dibutuhkan, kosong berarti tidak ada permission khusus
+	Permission string
+	// AllowOwner mengizinkan peminjam pemilik detail menjalankan aksi tanpa Permission
+	AllowOwner bool
+	// SystemOnly berarti aksi hanya dijalankan oleh sistem, bukan lewat API
+	SystemOnly bool
+	// Guard dijalankan sebelum status berubah, mengembalikan error jika syarat tidak terpenuhi
+	Guard func(tx *gorm.DB, detail *model.Detail) error
+	// Effect dijalankan dalam transaksi database yang sama setelah status berubah
+	Effect func(tx *gorm.DB, detail *model.Detail, actor Actor) error
+}
+
+// Transitions adalah tabel semua aksi yang diizinkan
+var Transitions = map[string]Transition{
+	ActionApprove: {
+		From:       []string{StatusPending},
+		To:         StatusApproved,
+		Permission: model.PermissionDetailApprove,
+		Guard:      checkAvailability,
+	},
+	ActionReject: {
+		From:       []string{StatusPending, StatusApproved},
+		To:         StatusRejected,
+		Permission: model.PermissionDetailApprove,
+	},
+	ActionCancel: {
+		From:       []string{StatusPending, StatusApproved},
+		To:         StatusCancelled,
+		Permission: model.PermissionLoanManage,
+		AllowOwner: true,
+	},
+	ActionResubmit: {
+		From:       []string{StatusRejected},
+		To:         StatusPending,
+		Permission: model.PermissionDetailApprove,
+		AllowOwner: true,
+		Guard:      checkAvailability,
+	},
+	ActionCheckout: {
+		From:       []string{StatusApproved},
+		To:         StatusLoaned,
+		Permission: model.PermissionDetailApprove,
+		Effect:     deductStock,
+	},
+	ActionReturn: {
+		From:       []string{StatusLoaned, StatusOverdue},
+		To:         StatusReturned,
+		Permission: model.PermissionDetailApprove,
+		Effect:     restoreStock,
+	},
+	ActionOverdue: {
+		From:       []string{StatusLoaned},
+		To:         StatusOverdue,
+		SystemOnly: true,
+		Effect:     recordOverdueAt,
+	},
+	// Barang hilang tidak kembali ke stok karena stok sudah dikurangi saat checkout
+	ActionLose: {
+		From:       []string{StatusLoaned, StatusOverdue},
+		To:         StatusLost,
+		Permission: model.PermissionDetailApprove,
+	},
+}
+
+// Apply menjalankan aksi terhadap detail dalam satu transaksi database. Perubahan status
+// dilakukan secara kondisional sehingga dua aksi paralel pada detail yang sama tidak
+// sama-sama berhasil.
+func Apply(db *gorm.DB, detailID uint, action string, actor Actor) (model.Detail, error) {
+	var detail model.Detail
+
+	transition, ok := Transitions[action]
+	if !ok {
+		return detail, newError(ErrUnknownAction, "Unknown action %s", action)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Transactions").First(&detail, detailID).Error; err != nil {
+			return newError(ErrDetailNotFound, "Detail not found")
+		}
+
+		if !transition.allowedFrom(detail.Status) {
+			return newError(ErrInvalidTransition, "Cannot %s detail with status %s", action, detail.Status)
+		}
+
+		if !transition.allowedFor(actor, &detail) {
+			return newError(ErrForbidden, "Forbidden: You are not allowed to %s this detail", action)
+		}
+
+		if transition.Guard != nil {
+			if err := transition.Guard(tx, &detail); err != nil {
+				return err
+			}
+		}
+
+		previousStatus := detail.Status
+		detail.Status = transition.To
+		result := tx.Model(&detail).Where("status = ?", previousStatus).Update("status", transition.To)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return newError(ErrConflict, "Detail status was changed by another request, please retry")
+		}
+
+		if transition.Effect != nil {
+			return transition.Effect(tx, &detail, actor)
+		}
+		return nil
+	})
+
+	return detail, err
+}
+
+func (t Transition) allowedFrom(status string) bool {
+	for _, from := range t.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Transition) allowedFor(actor Actor, detail *model.Detail) bool {
+	if actor.System {
+		return true
+	}
+	if t.SystemOnly {
+		return false
+	}
+	if t.Permission == "" || actor.hasPermission(t.Permission) {
+		return true
+	}
+	if t.AllowOwner {
+		for _, transaction := range detail.Transactions {
+			if transaction.UserID == actor.UserID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkAvailability memastikan rentang tanggal detail yang disetujui atau diajukan ulang masih
+// bisa dipenuhi menurut kalender ketersediaan. Reservasi detail itu sendiri tidak ikut dihitung.
+// Stok fisik baru diperiksa saat checkout.
+func checkAvailability(tx *gorm.DB, detail *model.Detail) error {
+	quantities := map[uint]int{}
+	for _, transaction := range detail.Transactions {
+		quantities[transaction.ItemID] += transaction.Quantity
+	}
+
+	from := detail.Out
+	if from.IsZero() {
+		from = time.Now()
+	}
+	to := detail.Entry
+	if to.IsZero() || to.Before(from) {
+		to = from
+	}
+	return CheckAvailabilityExcept(tx, quantities, from, to, detail.ID)
+}
+
+// deductStock mengurangi stok lewat ledger saat barang keluar. Pengurangan bersyarat
+// (stock >= quantity) mencegah dua checkout paralel membuat stok menjadi negatif.
+func deductStock(tx *gorm.DB, detail *model.Detail, actor Actor) error {
+	for _, transaction := range detail.Transactions {
+		if err := moveStock(tx, detail, transaction, -transaction.Quantity, stock.ReasonLoan, actor); err != nil {
+			return err
+		}
+
+		// Update status transaksi menjadi finish
+		if err := tx.Model(&model.Transaction{}).Where("id = ?", transaction.ID).UpdateColumn("status", "finish").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreStock mengembalikan quantity ke stok lewat ledger saat barang dikembalikan
+func restoreStock(tx *gorm.DB, detail *model.Detail, actor Actor) error {
+	for _, transaction := range detail.Transactions {
+		if err := moveStock(tx, detail, transaction, transaction.Quantity, stock.ReasonReturn, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func moveStock(tx *gorm.DB, detail *model.Detail, transaction model.Transaction, delta int, reason string, actor Actor) error {
+	movement := model.StockMovement{
+		ItemID:        transaction.ItemID,
+		Delta:         delta,
+		Reason:        reason,
+		DetailID:      &detail.ID,
+		TransactionID: &transaction.ID,
+	}
+	if !actor.System {
+		movement.ActorID = &actor.UserID
+	}
+
+	err := stock.Move(tx, &movement)
+	switch {
+	case errors.Is(err, stock.ErrItemNotFound):
+		return newError(ErrItemNotFound, "Item with ID %d not found", transaction.ItemID)
+	case errors.Is(err, stock.ErrInsufficientStock):
+		var item model.Item
+		tx.First(&item, transaction.ItemID)
+		return newError(ErrInsufficientStock, "Not enough stock available for item %s", item.Name)
+	}
+	return err
+}
+
+// recordOverdueAt mencatat kapan peminjaman mulai terlambat
+func recordOverdueAt(tx *gorm.DB, detail *model.Detail, actor Actor) error {
+	overdueAt := actor.now()
+	detail.OverdueAt = &overdueAt
+	return tx.Model(&model.Detail{}).Where("id = ?", detail.ID).UpdateColumn("overdue_at", overdueAt).Error
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer menyimpan setiap email sebagai file .eml di Dir, berguna untuk development
+// atau pengujian tanpa server SMTP
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	// Nama file diurutkan berdasarkan waktu, alamat tujuan dibersihkan dari karakter path
+	to := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, msg.To)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), to)
+
+	return os.WriteFile(filepath.Join(m.Dir, name), msg.Bytes(m.From), 0o600)
+}
+
+// LogMailer hanya menulis email ke log. Isi email (termasuk token) ikut tercatat, jadi hanya
+// untuk development.
+type LogMailer struct {
+	Logger *slog.Logger
+}
+
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	logger := m.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "Email not sent, MAILER=log", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,44 @@
+// Package mail mengirim email transaksional seperti tautan reset password dan verifikasi email.
+// Handler hanya bergantung pada interface Mailer; implementasinya dipilih lewat env MAILER:
+// SMTPMailer untuk produksi, FileMailer dan LogMailer untuk development dan pengujian.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Mailer mengirim satu email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Message adalah email teks biasa
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Bytes menyusun email lengkap dengan header dalam format RFC 5322
+func (m Message) Bytes(from string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", sanitizeHeader(from))
+	fmt.Fprintf(&b, "To: %s\r\n", sanitizeHeader(m.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", sanitizeHeader(m.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// sanitizeHeader membuang baris baru agar nilai dari user tidak bisa menyisipkan header lain
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
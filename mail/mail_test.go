@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStub adalah server SMTP minimal yang menyimpan perintah dan isi DATA yang diterima
+func smtpStub(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 stub ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 stub")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						received <- lines
+						return
+					}
+					data = strings.TrimRight(data, "\r\n")
+					if data == "." {
+						break
+					}
+					lines = append(lines, data)
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := smtpStub(t)
+	mailer := SMTPMailer{Addr: addr, From: "noreply@example.com", Timeout: 5 * time.Second}
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Reset password\r\nBcc: attacker@example.com",
+		Body:    "Open this link\nhttps://example.com/reset?token=abc",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	var lines []string
+	select {
+	case lines = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stub did not receive the message")
+	}
+	session := strings.Join(lines, "\n")
+	for _, want := range []string{"MAIL FROM:<noreply@example.com>", "RCPT TO:<user@example.com>", "To: user@example.com", "https://example.com/reset?token=abc"} {
+		if !strings.Contains(session, want) {
+			t.Errorf("expected SMTP session to contain %q, got:\n%s", want, session)
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Errorf("expected header injection to be stripped, got %q", line)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := FileMailer{Dir: dir, From: "noreply@example.com"}
+
+	if err := mailer.Send(context.Background(), Message{To: "../user@example.com", Subject: "Verify", Body: "token"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .eml file in %s, got %v", dir, files)
+	}
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), "Subject: Verify") || !strings.HasSuffix(string(content), "token") {
+		t.Errorf("unexpected message content:\n%s", content)
+	}
+}
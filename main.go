@@ -2,31 +2,179 @@ package main
 
 import (
 	"Gin-Inventory/config"
+	"Gin-Inventory/loan"
+	"Gin-Inventory/metrics"
 	"Gin-Inventory/middleware"
+	"Gin-Inventory/repository"
 	"Gin-Inventory/route"
+	"Gin-Inventory/scheduler"
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
-	// Inisialisasi konfigurasi dan koneksi database
-	config.InitConfig()
+	// Baca dan validasi konfigurasi, lalu buka koneksi database
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// Semua log, termasuk dari package log, ditulis sebagai JSON lewat slog
+	logger := config.NewLogger(cfg)
+	slog.SetDefault(logger)
 
-	// Inisialisasi router
-	r := gin.Default()
+	db := config.ConnectDatabase(cfg)
+
+	// migrate dijalankan sebelum pemeriksaan versi skema agar tetap bisa dipakai memperbaiki skema
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrateCommand(db, os.Args[2:])
+		return
+	}
+
+	// Pastikan skema database sesuai dengan binary ini
+	config.PrepareDatabase(cfg, db)
+
+	// Jalankan subcommand CLI jika ada, misalnya create-admin
+	if len(os.Args) > 1 {
+		runCommand(db, os.Args[1:])
+		return
+	}
+
+	// Jalankan job latar belakang: penandaan peminjaman yang terlambat serta pembersihan token dicabut
+	// dan catatan login gagal yang sudah kedaluwarsa
+	jobs := scheduler.New(scheduler.RealClock{})
+	jobs.Add(scheduler.Job{
+		Name:     "mark-overdue",
+		Interval: cfg.OverdueCheckInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			marked, err := loan.MarkOverdue(db.WithContext(ctx), now)
+			if marked > 0 {
+				metrics.LoanTransitions.WithLabelValues(loan.ActionOverdue).Add(float64(marked))
+				slog.Info("Marked loans as overdue", "count", marked)
+			}
+			return err
+		},
+	})
+	revocations := config.NewRevocationStore(cfg, db)
+	jobs.Add(scheduler.Job{
+		Name:     "prune-revoked-tokens",
+		Interval: cfg.TokenPruneInterval,
+		Run:      revocations.Prune,
+	})
+	attempts := config.NewLoginAttemptStore(cfg, db)
+	jobs.Add(scheduler.Job{
+		Name:     "prune-login-attempts",
+		Interval: cfg.LoginAttemptWindow,
+		Run: func(ctx context.Context, now time.Time) error {
+			return attempts.Prune(ctx, now, cfg.LoginAttemptWindow)
+		},
+	})
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobs.Start(jobsCtx)
+
+	registry, err := metrics.NewRegistry(db)
+	if err != nil {
+		log.Fatalf("Failed to set up metrics: %v", err)
+	}
+
+	// Inisialisasi router, log request ditulis LoggingMiddleware sebagai pengganti logger bawaan Gin
+	r := gin.New()
+	// Tanpa TRUSTED_PROXIES, X-Forwarded-For diabaikan agar klien tidak bisa memalsukan IP-nya,
+	// yang dipakai untuk penguncian login per IP dan audit login gagal
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.LoggingMiddleware(logger))
+
+	// Catat metrik HTTP sebelum middleware lain agar request yang dihentikan lebih awal ikut terhitung
+	r.Use(middleware.MetricsMiddleware())
+
+	// Panic dan error dari handler dijawab sebagai application/problem+json
+	r.Use(gin.CustomRecovery(middleware.RecoveryHandler))
+	r.Use(middleware.ErrorHandler())
+	r.NoRoute(middleware.NotFoundHandler)
 
 	// Tambahkan Middleware CORS
 	r.Use(middleware.CORSMiddleware())
 
 	// Set up routes
+	auth := middleware.AuthConfig{
+		DB:         db,
+		Secret:     []byte(cfg.JWTSecret),
+		TokenTTL:   cfg.JWTExpire,
+		RefreshTTL: cfg.RefreshTokenExpire,
+		Store:      revocations,
+		Attempts:   attempts,
+		Lockout: middleware.LockoutPolicy{
+			MaxAttempts:      cfg.LoginMaxAttempts,
+			MaxAttemptsPerIP: cfg.LoginMaxAttemptsPerIP,
+			Lockout:          cfg.LoginLockout,
+			MaxLockout:       cfg.LoginMaxLockout,
+			Window:           cfg.LoginAttemptWindow,
+		},
+		MFARequiredRoles: cfg.MFARequiredRoles,
+		MFAIssuer:        cfg.MFAIssuer,
+		MFAChallengeTTL:  cfg.MFAChallengeExpire,
+		SSO:              config.NewSSOProvider(cfg),
+		SSOStateTTL:      cfg.OIDCStateExpire,
+	}
+	repos := repository.NewGorm(db)
 	api := r.Group("/api/v1")
-	route.SetupUserRoutes(api)
-	route.SetupItemRoutes(api)
+	route.SetupUserRoutes(api, cfg, auth, repos, config.NewMailer(cfg, logger), jobs)
+	route.SetupItemRoutes(api, cfg, auth, repos)
+	route.SetupHealthRoutes(r, db)
+	route.SetupMetricsRoutes(r, cfg, registry)
+
+	// Jalankan server pada port dari konfigurasi sampai menerima SIGINT atau SIGTERM
+	srv := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		// Informasi URL API
+		log.Printf("Server is running at http://localhost%s", cfg.Addr())
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	shutdown(srv, jobs, stopJobs, db, cfg.ShutdownTimeout)
+}
+
+// shutdown berhenti menerima request baru, menunggu request yang sedang berjalan, job latar
+// belakang dan email yang sedang dikirim selesai paling lama timeout, lalu menutup koneksi database
+func shutdown(srv *http.Server, jobs *scheduler.Scheduler, stopJobs context.CancelFunc, db *gorm.DB, timeout time.Duration) {
+	log.Printf("Shutting down, waiting up to %s for requests and jobs to finish", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server did not shut down cleanly: %v", err)
+	}
 
-	// Informasi URL API
-	log.Println("Server is running at http://localhost:8080")
+	stopJobs()
+	if err := jobs.WaitContext(ctx); err != nil {
+		log.Printf("Background jobs did not finish in time: %v", err)
+	}
 
-	// Jalankan server pada port 8080
-	r.Run(":8080")
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// loanStatuses selalu dilaporkan, termasuk yang jumlahnya nol, agar alert tidak kehilangan deret
+var loanStatuses = []string{
+	loan.StatusPending,
+	loan.StatusApproved,
+	loan.StatusLoaned,
+	loan.StatusReturned,
+	loan.StatusRejected,
+	loan.StatusCancelled,
+	loan.StatusOverdue,
+	loan.StatusLost,
+}
+
+// domainCollector membaca metrik domain langsung dari database saat di-scrape sehingga nilainya
+// sama di semua instance dan tidak hilang saat restart
+type domainCollector struct {
+	db *gorm.DB
+
+	loans          *prometheus.Desc
+	oldestPending  *prometheus.Desc
+	itemsOutStock  *prometheus.Desc
+	stockMovements *prometheus.Desc
+}
+
+func newDomainCollector(db *gorm.DB) *domainCollector {
+	return &domainCollector{
+		db: db,
+		loans: prometheus.NewDesc(namespace+"_loans",
+			"Number of loans (Details) by status.", []string{"status"}, nil),
+		oldestPending: prometheus.NewDesc(namespace+"_oldest_pending_loan_age_seconds",
+			"Age of the oldest pending loan, 0 when nothing is pending.", nil, nil),
+		itemsOutStock: prometheus.NewDesc(namespace+"_items_out_of_stock",
+			"Number of items with zero stock.", nil, nil),
+		stockMovements: prometheus.NewDesc(namespace+"_stock_movements_total",
+			"Number of stock ledger entries by reason.", []string{"reason"}, nil),
+	}
+}
+
+func (c *domainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.loans
+	ch <- c.oldestPending
+	ch <- c.itemsOutStock
+	ch <- c.stockMovements
+}
+
+func (c *domainCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db := c.db.WithContext(ctx)
+
+	c.collectLoans(db, ch)
+	c.collectOldestPending(db, ch)
+
+	var outOfStock int64
+	if err := db.Model(&model.Item{}).Where("stock <= 0").Count(&outOfStock).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(c.itemsOutStock, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.itemsOutStock, prometheus.GaugeValue, float64(outOfStock))
+	}
+
+	var movements []struct {
+		Reason string
+		Count  int64
+	}
+	err := db.Model(&model.StockMovement{}).Select("reason, COUNT(*) AS count").Group("reason").Scan(&movements).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.stockMovements, err)
+		return
+	}
+	for _, movement := range movements {
+		ch <- prometheus.MustNewConstMetric(c.stockMovements, prometheus.CounterValue, float64(movement.Count), movement.Reason)
+	}
+}
+
+func (c *domainCollector) collectLoans(db *gorm.DB, ch chan<- prometheus.Metric) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := db.Model(&model.Detail{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.loans, err)
+		return
+	}
+
+	counts := map[string]int64{}
+	for _, status := range loanStatuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.loans, prometheus.GaugeValue, float64(count), status)
+	}
+}
+
+func (c *domainCollector) collectOldestPending(db *gorm.DB, ch chan<- prometheus.Metric) {
+	var details []model.Detail
+	err := db.Where("status = ?", loan.StatusPending).Order("created_at").Limit(1).Find(&details).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.oldestPending, err)
+		return
+	}
+	age := 0.0
+	if len(details) > 0 {
+		age = time.Since(details[0].CreatedAt).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(c.oldestPending, prometheus.GaugeValue, age)
+}
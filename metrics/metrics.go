@@ -0,0 +1,62 @@
+// Package metrics berisi metrik Prometheus untuk HTTP, pool database dan domain peminjaman.
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const namespace = "inventory"
+
+var (
+	// HTTPRequests menghitung request per method, route Gin dan status HTTP
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration mencatat durasi request per method dan route Gin
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// LoanTransitions menghitung aksi state machine peminjaman yang berhasil, misalnya
+	// rate(inventory_loan_transitions_total{action="approve"}[1h]) untuk persetujuan per jam
+	LoanTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "loan_transitions_total",
+		Help:      "Number of successful loan (Detail) transitions by action.",
+	}, []string{"action"})
+)
+
+// NewRegistry membuat registry berisi metrik proses Go, statistik pool database, metrik HTTP
+// dan metrik domain yang dibaca dari database setiap kali /metrics diambil
+func NewRegistry(db *gorm.DB) (*prometheus.Registry, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	for _, collector := range []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(sqlDB, db.Dialector.Name()),
+		HTTPRequests,
+		HTTPRequestDuration,
+		LoanTransitions,
+		newDomainCollector(db),
+	} {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
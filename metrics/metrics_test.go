@@ -0,0 +1,85 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/metrics"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegistryReportsDomainMetrics(t *testing.T) {
+	db, err := repository.OpenSQLite("")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	registry, err := metrics.NewRegistry(db)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	db.Create(&model.Item{Name: "Drill", Stock: 0})
+	db.Create(&model.Item{Name: "Saw", Stock: 2})
+	db.Create(&model.Detail{Code: "a", Status: loan.StatusPending})
+	db.Create(&model.Detail{Code: "b", Status: loan.StatusPending})
+	db.Create(&model.Detail{Code: "c", Status: loan.StatusLoaned})
+	db.Create(&model.StockMovement{ItemID: 2, Delta: 2, Reason: "purchase"})
+
+	expected := `
+# HELP inventory_items_out_of_stock Number of items with zero stock.
+# TYPE inventory_items_out_of_stock gauge
+inventory_items_out_of_stock 1
+# HELP inventory_loans Number of loans (Details) by status.
+# TYPE inventory_loans gauge
+inventory_loans{status="approved"} 0
+inventory_loans{status="cancelled"} 0
+inventory_loans{status="loaned"} 1
+inventory_loans{status="lost"} 0
+inventory_loans{status="overdue"} 0
+inventory_loans{status="pending"} 2
+inventory_loans{status="rejected"} 0
+inventory_loans{status="returned"} 0
+# HELP inventory_stock_movements_total Number of stock ledger entries by reason.
+# TYPE inventory_stock_movements_total counter
+inventory_stock_movements_total{reason="purchase"} 1
+`
+	names := []string{"inventory_items_out_of_stock", "inventory_loans", "inventory_stock_movements_total"}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+
+	// Deret pool database ikut terdaftar
+	if count, err := testutil.GatherAndCount(registry, "go_sql_open_connections"); err != nil || count != 1 {
+		t.Errorf("expected database pool metrics, got %d (%v)", count, err)
+	}
+}
+
+func TestMetricsMiddlewareLabelsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.MetricsMiddleware())
+	r.GET("/items/:id", func(c *gin.Context) { c.Status(200) })
+
+	counter := metrics.HTTPRequests.WithLabelValues("GET", "/items/:id", "200")
+	before := testutil.ToFloat64(counter)
+	for _, path := range []string{"/items/1", "/items/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 2 {
+		t.Errorf("expected 2 requests counted for /items/:id, got %v", got)
+	}
+
+	unmatched := metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")
+	before = testutil.ToFloat64(unmatched)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+	if got := testutil.ToFloat64(unmatched) - before; got != 1 {
+		t.Errorf("expected unmatched request to be counted, got %v", got)
+	}
+}
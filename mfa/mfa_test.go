@@ -0,0 +1,54 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	secret, uri, err := Generate("Gin-Inventory", "admin@example.com")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.HasPrefix(uri, "otpauth://totp/Gin-Inventory:admin@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected provisioning URI %s", uri)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	code, _ := Code(secret, now)
+	step, ok := Validate(secret, code, now)
+	if !ok || step != now.Unix()/Period {
+		t.Errorf("expected current code to be valid at step %d, got %d %v", now.Unix()/Period, step, ok)
+	}
+
+	// Kode periode sebelumnya masih diterima, dua periode sebelumnya tidak
+	previous, _ := Code(secret, now.Add(-Period*time.Second))
+	if step, ok := Validate(secret, previous, now); !ok || step != now.Unix()/Period-1 {
+		t.Errorf("expected previous code to be accepted with its own step, got %d %v", step, ok)
+	}
+	old, _ := Code(secret, now.Add(-2*Period*time.Second))
+	if _, ok := Validate(secret, old, now); ok && old != code && old != previous {
+		t.Error("expected code from two periods ago to be rejected")
+	}
+	if _, ok := Validate(secret, "abcdef", now); ok {
+		t.Error("expected invalid code to be rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := RecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %v (%v)", codes, err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Errorf("unexpected recovery code %q", code)
+		}
+		seen[code] = true
+	}
+	if NormalizeRecoveryCode(" ABCDE-fghij ") != "abcdefghij" {
+		t.Errorf("unexpected normalized code %q", NormalizeRecoveryCode(" ABCDE-fghij "))
+	}
+}
@@ -1,99 +1,227 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"time"
 
-	"Gin-Inventory/config"
+	"Gin-Inventory/apperror"
 	"Gin-Inventory/model"
+	"Gin-Inventory/response"
+	"Gin-Inventory/sso"
+	"Gin-Inventory/store"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
-func LoginHandler(c *gin.Context) {
-	var loginData LoginSchema
+// AuthConfig berisi pengaturan token yang dipakai handler autentikasi dan AuthMiddleware
+type AuthConfig struct {
+	// DB dipakai untuk mencari akun, sesi dan refresh token
+	DB *gorm.DB
+	// Secret adalah kunci HMAC untuk menandatangani access token
+	Secret []byte
+	// TokenTTL adalah masa berlaku access token
+	TokenTTL time.Duration
+	// RefreshTTL adalah masa berlaku refresh token
+	RefreshTTL time.Duration
+	// Store menyimpan token yang dicabut lewat logout
+	Store store.RevocationStore
+	// Attempts menyimpan hitungan login gagal untuk Lockout, nil mematikan penguncian
+	Attempts store.LoginAttemptStore
+	Lockout  LockoutPolicy
+	// MFARequiredRoles adalah role yang hanya boleh memakai token hasil login dengan TOTP
+	MFARequiredRoles []string
+	// MFAIssuer adalah nama aplikasi di URI provisioning TOTP
+	MFAIssuer string
+	// MFAChallengeTTL adalah masa berlaku challenge token login dua langkah
+	MFAChallengeTTL time.Duration
+	// SSO adalah client OpenID Connect untuk login SSO, nil mematikan login SSO
+	SSO *sso.Provider
+	// SSOStateTTL adalah masa berlaku state login SSO sampai callback
+	SSOStateTTL time.Duration
+
+	// mfaOptional mematikan kewajiban MFA di AuthMiddleware, lihat WithoutMFARequirement
+	mfaOptional bool
+}
 
-	if err := c.ShouldBindJSON(&loginData); err != nil {
-		errors := FormatValidationErrors(err)
-		c.JSON(403, gin.H{"errors": errors})
-		return
-	}
+// WithoutMFARequirement mengembalikan salinan konfigurasi yang AuthMiddleware-nya tidak mewajibkan
+// MFA, untuk route yang harus bisa diakses sebelum enrollment TOTP selesai seperti enrollment itu
+// sendiri dan logout
+func (auth AuthConfig) WithoutMFARequirement() AuthConfig {
+	auth.mfaOptional = true
+	return auth
+}
 
-	validationErrors := ValidateInput(loginData)
-	if validationErrors != nil {
-		c.JSON(403, gin.H{"errors": validationErrors})
-		return
-	}
+func LoginHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var loginData LoginSchema
 
-	// Cari pengguna di semua model
-	var user model.User
-	var admin model.Admin
+		if err := c.ShouldBindJSON(&loginData); err != nil {
+			c.Error(apperror.Validation(FormatValidationErrors(err)))
+			return
+		}
 
-	if err := config.DB.Where("email = ?", loginData.Email).First(&user).Error; err != nil {
-		if err := config.DB.Where("email = ?", loginData.Email).First(&admin).Error; err != nil {
-			c.JSON(401, gin.H{"error": "Invalid email or password"})
+		validationErrors := ValidateInput(loginData)
+		if validationErrors != nil {
+			c.Error(apperror.Validation(validationErrors))
+			return
+		}
+
+		// Tolak lebih dulu jika akun atau IP sedang dikunci karena terlalu banyak login gagal
+		if !checkLockout(c, auth, loginData.Email) {
+			return
+		}
+
+		// Cari akun berdasarkan email
+		var user model.User
+		if err := auth.DB.Where("email = ?", loginData.Email).First(&user).Error; err != nil {
+			loginFailed(c, auth, loginData.Email, nil)
+			c.Error(apperror.New(401, apperror.CodeInvalidCredentials, "Invalid email or password"))
 			return
 		}
-	}
 
-	// Verifikasi password
-	if user.ID != 0 {
+		// Verifikasi password
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password)); err != nil {
-			c.JSON(401, gin.H{"error": "Invalid email or password"})
+			loginFailed(c, auth, loginData.Email, &user.ID)
+			c.Error(apperror.New(401, apperror.CodeInvalidCredentials, "Invalid email or password"))
 			return
 		}
-	} else if admin.ID != 0 {
-		if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(loginData.Password)); err != nil {
-			c.JSON(401, gin.H{"error": "Invalid email or password"})
+
+		// Akun yang hanya boleh login lewat SSO. Dijawab setelah password benar agar tidak
+		// membocorkan akun mana yang memakai SSO.
+		if user.PasswordLoginDisabled {
+			c.Error(apperror.New(403, apperror.CodePasswordLoginDisabled, "Password login is disabled for this account, sign in with SSO"))
 			return
 		}
-	} else {
-		c.JSON(401, gin.H{"error": "Invalid email or password"})
-		return
-	}
 
-	// Generate token JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(config.JWTExpireDuration()).Unix(),
-	})
+		// Hitungan login gagal akun dengan TOTP aktif baru dihapus setelah kode TOTP benar agar
+		// tebakan kode tetap dibatasi
+		if user.TOTPEnabledAt == nil {
+			loginSucceeded(c, auth, loginData.Email)
+		}
+		completeLogin(c, auth, user, []string{AMRPassword})
+	}
+}
 
-	if admin.ID != 0 {
-		token = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"user_id": admin.ID,
-			"role":    admin.Role,
-			"exp":     time.Now().Add(config.JWTExpireDuration()).Unix(),
+// completeLogin menyelesaikan login setelah faktor pertama (password atau SSO) lolos. Akun dengan
+// TOTP aktif mendapat challenge token untuk POST /login/mfa, kecuali faktor pertama sudah
+// memakai MFA; selain itu langsung diterbitkan pasangan token.
+func completeLogin(c *gin.Context, auth AuthConfig, user model.User, amr []string) {
+	if user.TOTPEnabledAt != nil && !isMFA(amr) {
+		challenge, err := generateChallengeToken(auth, user.ID, amr)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate challenge token: %w", err))
+			return
+		}
+		response.Message(c, 200, "Two-factor authentication required", gin.H{
+			"mfa_required":    true,
+			"challenge_token": challenge,
+			"expires_in":      int(auth.MFAChallengeTTL.Seconds()),
 		})
+		return
 	}
 
-	tokenString, err := token.SignedString([]byte(config.JWTSecret))
+	// Generate token JWT
+	tokenString, refreshTokenString, err := issueTokenPair(c, auth, user.ID, user.Role, amr)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to generate token"})
+		c.Error(fmt.Errorf("failed to generate token: %w", err))
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"message": "Login successful",
-		"token":   tokenString,
+	response.Message(c, 200, "Login successful", gin.H{
+		"token":         tokenString,
+		"refresh_token": refreshTokenString,
+		"expires_in":    int(auth.TokenTTL.Seconds()),
 	})
 }
 
-func LogoutHandler(c *gin.Context) {
-	// Invalidate token by setting claims exp to time.Now() - 1 (expired token immediately)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": 0,
-		"role":    "",
-		"exp":     -1, // Token expired instantly
-	})
+// LogoutHandler mencabut token yang sedang dipakai sehingga tidak bisa digunakan lagi
+func LogoutHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jti, _ := c.Get("jti")
+		exp, _ := c.Get("exp")
+
+		jtiString, ok := jti.(string)
+		if !ok || jtiString == "" {
+			c.Error(apperror.New(400, apperror.CodeInvalidToken, "Token cannot be revoked: missing jti claim"))
+			return
+		}
 
-	tokenString, err := token.SignedString([]byte(config.JWTSecret))
+		if err := auth.Store.Revoke(jtiString, exp.(time.Time)); err != nil {
+			c.Error(fmt.Errorf("failed to invalidate token: %w", err))
+			return
+		}
+
+		// Cabut juga refresh token dari sesi (perangkat) ini
+		if sessionID, ok := c.Get("session_id"); ok && sessionID.(uint) != 0 {
+			if err := RevokeSession(auth.DB, sessionID.(uint)); err != nil {
+				c.Error(fmt.Errorf("failed to invalidate session: %w", err))
+				return
+			}
+		}
+
+		response.Message(c, 200, "Logout successful", nil)
+	}
+}
+
+// LogoutAllHandler mencabut semua token milik akun saat ini di semua perangkat
+func LogoutAllHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentID, _ := c.Get("current_id")
+
+		if err := RevokeAllTokens(auth, currentID.(uint)); err != nil {
+			c.Error(fmt.Errorf("failed to invalidate sessions: %w", err))
+			return
+		}
+
+		response.Message(c, 200, "All sessions logged out successfully", nil)
+	}
+}
+
+// RevokeAllTokens mencabut semua access token dan refresh token akun, misalnya saat logout dari
+// semua perangkat atau setelah password di-reset
+func RevokeAllTokens(auth AuthConfig, userID uint) error {
+	// iat hanya berpresisi detik, jadi batasnya dibulatkan ke bawah agar token yang diterbitkan
+	// sesudahnya di detik yang sama tetap berlaku. Token lama di detik itu sudah ditolak lewat
+	// sesinya yang dicabut di bawah.
+	if err := auth.Store.RevokeAll(userID, time.Now().Truncate(time.Second)); err != nil {
+		return err
+	}
+	return RevokeAllSessions(auth.DB, userID)
+}
+
+// GenerateToken membuat token JWT dengan jti unik agar dapat dicabut satu per satu.
+// sid menghubungkan access token dengan sesi refresh token-nya, amr berisi metode autentikasi
+// saat login dan mfa menandai login yang memakai faktor kedua.
+func GenerateToken(auth AuthConfig, userID uint, role string, sessionID uint, amr []string) (string, error) {
+	jti, err := generateJTI()
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to invalidate token"})
-		return
+		return "", err
 	}
 
-	// Clear cookie or token
-	c.JSON(200, gin.H{"message": "Logout successful", "token": tokenString})
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":     jti,
+		"user_id": userID,
+		"role":    role,
+		"sid":     sessionID,
+		"amr":     amr,
+		"mfa":     isMFA(amr),
+		"iat":     now.Unix(),
+		"exp":     now.Add(auth.TokenTTL).Unix(),
+	})
+
+	return token.SignedString(auth.Secret)
+}
+
+func generateJTI() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
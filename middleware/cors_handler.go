@@ -10,7 +10,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		// Tambahkan header CORS
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*") // Ubah "*" ke domain tertentu jika perlu
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		// Jika request adalah OPTIONS, berhenti di sini
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"Gin-Inventory/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problem adalah body response error sesuai RFC 7807 (application/problem+json)
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	// Code adalah kode error yang stabil untuk dipakai klien, misalnya ITEM_NOT_FOUND
+	Code      string   `json:"code"`
+	Errors    []string `json:"errors,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+}
+
+// ErrorHandler merender error terakhir yang dicatat handler lewat c.Error sebagai problem+json.
+// Error yang bukan *apperror.Error dipetakan dengan apperror.From, error tak dikenal menjadi 500
+// dan pesan aslinya hanya muncul di log request. Harus dipasang sebelum AuthMiddleware dan handler.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeProblem(c, apperror.From(c.Errors.Last().Err))
+	}
+}
+
+// NotFoundHandler dipasang di router.NoRoute agar route yang tidak ada juga dijawab problem+json
+func NotFoundHandler(c *gin.Context) {
+	c.Error(apperror.New(404, apperror.CodeRouteNotFound, "Route not found"))
+}
+
+// RecoveryHandler dipakai gin.CustomRecovery untuk menjawab panic dengan problem+json 500
+func RecoveryHandler(c *gin.Context, recovered interface{}) {
+	err := apperror.Internal(fmt.Errorf("panic: %v", recovered))
+	c.Error(err)
+	writeProblem(c, err)
+	c.Abort()
+}
+
+func writeProblem(c *gin.Context, err *apperror.Error) {
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(err.Status),
+		Status:    err.Status,
+		Detail:    err.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      err.Code,
+		Errors:    err.Details,
+		RequestID: c.GetString("request_id"),
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.JSON(err.Status, problem)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/stock"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandlerRendersProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecovery(RecoveryHandler))
+	r.Use(ErrorHandler())
+	r.NoRoute(NotFoundHandler)
+	r.GET("/typed", func(c *gin.Context) {
+		c.Error(apperror.New(404, apperror.CodeItemNotFound, "Item not found"))
+	})
+	r.GET("/domain", func(c *gin.Context) {
+		c.Error(stock.ErrInsufficientStock)
+	})
+	r.GET("/internal", func(c *gin.Context) {
+		c.Error(errors.New(`near "SELECT": syntax error`))
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/typed", 404, apperror.CodeItemNotFound},
+		{"/domain", 400, apperror.CodeInsufficientStock},
+		{"/internal", 500, apperror.CodeInternal},
+		{"/panic", 500, apperror.CodeInternal},
+		{"/missing", 404, apperror.CodeRouteNotFound},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+
+		var problem Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != tc.status || problem.Status != tc.status || problem.Code != tc.code {
+			t.Errorf("%s: expected %d %s, got %d %s", tc.path, tc.status, tc.code, w.Code, w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/problem+json") {
+			t.Errorf("%s: expected problem+json, got %s", tc.path, contentType)
+		}
+		if problem.Instance != tc.path || problem.Title == "" {
+			t.Errorf("%s: missing problem fields %+v", tc.path, problem)
+		}
+		// Pesan error internal tidak boleh bocor ke klien
+		if strings.Contains(w.Body.String(), "SELECT") || strings.Contains(w.Body.String(), "boom") {
+			t.Errorf("%s: internal error leaked: %s", tc.path, w.Body.String())
+		}
+	}
+}
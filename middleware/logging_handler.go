@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader adalah header yang membawa ID request dari klien/proxy dan dikembalikan di response
+const RequestIDHeader = "X-Request-ID"
+
+// quietRoutes adalah route yang dipanggil berkala oleh orkestrator dan Prometheus, dicatat di level debug
+var quietRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// LoggingMiddleware memberi setiap request sebuah ID (memakai X-Request-ID dari klien jika valid),
+// menyimpan logger yang membawa ID tersebut untuk dipakai controller lewat Logger(c), lalu
+// mencatat satu baris log per request setelah selesai diproses.
+func LoggingMiddleware(base *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Set("request_id", requestID)
+		c.Set("logger", base.With("request_id", requestID))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("request_id", requestID),
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := c.Get("current_id"); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		if role, ok := c.Get("role"); ok {
+			attrs = append(attrs, slog.Any("role", role))
+		}
+		if apiKey := c.GetString("api_key"); apiKey != "" {
+			attrs = append(attrs, slog.String("api_key", apiKey))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case quietRoutes[route]:
+			level = slog.LevelDebug
+		}
+		base.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Logger mengembalikan logger milik request yang sudah membawa request_id, serta user_id dan role
+// jika AuthMiddleware sudah berjalan. Di luar LoggingMiddleware hasilnya slog.Default().
+func Logger(c *gin.Context) *slog.Logger {
+	logger := slog.Default()
+	if value, ok := c.Get("logger"); ok {
+		logger = value.(*slog.Logger)
+	}
+	if userID, ok := c.Get("current_id"); ok {
+		logger = logger.With("user_id", userID, "role", c.GetString("role"))
+	}
+	return logger
+}
+
+// validRequestID menerima ID dari klien hanya jika pendek dan berisi karakter yang aman untuk log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
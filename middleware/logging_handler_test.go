@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	r := gin.New()
+	r.Use(LoggingMiddleware(base))
+	r.GET("/items/:id", func(c *gin.Context) {
+		// Menggantikan AuthMiddleware
+		c.Set("current_id", uint(7))
+		c.Set("role", "user")
+		Logger(c).Info("inside handler")
+		c.Status(404)
+	})
+
+	// ID dari klien dipakai ulang dan dikembalikan
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/items/3", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("expected request ID to be propagated, got %q", got)
+	}
+
+	var lines []map[string]interface{}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var line map[string]interface{}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("log is not JSON: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected handler log and request log, got %d lines", len(lines))
+	}
+	if lines[0]["request_id"] != "abc-123" || lines[0]["user_id"] != float64(7) {
+		t.Errorf("handler log should carry request and user, got %v", lines[0])
+	}
+	request := lines[1]
+	if request["route"] != "/items/:id" || request["status"] != float64(404) || request["role"] != "user" || request["level"] != "WARN" {
+		t.Errorf("unexpected request log %v", request)
+	}
+
+	// ID yang tidak aman diganti dengan ID baru
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/items/3", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got == "" || got == "bad id\n" {
+		t.Errorf("expected a generated request ID, got %q", got)
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutPolicy mengatur penguncian login setelah gagal berturut-turut. Kegagalan dihitung
+// terpisah per akun dan per alamat IP; setelah batasnya tercapai, subject dikunci selama
+// Lockout, dan setiap kegagalan berikutnya menggandakan lama kunci sampai MaxLockout.
+type LockoutPolicy struct {
+	// MaxAttempts adalah jumlah login gagal per akun sebelum akun dikunci
+	MaxAttempts int
+	// MaxAttemptsPerIP adalah jumlah login gagal dari satu alamat IP sebelum IP dikunci
+	MaxAttemptsPerIP int
+	// Lockout adalah lama kunci pertama
+	Lockout time.Duration
+	// MaxLockout adalah batas lama kunci
+	MaxLockout time.Duration
+	// Window adalah lama kegagalan diingat sejak kegagalan terakhir
+	Window time.Duration
+}
+
+// lockFor menghitung lama kunci setelah failures kegagalan, nol jika belum mencapai limit
+func (p LockoutPolicy) lockFor(failures, limit int) time.Duration {
+	if failures < limit {
+		return 0
+	}
+	lockout := p.Lockout
+	for i := limit; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+// AccountSubject dan IPSubject adalah kunci LoginAttemptStore untuk akun dan alamat IP
+func AccountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLockout menolak login jika akun atau alamat IP sedang dikunci. Header Retry-After diisi
+// dengan sisa waktu kunci terlama.
+func checkLockout(c *gin.Context, auth AuthConfig, email string) bool {
+	if auth.Attempts == nil {
+		return true
+	}
+
+	now := time.Now()
+	var remaining time.Duration
+	for _, subject := range []string{AccountSubject(email), IPSubject(c.ClientIP())} {
+		attempt, err := auth.Attempts.Get(subject)
+		if err != nil {
+			c.Error(err)
+			return false
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.Sub(now) > remaining {
+			remaining = attempt.LockedUntil.Sub(now)
+		}
+	}
+	if remaining <= 0 {
+		return true
+	}
+
+	recordLoginFailure(c, auth, email, nil, model.LoginFailureLocked)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	c.Error(apperror.New(429, apperror.CodeLoginLocked, "Too many failed login attempts, try again later"))
+	return false
+}
+
+// loginFailed mencatat login gagal untuk akun dan alamat IP, lalu mengunci yang melewati batas
+func loginFailed(c *gin.Context, auth AuthConfig, email string, userID *uint) {
+	recordLoginFailure(c, auth, email, userID, model.LoginFailureInvalidCredentials)
+	if auth.Attempts == nil {
+		return
+	}
+
+	now := time.Now()
+	limits := map[string]int{
+		AccountSubject(email):   auth.Lockout.MaxAttempts,
+		IPSubject(c.ClientIP()): auth.Lockout.MaxAttemptsPerIP,
+	}
+	for subject, limit := range limits {
+		failures, err := auth.Attempts.RecordFailure(subject, now, auth.Lockout.Window)
+		if err != nil {
+			Logger(c).Error("Failed to record login failure", "subject", subject, "error", err.Error())
+			continue
+		}
+		if lockout := auth.Lockout.lockFor(failures, limit); lockout > 0 {
+			if err := auth.Attempts.Lock(subject, now.Add(lockout)); err != nil {
+				Logger(c).Error("Failed to lock login", "subject", subject, "error", err.Error())
+				continue
+			}
+			Logger(c).Warn("Login locked after repeated failures", "subject", subject, "failures", failures, "lockout", lockout.String())
+		}
+	}
+}
+
+// loginSucceeded melupakan kegagalan akun. Hitungan per IP tidak dihapus agar satu akun yang
+// valid tidak bisa dipakai untuk mereset batas tebakan dari IP yang sama.
+func loginSucceeded(c *gin.Context, auth AuthConfig, email string) {
+	if auth.Attempts == nil {
+		return
+	}
+	if err := auth.Attempts.Clear(AccountSubject(email)); err != nil {
+		Logger(c).Error("Failed to clear login failures", "error", err.Error())
+	}
+}
+
+// recordLoginFailure menyimpan catatan audit login gagal
+func recordLoginFailure(c *gin.Context, auth AuthConfig, email string, userID *uint, reason string) {
+	failure := model.LoginFailure{
+		Email:     email,
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Reason:    reason,
+	}
+	if err := auth.DB.Create(&failure).Error; err != nil {
+		Logger(c).Error("Failed to write login audit record", "error", err.Error())
+	}
+}
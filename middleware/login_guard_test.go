@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy(t *testing.T) {
+	policy := LockoutPolicy{Lockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	cases := map[int]time.Duration{
+		4: 0,
+		5: time.Minute,
+		6: 2 * time.Minute,
+		7: 4 * time.Minute,
+		8: 8 * time.Minute,
+		9: 10 * time.Minute,
+		// Tidak overflow walau kegagalan terus bertambah
+		500: 10 * time.Minute,
+	}
+	for failures, want := range cases {
+		if got := policy.lockFor(failures, 5); got != want {
+			t.Errorf("lockFor(%d) = %s, want %s", failures, got, want)
+		}
+	}
+
+	if AccountSubject(" Alice@Example.com ") != "account:alice@example.com" {
+		t.Errorf("expected account subject to be normalized, got %q", AccountSubject(" Alice@Example.com "))
+	}
+}
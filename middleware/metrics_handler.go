@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"Gin-Inventory/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware mencatat jumlah dan durasi request per route Gin. Route dipakai sebagai
+// label (misalnya /api/v1/items/:id), bukan path asli, agar jumlah deret metrik tetap kecil.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
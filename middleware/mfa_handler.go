@@ -0,0 +1,407 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/mfa"
+	"Gin-Inventory/model"
+	"Gin-Inventory/response"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Nilai klaim amr (RFC 8176). AMRRecoveryCode bukan nilai standar, dipakai untuk login dengan
+// recovery code.
+const (
+	AMRPassword     = "pwd"
+	AMROTP          = "otp"
+	AMRRecoveryCode = "rc"
+	// AMRSSO menandai login lewat identity provider OpenID Connect
+	AMRSSO = "sso"
+	// AMRExternalMFA adalah nilai amr dari identity provider yang menyatakan login memakai MFA
+	AMRExternalMFA = "mfa"
+)
+
+// recoveryCodeCount adalah jumlah recovery code yang dibuat setiap enrollment atau pembaruan
+const recoveryCodeCount = 10
+
+const challengeTokenType = "mfa_challenge"
+
+var errInvalidMFACode = apperror.New(401, apperror.CodeInvalidMFACode, "Invalid two-factor authentication code")
+
+// MFALoginHandler adalah langkah kedua login untuk akun dengan TOTP aktif: menukar challenge
+// token dari LoginHandler dan kode TOTP (atau recovery code) dengan pasangan token
+func MFALoginHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var mfaData MFALoginSchema
+		if err := c.ShouldBindJSON(&mfaData); err != nil {
+			c.Error(apperror.Validation(FormatValidationErrors(err)))
+			return
+		}
+
+		claims, err := parseChallengeToken(auth, mfaData.ChallengeToken)
+		if err != nil {
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid or expired challenge token"))
+			return
+		}
+
+		var user model.User
+		if err := auth.DB.First(&user, claims.userID).Error; err != nil || user.TOTPEnabledAt == nil {
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid or expired challenge token"))
+			return
+		}
+
+		// Tebakan kode dibatasi dengan penguncian yang sama dengan tebakan password
+		if !checkLockout(c, auth, user.Email) {
+			return
+		}
+
+		method, err := verifySecondFactor(auth.DB, user, mfaData.Code, true)
+		if errors.Is(err, errInvalidMFACode) {
+			loginFailed(c, auth, user.Email, &user.ID)
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		// Challenge token hanya bisa dipakai sekali
+		if err := auth.Store.Revoke(claims.jti, claims.expiresAt); err != nil {
+			c.Error(fmt.Errorf("failed to invalidate challenge token: %w", err))
+			return
+		}
+		loginSucceeded(c, auth, user.Email)
+
+		completeLogin(c, auth, user, append(claims.amr, method))
+	}
+}
+
+// TOTPSetupHandler memulai enrollment TOTP: membuat secret baru dan URI otpauth:// yang bisa
+// ditampilkan sebagai QR code. TOTP baru aktif setelah kode pertama dikonfirmasi ke
+// TOTPEnableHandler.
+func TOTPSetupHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(c, auth)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabledAt != nil {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Two-factor authentication is already enabled"))
+			return
+		}
+
+		secret, uri, err := mfa.Generate(auth.MFAIssuer, user.Email)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate TOTP secret: %w", err))
+			return
+		}
+		if err := auth.DB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+			c.Error(err)
+			return
+		}
+
+		response.Message(c, 200, "Scan the QR code with an authenticator app, then confirm a code to enable two-factor authentication", gin.H{
+			"secret":      secret,
+			"otpauth_url": uri,
+		})
+	}
+}
+
+// TOTPEnableHandler mengaktifkan TOTP setelah kode dari authenticator dikonfirmasi, lalu
+// mengembalikan recovery code yang hanya ditampilkan sekali. Semua sesi dicabut sehingga
+// login berikutnya harus melewati langkah kedua.
+func TOTPEnableHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(c, auth)
+		if !ok {
+			return
+		}
+
+		var codeData MFACodeSchema
+		if err := c.ShouldBindJSON(&codeData); err != nil {
+			c.Error(apperror.Validation(FormatValidationErrors(err)))
+			return
+		}
+
+		if user.TOTPEnabledAt != nil {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Two-factor authentication is already enabled"))
+			return
+		}
+		if user.TOTPSecret == "" {
+			c.Error(apperror.New(400, apperror.CodeBadRequest, "Start the setup with POST /mfa/totp/setup first"))
+			return
+		}
+
+		step, valid := mfa.Validate(user.TOTPSecret, codeData.Code, time.Now())
+		if !valid {
+			c.Error(errInvalidMFACode)
+			return
+		}
+
+		var codes []string
+		err := auth.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled_at": time.Now(), "totp_last_step": step}).Error; err != nil {
+				return err
+			}
+			var err error
+			codes, err = replaceRecoveryCodes(tx, user.ID)
+			return err
+		})
+		if err != nil {
+			c.Error(fmt.Errorf("failed to enable two-factor authentication: %w", err))
+			return
+		}
+
+		if err := RevokeAllTokens(auth, user.ID); err != nil {
+			c.Error(fmt.Errorf("failed to invalidate sessions: %w", err))
+			return
+		}
+
+		response.Message(c, 200, "Two-factor authentication enabled, log in again to continue", gin.H{
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// TOTPDisableHandler mematikan TOTP dengan konfirmasi kode TOTP atau recovery code.
+// Akun dengan role yang wajib MFA tidak bisa mematikannya.
+func TOTPDisableHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(c, auth)
+		if !ok {
+			return
+		}
+
+		var codeData MFACodeSchema
+		if err := c.ShouldBindJSON(&codeData); err != nil {
+			c.Error(apperror.Validation(FormatValidationErrors(err)))
+			return
+		}
+
+		if user.TOTPEnabledAt == nil {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Two-factor authentication is not enabled"))
+			return
+		}
+		if Contains(auth.MFARequiredRoles, user.Role) {
+			c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: Two-factor authentication is required for role "+user.Role))
+			return
+		}
+
+		if _, err := verifySecondFactor(auth.DB, user, codeData.Code, true); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := ResetMFA(auth.DB, user.ID); err != nil {
+			c.Error(fmt.Errorf("failed to disable two-factor authentication: %w", err))
+			return
+		}
+
+		response.Message(c, 200, "Two-factor authentication disabled", nil)
+	}
+}
+
+// RecoveryCodesHandler membuat recovery code baru dengan konfirmasi kode TOTP. Recovery code
+// lama langsung tidak berlaku.
+func RecoveryCodesHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(c, auth)
+		if !ok {
+			return
+		}
+
+		var codeData MFACodeSchema
+		if err := c.ShouldBindJSON(&codeData); err != nil {
+			c.Error(apperror.Validation(FormatValidationErrors(err)))
+			return
+		}
+
+		if user.TOTPEnabledAt == nil {
+			c.Error(apperror.New(409, apperror.CodeConflict, "Two-factor authentication is not enabled"))
+			return
+		}
+		if _, err := verifySecondFactor(auth.DB, user, codeData.Code, false); err != nil {
+			c.Error(err)
+			return
+		}
+
+		var codes []string
+		err := auth.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			codes, err = replaceRecoveryCodes(tx, user.ID)
+			return err
+		})
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate recovery codes: %w", err))
+			return
+		}
+
+		response.Message(c, 200, "Recovery codes regenerated", gin.H{
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// ResetMFA mematikan TOTP akun dan menghapus recovery code-nya, dipakai TOTPDisableHandler dan
+// perintah CLI reset-mfa untuk akun yang kehilangan perangkat sekaligus recovery code-nya
+func ResetMFA(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"totp_secret": "", "totp_enabled_at": nil, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// verifySecondFactor memeriksa kode TOTP, atau recovery code jika allowRecovery, dan menandainya
+// terpakai. Hasilnya adalah nilai amr metode yang dipakai.
+func verifySecondFactor(db *gorm.DB, user model.User, code string, allowRecovery bool) (string, error) {
+	if step, valid := mfa.Validate(user.TOTPSecret, code, time.Now()); valid {
+		// Simpan step secara kondisional agar kode yang sama tidak bisa dipakai dua kali
+		result := db.Model(&model.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).Update("totp_last_step", step)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected == 0 {
+			return "", errInvalidMFACode
+		}
+		return AMROTP, nil
+	}
+
+	if !allowRecovery {
+		return "", errInvalidMFACode
+	}
+	hash := HashToken(mfa.NormalizeRecoveryCode(code))
+	result := db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", errInvalidMFACode
+	}
+	return AMRRecoveryCode, nil
+}
+
+// replaceRecoveryCodes menghapus recovery code lama dan menyimpan hash dari kode baru
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := mfa.RecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		recoveryCode := model.RecoveryCode{UserID: userID, CodeHash: HashToken(mfa.NormalizeRecoveryCode(code))}
+		if err := tx.Create(&recoveryCode).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// currentAccount memuat akun yang sedang login
+func currentAccount(c *gin.Context, auth AuthConfig) (model.User, bool) {
+	var user model.User
+	if err := auth.DB.First(&user, c.GetUint("current_id")).Error; err != nil {
+		c.Error(apperror.New(401, apperror.CodeUnauthorized, "Unauthorized"))
+		return model.User{}, false
+	}
+	return user, true
+}
+
+type challengeClaims struct {
+	userID    uint
+	jti       string
+	expiresAt time.Time
+	// amr adalah metode faktor pertama, misalnya password atau SSO
+	amr []string
+}
+
+// generateChallengeToken membuat token berumur pendek yang membuktikan faktor pertama (amr) sudah
+// lolos. Klaim typ membuatnya ditolak AuthMiddleware sebagai access token.
+func generateChallengeToken(auth AuthConfig, userID uint, amr []string) (string, error) {
+	jti, err := generateJTI()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":     challengeTokenType,
+		"jti":     jti,
+		"user_id": userID,
+		"amr":     amr,
+		"iat":     now.Unix(),
+		"exp":     now.Add(auth.MFAChallengeTTL).Unix(),
+	})
+	return token.SignedString(auth.Secret)
+}
+
+func parseChallengeToken(auth AuthConfig, tokenString string) (challengeClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return auth.Secret, nil
+	})
+	if err != nil {
+		return challengeClaims{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["typ"] != challengeTokenType {
+		return challengeClaims{}, errors.New("not a challenge token")
+	}
+	userID, _ := claims["user_id"].(float64)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	if jti == "" {
+		return challengeClaims{}, errors.New("challenge token without jti")
+	}
+
+	revoked, err := auth.Store.IsRevoked(jti)
+	if err != nil || revoked {
+		return challengeClaims{}, errors.New("challenge token already used")
+	}
+
+	amr := []string{}
+	methods, _ := claims["amr"].([]interface{})
+	for _, method := range methods {
+		if m, ok := method.(string); ok && m != AMROTP && m != AMRRecoveryCode {
+			amr = append(amr, m)
+		}
+	}
+	if len(amr) == 0 {
+		amr = []string{AMRPassword}
+	}
+	return challengeClaims{userID: uint(userID), jti: jti, expiresAt: time.Unix(int64(exp), 0), amr: amr}, nil
+}
+
+// isMFA memeriksa apakah login memakai faktor kedua
+func isMFA(amr []string) bool {
+	for _, method := range amr {
+		if method == AMROTP || method == AMRRecoveryCode || method == AMRExternalMFA {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains memeriksa apakah value ada di values, misalnya role di MFARequiredRoles
+func Contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
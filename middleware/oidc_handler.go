@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/model"
+	"Gin-Inventory/sso"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errInvalidSSOState = apperror.New(400, apperror.CodeInvalidToken, "Invalid or expired login state, start the login again")
+
+// OIDCLoginHandler memulai login SSO: menyimpan state, nonce dan PKCE verifier lalu mengarahkan
+// browser ke halaman login identity provider
+func OIDCLoginHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, err := GenerateRandomToken(32)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate login state: %w", err))
+			return
+		}
+		nonce, err := GenerateRandomToken(32)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate nonce: %w", err))
+			return
+		}
+		verifier := sso.NewVerifier()
+
+		authURL, err := auth.SSO.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+		if err != nil {
+			Logger(c).Error("Failed to start SSO login", "error", err.Error())
+			c.Error(apperror.New(502, apperror.CodeSSOFailed, "Identity provider is unavailable"))
+			return
+		}
+
+		// Login yang tidak pernah kembali dari IdP dibersihkan setiap ada login baru
+		now := time.Now()
+		if err := auth.DB.Unscoped().Where("expires_at <= ?", now).Delete(&model.OIDCLogin{}).Error; err != nil {
+			Logger(c).Error("Failed to prune expired SSO logins", "error", err.Error())
+		}
+		login := model.OIDCLogin{
+			StateHash: HashToken(state),
+			Nonce:     nonce,
+			Verifier:  verifier,
+			ExpiresAt: now.Add(auth.SSOStateTTL),
+		}
+		if err := auth.DB.Create(&login).Error; err != nil {
+			c.Error(fmt.Errorf("failed to save login state: %w", err))
+			return
+		}
+
+		c.Redirect(302, authURL)
+	}
+}
+
+// OIDCCallbackHandler menyelesaikan login SSO setelah IdP mengarahkan browser kembali dengan code
+// dan state. Identity dicocokkan ke akun lokal (lihat ssoAccount), lalu login diselesaikan seperti
+// login password, termasuk langkah TOTP jika IdP tidak melaporkan MFA.
+func OIDCCallbackHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if idpError := c.Query("error"); idpError != "" {
+			Logger(c).Warn("Identity provider rejected SSO login", "error", idpError, "description", c.Query("error_description"))
+			c.Error(apperror.New(401, apperror.CodeSSOFailed, "Single sign-on was cancelled or rejected by the identity provider"))
+			return
+		}
+
+		login, ok := consumeSSOState(c, auth, c.Query("state"))
+		if !ok {
+			return
+		}
+
+		identity, err := auth.SSO.Exchange(c.Request.Context(), c.Query("code"), login.Verifier, login.Nonce)
+		if err != nil {
+			Logger(c).Warn("SSO login failed", "error", err.Error())
+			c.Error(apperror.New(401, apperror.CodeSSOFailed, "Single sign-on failed"))
+			return
+		}
+
+		user, err := ssoAccount(auth.DB, auth.SSO.Config(), identity)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		amr := []string{AMRSSO}
+		if Contains(identity.AMR, AMRExternalMFA) {
+			amr = append(amr, AMRExternalMFA)
+		}
+		completeLogin(c, auth, user, amr)
+	}
+}
+
+// consumeSSOState mengambil dan menghapus state login SSO sehingga callback tidak bisa diulang
+func consumeSSOState(c *gin.Context, auth AuthConfig, state string) (model.OIDCLogin, bool) {
+	if state == "" {
+		c.Error(errInvalidSSOState)
+		return model.OIDCLogin{}, false
+	}
+
+	var login model.OIDCLogin
+	err := auth.DB.Where("state_hash = ?", HashToken(state)).First(&login).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Error(errInvalidSSOState)
+		return model.OIDCLogin{}, false
+	}
+	if err != nil {
+		c.Error(err)
+		return model.OIDCLogin{}, false
+	}
+
+	// Hapus secara kondisional agar dua callback bersamaan tidak sama-sama lolos
+	result := auth.DB.Unscoped().Where("id = ?", login.ID).Delete(&model.OIDCLogin{})
+	if result.Error != nil {
+		c.Error(result.Error)
+		return model.OIDCLogin{}, false
+	}
+	if result.RowsAffected == 0 || !time.Now().Before(login.ExpiresAt) {
+		c.Error(errInvalidSSOState)
+		return model.OIDCLogin{}, false
+	}
+	return login, true
+}
+
+// ssoAccount mencari akun lokal untuk identity dari IdP:
+//  1. akun yang sudah terhubung dengan subject yang sama;
+//  2. akun dengan email yang sama, dihubungkan jika IdP sudah memverifikasi email tersebut;
+//  3. akun baru (just-in-time) jika AutoProvision aktif, hanya bisa login lewat SSO.
+//
+// Jika RoleMapping diisi, role akun disamakan dengan grup IdP setiap login.
+func ssoAccount(db *gorm.DB, cfg sso.Config, identity sso.Identity) (model.User, error) {
+	var user model.User
+	err := db.Where("oidc_subject = ?", identity.Subject).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if identity.Email == "" {
+			return model.User{}, apperror.New(403, apperror.CodeForbidden, "Identity provider did not return an email address")
+		}
+
+		err = db.Where("email = ?", identity.Email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return provisionSSOAccount(db, cfg, identity)
+		case err != nil:
+			return model.User{}, err
+		case !identity.EmailVerified:
+			return model.User{}, apperror.New(403, apperror.CodeForbidden, "Email address is not verified by the identity provider, the account cannot be linked")
+		case user.OIDCSubject != nil:
+			return model.User{}, apperror.New(403, apperror.CodeForbidden, "Account is already linked to another identity")
+		}
+		user.OIDCSubject = &identity.Subject
+	}
+
+	if identity.EmailVerified && user.EmailVerifiedAt == nil && strings.EqualFold(user.Email, identity.Email) {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if len(cfg.RoleMapping) > 0 {
+		role, ok := cfg.Role(identity.Groups)
+		if !ok {
+			return model.User{}, apperror.New(403, apperror.CodeForbidden, "Account is not in any group allowed to sign in")
+		}
+		user.Role = role
+	}
+
+	if err := db.Model(&user).Select("oidc_subject", "email_verified_at", "role").Updates(&user).Error; err != nil {
+		return model.User{}, fmt.Errorf("failed to update SSO account: %w", err)
+	}
+	return user, nil
+}
+
+// provisionSSOAccount membuat akun untuk login SSO pertama. Akun ini tidak punya password dan
+// login password-nya dimatikan.
+func provisionSSOAccount(db *gorm.DB, cfg sso.Config, identity sso.Identity) (model.User, error) {
+	if !cfg.AutoProvision {
+		return model.User{}, apperror.New(403, apperror.CodeForbidden, "No local account exists for this identity")
+	}
+	role, ok := cfg.Role(identity.Groups)
+	if !ok {
+		return model.User{}, apperror.New(403, apperror.CodeForbidden, "Account is not in any group allowed to sign in")
+	}
+
+	name := identity.Name
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	user := model.User{
+		Name:                  name,
+		Email:                 identity.Email,
+		Role:                  role,
+		OIDCSubject:           &identity.Subject,
+		PasswordLoginDisabled: true,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return model.User{}, fmt.Errorf("failed to provision SSO account: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequirePermission memastikan akun yang login memiliki semua permission yang diminta.
+// Harus dipasang setelah AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, permission := range permissions {
+			if !HasPermission(c, permission) {
+				c.Error(apperror.New(403, apperror.CodeForbidden, "Forbidden: Missing permission "+permission))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail menolak akun yang email-nya belum diverifikasi.
+// Harus dipasang setelah AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("email_verified") {
+			c.Error(apperror.New(403, apperror.CodeEmailNotVerified, "Forbidden: Verify your email address first"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSession menolak request yang diautentikasi dengan API key, untuk route yang mengelola
+// akun atau kredensial sehingga hanya boleh dipakai lewat login. Harus dipasang setelah AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.Error(apperror.New(403, apperror.CodeSessionRequired, "Forbidden: This endpoint cannot be used with an API key"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission memeriksa apakah akun yang login memiliki permission tertentu
+func HasPermission(c *gin.Context, permission string) bool {
+	value, exists := c.Get("permissions")
+	if !exists {
+		return false
+	}
+
+	permissions, ok := value.([]string)
+	if !ok {
+		return false
+	}
+
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func loadPermissions(db *gorm.DB, roleName string) ([]string, error) {
+	var role model.Role
+	err := db.Preload("Permissions").Where("name = ?", roleName).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role.PermissionNames(), nil
+}
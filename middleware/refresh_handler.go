@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/model"
+	"Gin-Inventory/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RefreshTokenHandler menukar refresh token yang masih berlaku dengan pasangan token baru.
+// Refresh token lama langsung ditandai terpakai (rotasi); jika token yang sudah terpakai
+// dikirim lagi, seluruh sesi (family) dicabut karena token kemungkinan telah dicuri.
+func RefreshTokenHandler(auth AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var refreshData RefreshSchema
+		if err := c.ShouldBindJSON(&refreshData); err != nil {
+			c.Error(apperror.Validation(FormatValidationErrors(err)))
+			return
+		}
+
+		var refreshToken model.RefreshToken
+		if err := auth.DB.Preload("Session").Where("token_hash = ?", HashToken(refreshData.RefreshToken)).First(&refreshToken).Error; err != nil {
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid refresh token"))
+			return
+		}
+
+		// Token yang sudah pernah dipakai atau dicabut berarti ada yang memakai ulang token lama
+		if refreshToken.UsedAt != nil || refreshToken.RevokedAt != nil {
+			if err := RevokeSession(auth.DB, refreshToken.SessionID); err != nil {
+				c.Error(fmt.Errorf("failed to revoke session: %w", err))
+				return
+			}
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Refresh token reuse detected, session has been revoked"))
+			return
+		}
+
+		if refreshToken.Session.RevokedAt != nil || time.Now().After(refreshToken.ExpiresAt) {
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Refresh token expired or revoked"))
+			return
+		}
+
+		// Token baru memakai role akun saat ini, bukan role saat login
+		var account model.User
+		if err := auth.DB.Select("id", "role").First(&account, refreshToken.UserID).Error; err != nil {
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid refresh token"))
+			return
+		}
+		refreshToken.Session.Role = account.Role
+
+		var newRefreshToken string
+		err := auth.DB.Transaction(func(tx *gorm.DB) error {
+			// Tandai terpakai secara kondisional supaya dua permintaan paralel tidak sama-sama berhasil
+			now := time.Now()
+			result := tx.Model(&model.RefreshToken{}).
+				Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", refreshToken.ID).
+				Update("used_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errRefreshTokenReused
+			}
+
+			// Role sesi ikut disamakan agar refresh token berikutnya mencatat role akun saat ini
+			if err := tx.Model(&model.Session{}).Where("id = ?", refreshToken.SessionID).Updates(map[string]interface{}{"last_used_at": now, "role": account.Role}).Error; err != nil {
+				return err
+			}
+
+			var err error
+			newRefreshToken, err = createRefreshToken(tx, &refreshToken.Session, auth.RefreshTTL)
+			return err
+		})
+		if errors.Is(err, errRefreshTokenReused) {
+			if err := RevokeSession(auth.DB, refreshToken.SessionID); err != nil {
+				c.Error(fmt.Errorf("failed to revoke session: %w", err))
+				return
+			}
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, "Refresh token reuse detected, session has been revoked"))
+			return
+		}
+		if err != nil {
+			c.Error(fmt.Errorf("failed to rotate refresh token: %w", err))
+			return
+		}
+
+		tokenString, err := GenerateToken(auth, account.ID, account.Role, refreshToken.SessionID, strings.Fields(refreshToken.Session.AMR))
+		if err != nil {
+			c.Error(fmt.Errorf("failed to generate token: %w", err))
+			return
+		}
+
+		response.Message(c, 200, "Token refreshed successfully", gin.H{
+			"token":         tokenString,
+			"refresh_token": newRefreshToken,
+			"expires_in":    int(auth.TokenTTL.Seconds()),
+		})
+	}
+}
+
+// issueTokenPair membuat sesi baru untuk perangkat yang login beserta access token dan refresh token-nya
+func issueTokenPair(c *gin.Context, auth AuthConfig, userID uint, role string, amr []string) (string, string, error) {
+	now := time.Now()
+	session := model.Session{
+		UserID:     userID,
+		Role:       role,
+		Device:     c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		AMR:        strings.Join(amr, " "),
+		LastUsedAt: now,
+	}
+
+	var refreshTokenString string
+	err := auth.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		refreshTokenString, err = createRefreshToken(tx, &session, auth.RefreshTTL)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	tokenString, err := GenerateToken(auth, userID, role, session.ID, amr)
+	if err != nil {
+		return "", "", err
+	}
+
+	return tokenString, refreshTokenString, nil
+}
+
+func createRefreshToken(tx *gorm.DB, session *model.Session, ttl time.Duration) (string, error) {
+	token, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := model.RefreshToken{
+		TokenHash: HashToken(token),
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		Role:      session.Role,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RevokeSession mencabut sesi beserta semua refresh token di dalamnya
+func RevokeSession(db *gorm.DB, sessionID uint) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Session{}).Where("id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.RefreshToken{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", now).Error
+	})
+}
+
+// RevokeAllSessions mencabut semua sesi milik satu akun, termasuk sesi yang dibuat dengan role lama
+func RevokeAllSessions(db *gorm.DB, userID uint) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", now).Error
+	})
+}
+
+// GenerateRandomToken membuat token acak yang aman untuk URL dari n byte acak
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken mengembalikan hash SHA-256 dari token untuk disimpan di database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
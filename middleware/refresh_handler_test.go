@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/store"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionTestServer menyiapkan login, refresh dan satu route terproteksi yang mengembalikan role token
+func sessionTestServer(t *testing.T) (*gin.Engine, AuthConfig) {
+	t.Helper()
+
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "session.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	auth := AuthConfig{
+		DB:         db,
+		Secret:     []byte("test-secret"),
+		TokenTTL:   time.Hour,
+		RefreshTTL: time.Hour,
+		Store:      store.NewMemoryRevocationStore(),
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err := db.Create(&model.User{Name: "Alice", Email: "alice@example.com", Password: string(hash), Role: model.RoleUser}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.POST("/login", LoginHandler(auth))
+	r.POST("/token/refresh", RefreshTokenHandler(auth))
+	r.POST("/logout-all", AuthMiddleware(auth), LogoutAllHandler(auth))
+	r.GET("/me", AuthMiddleware(auth), func(c *gin.Context) {
+		c.JSON(200, gin.H{"data": gin.H{"role": c.GetString("role")}})
+	})
+	return r, auth
+}
+
+func call(t *testing.T, r *gin.Engine, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	encoded, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	result := map[string]interface{}{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	data, _ := result["data"].(map[string]interface{})
+	return w.Code, data
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	r, auth := sessionTestServer(t)
+
+	code, login := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	if code != 200 {
+		t.Fatalf("login failed with %d", code)
+	}
+	access, refresh := login["token"].(string), login["refresh_token"].(string)
+
+	code, rotated := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": refresh})
+	if code != 200 || rotated["refresh_token"] == refresh {
+		t.Fatalf("expected a new refresh token, got %d %v", code, rotated)
+	}
+
+	// Refresh memakai role akun saat ini, bukan role saat login
+	if err := auth.DB.Model(&model.User{}).Where("email = ?", "alice@example.com").Update("role", model.RoleAdmin).Error; err != nil {
+		t.Fatalf("failed to change role: %v", err)
+	}
+	code, promoted := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": rotated["refresh_token"]})
+	if code != 200 {
+		t.Fatalf("refresh failed with %d", code)
+	}
+	if code, me := call(t, r, "GET", "/me", promoted["token"].(string), nil); code != 200 || me["role"] != model.RoleAdmin {
+		t.Errorf("expected the refreshed token to carry the current role, got %d %v", code, me)
+	}
+	if code, _ := call(t, r, "GET", "/me", access, nil); code != 401 {
+		t.Errorf("expected a token with the old role to be rejected, got %d", code)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	r, _ := sessionTestServer(t)
+
+	_, login := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	refresh := login["refresh_token"].(string)
+	_, rotated := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": refresh})
+	access := rotated["token"].(string)
+	if code, _ := call(t, r, "GET", "/me", access, nil); code != 200 {
+		t.Fatalf("expected the rotated access token to work, got %d", code)
+	}
+
+	// Refresh token lama yang dipakai ulang mencabut seluruh sesi
+	if code, _ := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": refresh}); code != 401 {
+		t.Fatalf("expected reuse to be rejected, got %d", code)
+	}
+	if code, _ := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": rotated["refresh_token"]}); code != 401 {
+		t.Errorf("expected the latest refresh token of the session to be revoked, got %d", code)
+	}
+	if code, _ := call(t, r, "GET", "/me", access, nil); code != 401 {
+		t.Errorf("expected access tokens of the revoked session to be rejected, got %d", code)
+	}
+
+	// Sesi lain dari akun yang sama tidak terpengaruh
+	_, other := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	if code, _ := call(t, r, "GET", "/me", other["token"].(string), nil); code != 200 {
+		t.Errorf("expected another session to stay valid, got %d", code)
+	}
+}
+
+func TestRevokeAllTokensCutoff(t *testing.T) {
+	r, auth := sessionTestServer(t)
+
+	_, before := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	var user model.User
+	auth.DB.Where("email = ?", "alice@example.com").First(&user)
+	if err := RevokeAllTokens(auth, user.ID); err != nil {
+		t.Fatalf("RevokeAllTokens failed: %v", err)
+	}
+
+	// Login tepat sesudah logout dari semua sesi, biasanya di detik yang sama, tetap berlaku
+	_, after := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	if code, _ := call(t, r, "GET", "/me", after["token"].(string), nil); code != 200 {
+		t.Errorf("expected a token issued after the cutoff to be accepted, got %d", code)
+	}
+	if code, _ := call(t, r, "GET", "/me", before["token"].(string), nil); code != 401 {
+		t.Errorf("expected a token issued before the cutoff to be rejected, got %d", code)
+	}
+}
+
+func TestLogoutAllAfterRoleChange(t *testing.T) {
+	r, auth := sessionTestServer(t)
+
+	_, old := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	if err := auth.DB.Model(&model.User{}).Where("email = ?", "alice@example.com").Update("role", model.RoleAdmin).Error; err != nil {
+		t.Fatalf("failed to change role: %v", err)
+	}
+
+	// Logout dari semua sesi dengan role baru juga mencabut sesi yang dibuat dengan role lama
+	_, current := call(t, r, "POST", "/login", "", gin.H{"email": "alice@example.com", "password": "secret"})
+	if code, _ := call(t, r, "POST", "/logout-all", current["token"].(string), nil); code != 200 {
+		t.Fatalf("logout-all failed with %d", code)
+	}
+	if code, _ := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": old["refresh_token"]}); code != 401 {
+		t.Errorf("expected the refresh token issued under the old role to be revoked, got %d", code)
+	}
+	if code, _ := call(t, r, "POST", "/token/refresh", "", gin.H{"refresh_token": current["refresh_token"]}); code != 401 {
+		t.Errorf("expected the refresh token issued under the current role to be revoked, got %d", code)
+	}
+
+	var user model.User
+	auth.DB.Where("email = ?", "alice@example.com").First(&user)
+	if before, _ := auth.Store.RevokedBefore(user.ID); before.IsZero() {
+		t.Error("expected the cutoff to be recorded for the account")
+	}
+}
@@ -3,19 +3,29 @@ package middleware
 import (
 	"fmt"
 	"strings"
+	"time"
 
-	"Gin-Inventory/config"
+	"Gin-Inventory/apperror"
+	"Gin-Inventory/model"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(auth AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Klien mesin mengirim API key, bukan access token
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			if authenticateAPIKey(c, auth, key) {
+				c.Next()
+			}
+			return
+		}
+
 		// Ambil token dari header Authorization
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.JSON(401, gin.H{"error": "Authorization token required"})
+			c.Error(apperror.New(401, apperror.CodeUnauthorized, "Authorization token required"))
 			c.Abort()
 			return
 		}
@@ -29,24 +39,121 @@ func AuthMiddleware() gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method")
 			}
-			return []byte(config.JWTSecret), nil
+			return auth.Secret, nil
 		})
 
+		// Token yang tidak bisa di-parse sama sekali (bukan JWT, segmen kurang, base64 rusak)
+		// membuat jwt.Parse mengembalikan token nil. Tanpa pemeriksaan ini token.Claims di bawah
+		// panic dan request berakhir 500, padahal seharusnya ditolak sebagai token tidak valid.
+		if token == nil {
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, err.Error()))
+			c.Abort()
+			return
+		}
+
 		// Jika token valid, ambil klaim
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+			// Token dengan typ, misalnya challenge token login dua langkah, bukan access token
+			if _, typed := claims["typ"]; typed {
+				c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid token claims"))
+				c.Abort()
+				return
+			}
+
 			// Ambil `user_id` dari klaim token
 			currentID, ok := claims["user_id"].(float64) // `float64` karena nilai dari MapClaims default-nya float
 			if !ok {
-				c.JSON(401, gin.H{"error": "Invalid token claims"})
+				c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid token claims"))
 				c.Abort()
 				return
 			}
 
+			role, _ := claims["role"].(string)
+
+			// Pastikan akun masih ada dan role di token sama dengan role saat ini
+			var account model.User
+			if err := auth.DB.Select("id", "role", "email_verified_at").First(&account, uint(currentID)).Error; err != nil || account.Role != role {
+				c.Error(apperror.New(401, apperror.CodeInvalidToken, "Invalid token claims"))
+				c.Abort()
+				return
+			}
+
+			// Access token ikut berhenti berlaku saat sesinya dicabut, misalnya karena logout atau
+			// refresh token sesi itu dipakai ulang, walau token belum kedaluwarsa
+			sessionID, _ := claims["sid"].(float64)
+			if sessionID != 0 {
+				var active int64
+				if err := auth.DB.Model(&model.Session{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", uint(sessionID), account.ID).Count(&active).Error; err != nil {
+					c.Error(fmt.Errorf("failed to check session: %w", err))
+					c.Abort()
+					return
+				}
+				if active == 0 {
+					c.Error(apperror.New(401, apperror.CodeInvalidToken, "Session has been revoked"))
+					c.Abort()
+					return
+				}
+			}
+
+			// Tolak token yang sudah dicabut lewat logout
+			jti, _ := claims["jti"].(string)
+			if jti != "" {
+				revoked, err := auth.Store.IsRevoked(jti)
+				if err != nil {
+					c.Error(fmt.Errorf("failed to check token revocation: %w", err))
+					c.Abort()
+					return
+				}
+				if revoked {
+					c.Error(apperror.New(401, apperror.CodeInvalidToken, "Token has been revoked"))
+					c.Abort()
+					return
+				}
+			}
+
+			// Tolak token yang diterbitkan sebelum "logout dari semua sesi"
+			issuedAt, _ := claims["iat"].(float64)
+			revokedBefore, err := auth.Store.RevokedBefore(uint(currentID))
+			if err != nil {
+				c.Error(fmt.Errorf("failed to check token revocation: %w", err))
+				c.Abort()
+				return
+			}
+			if !revokedBefore.IsZero() && time.Unix(int64(issuedAt), 0).Before(revokedBefore) {
+				c.Error(apperror.New(401, apperror.CodeInvalidToken, "Token has been revoked"))
+				c.Abort()
+				return
+			}
+
+			// Role yang wajib MFA hanya boleh memakai token hasil login dengan faktor kedua
+			mfa, _ := claims["mfa"].(bool)
+			if !auth.mfaOptional && !mfa && Contains(auth.MFARequiredRoles, role) {
+				c.Error(apperror.New(403, apperror.CodeMFARequired, "Two-factor authentication is required for this account, enroll with POST /api/v1/mfa/totp/setup"))
+				c.Abort()
+				return
+			}
+
+			exp, _ := claims["exp"].(float64)
+
 			// Simpan `current_id` ke dalam context
 			c.Set("current_id", uint(currentID)) // Konversi ke `uint` sesuai tipe yang digunakan
 			c.Set("role", claims["role"])        // Simpan juga role jika diperlukan
+			c.Set("jti", jti)                    // Dibutuhkan LogoutHandler untuk mencabut token
+			c.Set("exp", time.Unix(int64(exp), 0))
+			c.Set("session_id", uint(sessionID))
+			c.Set("email_verified", account.EmailVerifiedAt != nil) // Dibutuhkan RequireVerifiedEmail
+			c.Set("mfa", mfa)
+
+			// Muat permission milik role untuk dipakai RequirePermission dan HasPermission
+			permissions, err := loadPermissions(auth.DB, role)
+			if err != nil {
+				c.Error(fmt.Errorf("failed to load permissions: %w", err))
+				c.Abort()
+				return
+			}
+			c.Set("permissions", permissions)
 		} else {
-			c.JSON(401, gin.H{"error": err.Error()})
+			c.Error(apperror.New(401, apperror.CodeInvalidToken, err.Error()))
 			c.Abort()
 			return
 		}
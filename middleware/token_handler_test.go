@@ -0,0 +1,13 @@
+package middleware
+
+import "testing"
+
+func TestAuthMiddlewareRejectsMalformedToken(t *testing.T) {
+	r, _ := sessionTestServer(t)
+
+	for _, token := range []string{"not-a-token", "a.b", "a.b.c", "..."} {
+		if code, _ := call(t, r, "GET", "/me", token, nil); code != 401 {
+			t.Errorf("expected 401 for %q, got %d", token, code)
+		}
+	}
+}
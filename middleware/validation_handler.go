@@ -27,6 +27,13 @@ func init() {
 			_, err := time.Parse("2006-01-02", fl.Field().String())
 			return err == nil
 		})
+		// Arah Delta harus sesuai alasan: pembelian menambah stok, kehilangan mengurangi stok
+		validate.RegisterStructValidation(func(sl validator.StructLevel) {
+			movement := sl.Current().Interface().(StockMovementSchema)
+			if (movement.Reason == "purchase" && movement.Delta < 0) || (movement.Reason == "loss" && movement.Delta > 0) {
+				sl.ReportError(movement.Delta, "Delta", "Delta", "delta_sign", movement.Reason)
+			}
+		}, StockMovementSchema{})
 	}
 }
 
@@ -39,10 +46,9 @@ type TransactionSchema struct {
 }
 
 type DetailSchema struct {
-	Code   string `json:"code" binding:"omitempty"`
-	Out    string `json:"out" binding:"omitempty,date_format"`
-	Entry  string `json:"entry" binding:"omitempty,date_format"`
-	Status string `json:"status" binding:"omitempty"`
+	Code  string `json:"code" binding:"omitempty"`
+	Out   string `json:"out" binding:"omitempty,date_format"`
+	Entry string `json:"entry" binding:"omitempty,date_format"`
 }
 
 type ItemSchema struct {
@@ -50,6 +56,18 @@ type ItemSchema struct {
 	Stock int    `json:"stock" binding:"required,min=0"`
 }
 
+// UpdateItemSchema memakai pointer untuk Stock agar stok 0 bisa dibedakan dari field yang tidak dikirim
+type UpdateItemSchema struct {
+	Name  string `json:"name" binding:"omitempty,name_format"`
+	Stock *int   `json:"stock" binding:"omitempty,min=0"`
+}
+
+type StockMovementSchema struct {
+	Delta  int    `json:"delta" binding:"required,ne=0"`
+	Reason string `json:"reason" binding:"required,oneof=purchase adjustment loss"`
+	Note   string `json:"note" binding:"omitempty,max=255"`
+}
+
 type UpdateSchema struct {
 	Name     string `json:"name" binding:"omitempty,name_format"`
 	Email    string `json:"email" binding:"omitempty,email"`
@@ -62,11 +80,50 @@ type UserSchema struct {
 	Password string `json:"password" binding:"required,min=3"`
 }
 
+type InvitationSchema struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordSchema struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordSchema struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=3"`
+}
+
+type MFALoginSchema struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type MFACodeSchema struct {
+	Code string `json:"code" binding:"required"`
+}
+
 type LoginSchema struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=3"`
 }
 
+type RefreshSchema struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// PasswordLoginSchema menyalakan atau mematikan login password sebuah akun
+type PasswordLoginSchema struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// APIKeySchema adalah input pembuatan API key. Key selalu milik akun yang membuatnya.
+// ExpiresAt (RFC 3339) kosong berarti key tidak kedaluwarsa.
+type APIKeySchema struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at" binding:"omitempty"`
+}
+
 // FormatValidationErrors mengembalikan semua pesan kesalahan validasi sebagai array string
 func FormatValidationErrors(err error) []string {
 	var errors []string
@@ -90,6 +147,12 @@ func formatFieldError(fe validator.FieldError) string {
 		return fmt.Sprintf("Field '%s' must be filled in.", fe.Field())
 	case "email":
 		return fmt.Sprintf("Field '%s' must be a valid email address.", fe.Field())
+	case "delta_sign":
+		direction := "positive"
+		if fe.Param() == "loss" {
+			direction = "negative"
+		}
+		return fmt.Sprintf("Field '%s' must be %s for reason '%s'.", fe.Field(), direction, fe.Param())
 	case "min":
 		return fmt.Sprintf("Field '%s' must have at least %s characters.", fe.Field(), fe.Param())
 	default:
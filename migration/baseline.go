@@ -0,0 +1,164 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Struct di file ini adalah salinan model saat skema versi 1 dirilis. Baseline memakai salinan
+// ini, bukan package model, sehingga versi 1 selalu membuat skema yang sama walau model sudah
+// berubah. Kolom dan tabel yang ditambahkan sesudahnya dibuat oleh migrasi berikutnya.
+
+type baselinePermission struct {
+	gorm.Model
+	Name string `gorm:"size:100;uniqueIndex;not null"`
+}
+
+func (baselinePermission) TableName() string {
+	return "permission"
+}
+
+type baselineRole struct {
+	gorm.Model
+	Name        string               `gorm:"size:50;uniqueIndex;not null"`
+	Permissions []baselinePermission `gorm:"many2many:role_permission;joinForeignKey:RoleID;joinReferences:PermissionID"`
+}
+
+func (baselineRole) TableName() string {
+	return "role"
+}
+
+type baselineUser struct {
+	gorm.Model
+	Name         string                `gorm:"size:100;not null"`
+	Email        string                `gorm:"size:100;unique;not null"`
+	Password     string                `gorm:"size:255;not null"`
+	Role         string                `gorm:"size:50;not null"`
+	Transactions []baselineTransaction `gorm:"foreignKey:UserID"`
+}
+
+func (baselineUser) TableName() string {
+	return "user"
+}
+
+type baselineItem struct {
+	gorm.Model
+	Name        string                `gorm:"size:100;unique;not null"`
+	Stock       int                   `gorm:"not null"`
+	Transaction []baselineTransaction `gorm:"foreignKey:ItemID"`
+}
+
+func (baselineItem) TableName() string {
+	return "item"
+}
+
+type baselineDetail struct {
+	gorm.Model
+	Code         string                `gorm:"size:100;not null;uniqueIndex"`
+	Out          time.Time             `gorm:"null"`
+	Entry        time.Time             `gorm:"null"`
+	Status       string                `gorm:"size:50;not null;default:'pending'"`
+	OverdueAt    *time.Time            `gorm:"null"`
+	Transactions []baselineTransaction `gorm:"foreignKey:DetailID;constraint:OnDelete:CASCADE;"`
+}
+
+func (baselineDetail) TableName() string {
+	return "detail"
+}
+
+type baselineTransaction struct {
+	gorm.Model
+	UserID   uint           `gorm:"not null"`
+	DetailID *uint          `gorm:"null"`
+	ItemID   uint           `gorm:"not null"`
+	Quantity int            `gorm:"not null"`
+	Status   string         `gorm:"size:50;not null;default:'draft'"`
+	User     baselineUser   `gorm:"foreignKey:UserID"`
+	Detail   baselineDetail `gorm:"foreignKey:DetailID;constraint:OnDelete:CASCADE;"`
+	Item     baselineItem   `gorm:"foreignKey:ItemID;constraint:OnDelete:CASCADE;"`
+}
+
+func (baselineTransaction) TableName() string {
+	return "transaction"
+}
+
+type baselineStockMovement struct {
+	gorm.Model
+	ItemID        uint         `gorm:"not null;index"`
+	Delta         int          `gorm:"not null"`
+	Reason        string       `gorm:"size:50;not null"`
+	DetailID      *uint        `gorm:"null"`
+	TransactionID *uint        `gorm:"null"`
+	ActorID       *uint        `gorm:"null"`
+	Note          string       `gorm:"size:255"`
+	Item          baselineItem `gorm:"foreignKey:ItemID;constraint:OnDelete:CASCADE;"`
+}
+
+func (baselineStockMovement) TableName() string {
+	return "stock_movement"
+}
+
+type baselineRevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (baselineRevokedToken) TableName() string {
+	return "revoked_token"
+}
+
+type baselineSessionRevocation struct {
+	gorm.Model
+	UserID        uint      `gorm:"not null;uniqueIndex:idx_session_revocation_subject"`
+	Role          string    `gorm:"size:50;not null;uniqueIndex:idx_session_revocation_subject"`
+	RevokedBefore time.Time `gorm:"not null"`
+}
+
+func (baselineSessionRevocation) TableName() string {
+	return "session_revocation"
+}
+
+type baselineSession struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index"`
+	Role       string     `gorm:"size:50;not null"`
+	Device     string     `gorm:"size:255"`
+	IP         string     `gorm:"size:64"`
+	LastUsedAt time.Time  `gorm:"not null"`
+	RevokedAt  *time.Time `gorm:"null"`
+}
+
+func (baselineSession) TableName() string {
+	return "session"
+}
+
+type baselineRefreshToken struct {
+	gorm.Model
+	TokenHash string          `gorm:"size:64;uniqueIndex;not null"`
+	SessionID uint            `gorm:"not null;index"`
+	UserID    uint            `gorm:"not null"`
+	Role      string          `gorm:"size:50;not null"`
+	ExpiresAt time.Time       `gorm:"not null"`
+	UsedAt    *time.Time      `gorm:"null"`
+	RevokedAt *time.Time      `gorm:"null"`
+	Session   baselineSession `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE;"`
+}
+
+func (baselineRefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+type baselineAdminInvitation struct {
+	gorm.Model
+	Email       string     `gorm:"size:100;not null"`
+	TokenHash   string     `gorm:"size:64;uniqueIndex;not null"`
+	InvitedByID uint       `gorm:"not null"`
+	ExpiresAt   time.Time  `gorm:"not null"`
+	UsedAt      *time.Time `gorm:"null"`
+}
+
+func (baselineAdminInvitation) TableName() string {
+	return "admin_invitation"
+}
@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+)
+
+// legacyAdmin adalah bentuk tabel admin lama sebelum digabung ke tabel user
+type legacyAdmin struct {
+	ID        uint
+	Name      string
+	Email     string
+	Password  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// mergeAdminAccounts memindahkan akun dari tabel admin lama ke tabel user dengan role admin.
+// Jika email sudah terdaftar sebagai user, akun tersebut dinaikkan menjadi admin dengan nama dan
+// password dari tabel admin. Pendaftaran user dulu terbuka untuk siapa saja, jadi password user
+// dengan email admin tidak boleh ikut menjadi password admin. Tabel lama diganti nama menjadi
+// admin_legacy, bukan dihapus.
+func mergeAdminAccounts(db *gorm.DB) error {
+	if !db.Migrator().HasTable("admin") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var admins []legacyAdmin
+		if err := tx.Table("admin").Where("deleted_at IS NULL").Find(&admins).Error; err != nil {
+			return err
+		}
+
+		for _, admin := range admins {
+			var user baselineUser
+			err := tx.Where("email = ?", admin.Email).First(&user).Error
+			if err == nil {
+				log.Printf("Merging admin %s into existing user ID %d, the user's password is replaced by the admin's", admin.Email, user.ID)
+				err := tx.Model(&user).Updates(map[string]interface{}{
+					"name":     admin.Name,
+					"password": admin.Password,
+					"role":     model.RoleAdmin,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			newUser := baselineUser{
+				Name:     admin.Name,
+				Email:    admin.Email,
+				Password: admin.Password,
+				Role:     model.RoleAdmin,
+			}
+			newUser.CreatedAt = admin.CreatedAt
+			if err := tx.Create(&newUser).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Migrator().RenameTable("admin", "admin_legacy")
+	})
+}
+
+// dedupeDetailCodes memberi akhiran ID pada kode detail lama yang kembar sebelum unique index
+// pada detail.code dibuat. Detail pertama dengan kode tersebut tetap memakai kode aslinya.
+func dedupeDetailCodes(db *gorm.DB) error {
+	if !db.Migrator().HasTable("detail") || db.Migrator().HasIndex(&baselineDetail{}, "Code") {
+		return nil
+	}
+
+	var duplicates []struct {
+		Code  string
+		First uint
+	}
+	err := db.Table("detail").Select("code, MIN(id) AS first").Group("code").Having("COUNT(*) > 1").Scan(&duplicates).Error
+	if err != nil {
+		return err
+	}
+
+	for _, duplicate := range duplicates {
+		err := db.Exec("UPDATE detail SET code = "+concatID(db)+" WHERE code = ? AND id <> ?", duplicate.Code, duplicate.First).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// concatID adalah ekspresi SQL "code-id" sesuai dialek database
+func concatID(db *gorm.DB) string {
+	if db.Dialector.Name() == "mysql" {
+		return "CONCAT(code, '-', id)"
+	}
+	return "code || '-' || id"
+}
+
+// renameReturnStatus mengganti status lama "return" menjadi "returned" sesuai state machine loan
+func renameReturnStatus(db *gorm.DB) error {
+	return db.Exec("UPDATE detail SET status = ? WHERE status = ?", "returned", "return").Error
+}
+
+// openingStockBalances mencatat stok item yang belum punya riwayat di ledger sebagai saldo awal,
+// sehingga jumlah ledger sama dengan Item.Stock untuk data yang dibuat sebelum ledger ada
+func openingStockBalances(db *gorm.DB) error {
+	var items []baselineItem
+	if err := db.Where("NOT EXISTS (SELECT 1 FROM stock_movement WHERE stock_movement.item_id = item.id)").Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		movement := baselineStockMovement{
+			ItemID: item.ID,
+			Delta:  item.Stock,
+			Reason: "adjustment",
+			Note:   "opening balance",
+		}
+		if err := db.Create(&movement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package migration mengelola versi skema database. Setiap migrasi ditulis dalam Go dan ikut
+// ter-compile ke binary, sehingga bisa memakai Migrator GORM yang sama di MySQL, PostgreSQL
+// dan SQLite. Versi yang sudah dijalankan dicatat di tabel schema_migration.
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew dikembalikan jika database sudah dimigrasi oleh binary yang lebih baru
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// Migration adalah satu langkah perubahan skema. Up dan Down dijalankan dalam transaksi
+// (kecuali DDL di MySQL yang selalu auto-commit), jadi sebaiknya tetap aman diulang.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration mencatat migrasi yang sudah dijalankan
+type SchemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:100;not null"`
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migration"
+}
+
+// Latest adalah versi skema terbaru yang dikenal binary ini
+func Latest() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Current mengembalikan versi skema database, 0 jika belum pernah dimigrasi
+func Current(db *gorm.DB) (int, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return 0, err
+	}
+	return Version(db)
+}
+
+// Version membaca versi skema database tanpa membuat tabel schema_migration,
+// aman dipanggil berulang kali misalnya dari pemeriksaan readiness
+func Version(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+
+	var version int
+	err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Check memastikan binary ini mengenal versi skema database, lalu mengembalikan migrasi
+// yang belum dijalankan
+func Check(db *gorm.DB) ([]Migration, error) {
+	current, err := Current(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > Latest() {
+		return nil, fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaTooNew, current, Latest())
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up menjalankan semua migrasi yang belum dijalankan secara berurutan
+func Up(db *gorm.DB) ([]Migration, error) {
+	pending, err := Check(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// Down membatalkan sejumlah steps migrasi terakhir
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	if _, err := Check(db); err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := 0; i < steps; i++ {
+		current, err := Current(db)
+		if err != nil || current == 0 {
+			return reverted, err
+		}
+
+		m, ok := find(current)
+		if !ok {
+			return reverted, fmt.Errorf("unknown migration version %d", current)
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("rollback of migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// StatusEntry adalah status satu migrasi untuk perintah migrate status
+type StatusEntry struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Status mengembalikan semua migrasi yang dikenal beserta waktu dijalankannya
+func Status(db *gorm.DB) ([]StatusEntry, error) {
+	if _, err := Current(db); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := map[int]time.Time{}
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	var entries []StatusEntry
+	for _, m := range migrations {
+		entry := StatusEntry{Migration: m}
+		if t, ok := appliedAt[m.Version]; ok {
+			entry.AppliedAt = &t
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func find(version int) (Migration, bool) {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
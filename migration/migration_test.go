@@ -0,0 +1,256 @@
+package migration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// Setiap koneksi :memory: punya database sendiri
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	db := openTestDB(t)
+
+	// Version tidak membuat tabel schema_migration
+	if version, err := Version(db); err != nil || version != 0 || db.Migrator().HasTable(&SchemaMigration{}) {
+		t.Errorf("expected version 0 without creating the table, got %d (%v)", version, err)
+	}
+
+	applied, err := Up(db)
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("expected all migrations to be applied, got %d (%v)", len(applied), err)
+	}
+	if current, _ := Current(db); current != Latest() {
+		t.Errorf("expected version %d, got %d", Latest(), current)
+	}
+	if !db.Migrator().HasTable(&model.Detail{}) || !db.Migrator().HasIndex(&model.Detail{}, "Code") {
+		t.Error("expected baseline tables and indexes to exist")
+	}
+
+	// Menjalankan ulang tidak melakukan apa-apa
+	if applied, err := Up(db); err != nil || len(applied) != 0 {
+		t.Errorf("expected no pending migrations, got %d (%v)", len(applied), err)
+	}
+
+	entries, err := Status(db)
+	if err != nil || len(entries) != len(migrations) || entries[0].AppliedAt == nil {
+		t.Errorf("unexpected status %+v (%v)", entries, err)
+	}
+
+	reverted, err := Down(db, len(migrations))
+	if err != nil || len(reverted) != len(migrations) {
+		t.Fatalf("expected all migrations to be rolled back, got %d (%v)", len(reverted), err)
+	}
+	if current, _ := Current(db); current != 0 {
+		t.Errorf("expected version 0, got %d", current)
+	}
+	if db.Migrator().HasTable(&model.Detail{}) {
+		t.Error("expected baseline tables to be dropped")
+	}
+}
+
+func TestBaselineIsFrozen(t *testing.T) {
+	db := openTestDB(t)
+
+	// Versi 1 hanya membuat skema saat baseline dirilis, kolom sesudahnya milik migrasi berikutnya
+	if err := db.Transaction(migrations[0].Up); err != nil {
+		t.Fatalf("baseline failed: %v", err)
+	}
+	later := []struct{ table, column string }{
+		{"user", "email_verified_at"}, {"user", "totp_secret"}, {"user", "oidc_subject"}, {"session", "amr"},
+	}
+	for _, c := range later {
+		if db.Migrator().HasColumn(c.table, c.column) {
+			t.Errorf("expected %s.%s not to exist after the baseline", c.table, c.column)
+		}
+	}
+
+	db.AutoMigrate(&SchemaMigration{})
+	if err := db.Create(&SchemaMigration{Version: 1, Name: migrations[0].Name}).Error; err != nil {
+		t.Fatalf("failed to record the baseline: %v", err)
+	}
+	if _, err := Up(db); err != nil {
+		t.Fatalf("expected later migrations to add their columns, got %v", err)
+	}
+	for _, c := range later {
+		if !db.Migrator().HasColumn(c.table, c.column) {
+			t.Errorf("expected %s.%s to exist", c.table, c.column)
+		}
+	}
+}
+
+func TestMigrationsCoverModels(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := Up(db); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+
+	// Migrasi memakai salinan skema, jadi perubahan model tanpa migrasi baru harus ketahuan di sini
+	models := []interface{}{
+		&model.Permission{}, &model.Role{}, &model.User{}, &model.Item{}, &model.Detail{}, &model.Transaction{},
+		&model.StockMovement{}, &model.RevokedToken{}, &model.SessionRevocation{}, &model.Session{},
+		&model.RefreshToken{}, &model.AdminInvitation{}, &model.UserToken{}, &model.LoginAttempt{},
+		&model.LoginFailure{}, &model.RecoveryCode{}, &model.APIKey{}, &model.OIDCLogin{},
+	}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			t.Fatalf("failed to parse %T: %v", m, err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(m, field.DBName) {
+				t.Errorf("expected column %s.%s to be created by a migration", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func TestStockMovementIsImmutable(t *testing.T) {
+	db := openTestDB(t)
+	db.Exec("PRAGMA foreign_keys = ON")
+	if _, err := Up(db); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if db.Migrator().HasColumn("stock_movement", "deleted_at") || db.Migrator().HasColumn("stock_movement", "updated_at") {
+		t.Error("expected stock_movement to have no updated_at or deleted_at column")
+	}
+	if !db.Migrator().HasIndex(&model.StockMovement{}, "ItemID") {
+		t.Error("expected the stock_movement.item_id index to be kept")
+	}
+	var ddl string
+	db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'stock_movement'").Scan(&ddl)
+	if strings.Contains(ddl, "CASCADE") {
+		t.Errorf("expected the item constraint not to cascade, got %s", ddl)
+	}
+
+	// Item yang punya riwayat ledger tidak bisa dihapus permanen
+	item := model.Item{Name: "Projector"}
+	db.Create(&item)
+	if err := db.Create(&model.StockMovement{ItemID: item.ID, Delta: 1, Reason: "purchase"}).Error; err != nil {
+		t.Fatalf("failed to record movement: %v", err)
+	}
+	if err := db.Unscoped().Delete(&item).Error; err == nil {
+		t.Error("expected deleting an item with ledger history to be restricted")
+	}
+}
+
+func TestSessionRevocationMergesRoles(t *testing.T) {
+	db := openTestDB(t)
+
+	// Database versi 7 menyimpan batas logout per akun dan role
+	db.AutoMigrate(&SchemaMigration{})
+	for _, m := range migrations[:7] {
+		if err := db.Transaction(m.Up); err != nil {
+			t.Fatalf("migration %d failed: %v", m.Version, err)
+		}
+		db.Create(&SchemaMigration{Version: m.Version, Name: m.Name})
+	}
+	cutoff := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	db.Create(&baselineUser{Name: "Alice", Email: "alice@example.com", Password: "hash", Role: model.RoleAdmin})
+	db.Create(&baselineSessionRevocation{UserID: 1, Role: model.RoleUser, RevokedBefore: cutoff})
+	db.Create(&baselineSessionRevocation{UserID: 1, Role: model.RoleAdmin, RevokedBefore: cutoff.Add(-time.Hour)})
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if db.Migrator().HasColumn("session_revocation", "role") {
+		t.Error("expected session_revocation.role to be dropped")
+	}
+	var revocations []model.SessionRevocation
+	db.Find(&revocations)
+	if len(revocations) != 1 || !revocations[0].RevokedBefore.Equal(cutoff) {
+		t.Errorf("expected one revocation per account with the latest cutoff, got %+v", revocations)
+	}
+
+	// Rollback mengisi role dengan role akun saat ini
+	if _, err := Down(db, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	var restored []baselineSessionRevocation
+	db.Find(&restored)
+	if len(restored) != 1 || restored[0].Role != model.RoleAdmin || !restored[0].RevokedBefore.Equal(cutoff) {
+		t.Errorf("unexpected revocations after rollback: %+v", restored)
+	}
+}
+
+func TestBaselineUpgradesLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	// Database lama: dibuat AutoMigrate tanpa unique index pada kode, dengan tabel admin terpisah
+	db.Exec("CREATE TABLE detail (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME, code TEXT NOT NULL, out DATETIME, entry DATETIME, status TEXT NOT NULL DEFAULT 'pending')")
+	db.Exec("INSERT INTO detail (id, code, status) VALUES (1, 'ivt1', 'return'), (2, 'ivt1', 'pending')")
+	db.Exec("CREATE TABLE admin (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME, name TEXT, email TEXT, password TEXT)")
+	db.Exec("INSERT INTO admin (id, name, email, password) VALUES (1, 'Admin', 'admin@example.com', 'hash')")
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var details []model.Detail
+	db.Order("id").Find(&details)
+	if len(details) != 2 || details[0].Code != "ivt1" || details[1].Code != "ivt1-2" || details[0].Status != "returned" {
+		t.Errorf("unexpected details after baseline: %+v", details)
+	}
+
+	var admin model.User
+	if err := db.Where("email = ? AND role = ?", "admin@example.com", model.RoleAdmin).First(&admin).Error; err != nil {
+		t.Errorf("expected legacy admin to be merged: %v", err)
+	}
+	if admin.EmailVerifiedAt == nil {
+		t.Error("expected existing accounts to be marked as verified")
+	}
+}
+
+func TestBaselineMergeKeepsAdminCredentials(t *testing.T) {
+	db := openTestDB(t)
+
+	// Siapa saja dulu bisa mendaftar sebagai user dengan email admin
+	db.Exec("CREATE TABLE admin (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME, name TEXT, email TEXT, password TEXT)")
+	db.Exec("INSERT INTO admin (id, name, email, password) VALUES (1, 'Admin', 'admin@example.com', 'admin-hash')")
+	db.Exec(`CREATE TABLE "user" (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME, name TEXT NOT NULL, email TEXT NOT NULL UNIQUE, password TEXT NOT NULL, role TEXT NOT NULL DEFAULT 'user')`)
+	db.Exec(`INSERT INTO "user" (id, name, email, password, role) VALUES (7, 'Squatter', 'admin@example.com', 'squatter-hash', 'user')`)
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var users []model.User
+	db.Where("email = ?", "admin@example.com").Find(&users)
+	if len(users) != 1 {
+		t.Fatalf("expected a single account for the admin email, got %d", len(users))
+	}
+	if users[0].Role != model.RoleAdmin || users[0].Password != "admin-hash" || users[0].Name != "Admin" {
+		t.Errorf("expected the admin's name and password to win, got %+v", users[0])
+	}
+}
+
+func TestRefusesNewerSchema(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := Up(db); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	db.Create(&SchemaMigration{Version: Latest() + 1, Name: "from the future"})
+
+	if _, err := Check(db); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew, got %v", err)
+	}
+	if _, err := Up(db); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected Up to refuse, got %v", err)
+	}
+}
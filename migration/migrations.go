@@ -0,0 +1,322 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+// migrations adalah daftar semua migrasi, urut berdasarkan Version. Migrasi yang sudah dirilis
+// tidak boleh diubah; perubahan skema baru ditambahkan sebagai migrasi dengan versi berikutnya.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up:      baselineUp,
+		Down:    baselineDown,
+	},
+	{
+		Version: 2,
+		Name:    "email_verification_and_user_token",
+		Up:      userTokenUp,
+		Down:    userTokenDown,
+	},
+	{
+		Version: 3,
+		Name:    "login_attempt_and_failure",
+		Up:      loginAttemptUp,
+		Down:    loginAttemptDown,
+	},
+	{
+		Version: 4,
+		Name:    "totp_and_recovery_code",
+		Up:      totpUp,
+		Down:    totpDown,
+	},
+	{
+		Version: 5,
+		Name:    "api_key",
+		Up:      apiKeyUp,
+		Down:    apiKeyDown,
+	},
+	{
+		Version: 6,
+		Name:    "oidc_login",
+		Up:      oidcUp,
+		Down:    oidcDown,
+	},
+	{
+		Version: 7,
+		Name:    "immutable_stock_movement",
+		Up:      stockMovementUp,
+		Down:    stockMovementDown,
+	},
+	{
+		Version: 8,
+		Name:    "account_wide_session_revocation",
+		Up:      sessionRevocationUp,
+		Down:    sessionRevocationDown,
+	},
+}
+
+// baselineTables adalah tabel yang dibuat AutoMigrate sebelum migrasi berversi ada, dalam bentuk
+// salinan di baseline.go dan urut dari yang tidak bergantung ke tabel lain
+var baselineTables = []interface{}{
+	&baselinePermission{}, &baselineRole{}, &baselineUser{}, &baselineItem{}, &baselineDetail{},
+	&baselineTransaction{}, &baselineStockMovement{}, &baselineRevokedToken{}, &baselineSessionRevocation{},
+	&baselineSession{}, &baselineRefreshToken{}, &baselineAdminInvitation{},
+}
+
+// baselineUp menyamakan database lama (dibuat AutoMigrate) maupun database kosong dengan skema
+// versi 1, termasuk perbaikan data yang dulu dijalankan setiap start. Semua langkah aman
+// dijalankan di database yang sudah punya tabel-tabel ini.
+func baselineUp(tx *gorm.DB) error {
+	if err := dedupeDetailCodes(tx); err != nil {
+		return err
+	}
+	if err := tx.AutoMigrate(baselineTables...); err != nil {
+		return err
+	}
+	if err := mergeAdminAccounts(tx); err != nil {
+		return err
+	}
+	if err := renameReturnStatus(tx); err != nil {
+		return err
+	}
+	return openingStockBalances(tx)
+}
+
+// baselineDown menghapus semua tabel aplikasi. Penggabungan tabel admin lama tidak dikembalikan.
+func baselineDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable("role_permission"); err != nil {
+		return err
+	}
+	for i := len(baselineTables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userTokenUp menambahkan kolom verifikasi email dan tabel token reset password/verifikasi.
+// Akun yang sudah ada dianggap terverifikasi agar tidak tiba-tiba diblokir mengajukan peminjaman.
+func userTokenUp(tx *gorm.DB) error {
+	if err := tx.Migrator().AddColumn(&v2User{}, "EmailVerifiedAt"); err != nil {
+		return err
+	}
+	if err := tx.Model(&v2User{}).Where("email_verified_at IS NULL").
+		UpdateColumn("email_verified_at", gorm.Expr("COALESCE(created_at, CURRENT_TIMESTAMP)")).Error; err != nil {
+		return err
+	}
+	return tx.Migrator().CreateTable(&v2UserToken{})
+}
+
+func userTokenDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable(&v2UserToken{}); err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&v2User{}, "EmailVerifiedAt")
+}
+
+// loginAttemptUp menambahkan tabel penghitung login gagal (lockout) dan audit login gagal
+func loginAttemptUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v3LoginAttempt{}, &v3LoginFailure{})
+}
+
+func loginAttemptDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v3LoginFailure{}, &v3LoginAttempt{})
+}
+
+// totpColumns adalah kolom TOTP pada user dan metode autentikasi pada sesi
+var totpColumns = []struct {
+	model interface{}
+	field string
+}{
+	{&v4User{}, "TOTPSecret"},
+	{&v4User{}, "TOTPEnabledAt"},
+	{&v4User{}, "TOTPLastStep"},
+	{&v4Session{}, "AMR"},
+}
+
+// totpUp menambahkan autentikasi dua faktor TOTP beserta recovery code
+func totpUp(tx *gorm.DB) error {
+	for _, column := range totpColumns {
+		if err := tx.Migrator().AddColumn(column.model, column.field); err != nil {
+			return err
+		}
+	}
+	return tx.Migrator().CreateTable(&v4RecoveryCode{})
+}
+
+func totpDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable(&v4RecoveryCode{}); err != nil {
+		return err
+	}
+	for i := len(totpColumns) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropColumn(totpColumns[i].model, totpColumns[i].field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apiKeyUp menambahkan API key untuk klien mesin
+func apiKeyUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v5APIKey{})
+}
+
+func apiKeyDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v5APIKey{})
+}
+
+// oidcColumns adalah kolom akun untuk login SSO
+var oidcColumns = []string{"OIDCSubject", "PasswordLoginDisabled"}
+
+// oidcUp menambahkan login SSO OpenID Connect: hubungan akun dengan IdP, opsi mematikan login
+// password, dan state login yang sedang berjalan
+func oidcUp(tx *gorm.DB) error {
+	for _, field := range oidcColumns {
+		if err := tx.Migrator().AddColumn(&v6User{}, field); err != nil {
+			return err
+		}
+	}
+	if err := tx.Migrator().CreateIndex(&v6User{}, "OIDCSubject"); err != nil {
+		return err
+	}
+	return tx.Migrator().CreateTable(&v6OIDCLogin{})
+}
+
+func oidcDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable(&v6OIDCLogin{}); err != nil {
+		return err
+	}
+	if err := tx.Migrator().DropIndex(&v6User{}, "OIDCSubject"); err != nil {
+		return err
+	}
+	for i := len(oidcColumns) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropColumn(&v6User{}, oidcColumns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stockMovementUp menjadikan ledger stok append-only: kolom updated_at dan deleted_at dihapus,
+// dan item yang masih punya riwayat tidak bisa dihapus permanen. Pergerakan yang pernah
+// di-soft delete tidak ikut dihitung Reconcile, sehingga dihapus agar saldo ledger tidak berubah.
+func stockMovementUp(tx *gorm.DB) error {
+	if err := tx.Unscoped().Where("deleted_at IS NOT NULL").Delete(&baselineStockMovement{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Migrator().DropIndex(&baselineStockMovement{}, "DeletedAt"); err != nil {
+		return err
+	}
+	for _, field := range []string{"UpdatedAt", "DeletedAt"} {
+		if err := tx.Migrator().DropColumn(&baselineStockMovement{}, field); err != nil {
+			return err
+		}
+	}
+	if err := tx.Migrator().DropConstraint(&baselineStockMovement{}, "Item"); err != nil {
+		return err
+	}
+	if err := tx.Migrator().CreateConstraint(&v7StockMovement{}, "Item"); err != nil {
+		return err
+	}
+	return restoreItemIndex(tx)
+}
+
+func stockMovementDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropConstraint(&v7StockMovement{}, "Item"); err != nil {
+		return err
+	}
+	for _, field := range []string{"UpdatedAt", "DeletedAt"} {
+		if err := tx.Migrator().AddColumn(&baselineStockMovement{}, field); err != nil {
+			return err
+		}
+	}
+	if err := tx.Migrator().CreateConstraint(&baselineStockMovement{}, "Item"); err != nil {
+		return err
+	}
+	if err := tx.Migrator().CreateIndex(&baselineStockMovement{}, "DeletedAt"); err != nil {
+		return err
+	}
+	return restoreItemIndex(tx)
+}
+
+// restoreItemIndex membuat ulang index stock_movement.item_id. SQLite mengubah constraint dengan
+// membuat ulang tabel sehingga index ikut hilang, sedangkan MySQL dan PostgreSQL tetap menyimpannya.
+func restoreItemIndex(tx *gorm.DB) error {
+	if tx.Migrator().HasIndex(&v7StockMovement{}, "ItemID") {
+		return nil
+	}
+	return tx.Migrator().CreateIndex(&v7StockMovement{}, "ItemID")
+}
+
+// sessionRevocationUp menjadikan "logout dari semua sesi" berlaku untuk akun, bukan per role,
+// karena role akun bisa berubah. Tabel dibuat ulang dengan satu baris per akun yang menyimpan
+// batas waktu paling akhir dari semua role-nya.
+func sessionRevocationUp(tx *gorm.DB) error {
+	var rows []baselineSessionRevocation
+	if err := tx.Order("id").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	latest := map[uint]*v8SessionRevocation{}
+	var merged []*v8SessionRevocation
+	for _, row := range rows {
+		if existing, ok := latest[row.UserID]; ok {
+			if row.RevokedBefore.After(existing.RevokedBefore) {
+				existing.RevokedBefore = row.RevokedBefore
+			}
+			continue
+		}
+		revocation := &v8SessionRevocation{UserID: row.UserID, RevokedBefore: row.RevokedBefore}
+		latest[row.UserID] = revocation
+		merged = append(merged, revocation)
+	}
+
+	if err := tx.Migrator().DropTable(&baselineSessionRevocation{}); err != nil {
+		return err
+	}
+	if err := tx.Migrator().CreateTable(&v8SessionRevocation{}); err != nil {
+		return err
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return tx.Create(merged).Error
+}
+
+// sessionRevocationDown mengembalikan kolom role dengan mengisi role akun saat ini
+func sessionRevocationDown(tx *gorm.DB) error {
+	var rows []v8SessionRevocation
+	if err := tx.Order("id").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	var users []baselineUser
+	if err := tx.Unscoped().Select("id", "role").Find(&users).Error; err != nil {
+		return err
+	}
+	roles := map[uint]string{}
+	for _, user := range users {
+		roles[user.ID] = user.Role
+	}
+
+	var restored []baselineSessionRevocation
+	for _, row := range rows {
+		if role, ok := roles[row.UserID]; ok {
+			restored = append(restored, baselineSessionRevocation{UserID: row.UserID, Role: role, RevokedBefore: row.RevokedBefore})
+		}
+	}
+
+	if err := tx.Migrator().DropTable(&v8SessionRevocation{}); err != nil {
+		return err
+	}
+	if err := tx.Migrator().CreateTable(&baselineSessionRevocation{}); err != nil {
+		return err
+	}
+	if len(restored) == 0 {
+		return nil
+	}
+	return tx.Create(&restored).Error
+}
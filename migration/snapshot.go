@@ -0,0 +1,170 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Struct di file ini adalah salinan bagian skema yang diubah migrasi versi 2 dan seterusnya, sama
+// seperti baseline.go untuk versi 1. Setiap migrasi memakai salinannya sendiri, sehingga mengubah
+// package model tidak mengubah apa yang dibuat migrasi lama. Struct untuk AddColumn hanya memuat
+// kolom yang ditambahkan migrasi tersebut.
+
+// Versi 2: email_verification_and_user_token
+
+type v2User struct {
+	EmailVerifiedAt *time.Time `gorm:"null"`
+}
+
+func (v2User) TableName() string {
+	return "user"
+}
+
+type v2UserToken struct {
+	gorm.Model
+	UserID    uint         `gorm:"not null;index"`
+	Purpose   string       `gorm:"size:30;not null"`
+	TokenHash string       `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time   `gorm:"null"`
+	User      baselineUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+func (v2UserToken) TableName() string {
+	return "user_token"
+}
+
+// Versi 3: login_attempt_and_failure
+
+type v3LoginAttempt struct {
+	gorm.Model
+	Subject       string     `gorm:"size:191;uniqueIndex;not null"`
+	Failures      int        `gorm:"not null"`
+	LastFailureAt time.Time  `gorm:"not null"`
+	LockedUntil   *time.Time `gorm:"index"`
+}
+
+func (v3LoginAttempt) TableName() string {
+	return "login_attempt"
+}
+
+type v3LoginFailure struct {
+	gorm.Model
+	Email     string `gorm:"size:100;index"`
+	UserID    *uint  `gorm:"null"`
+	IP        string `gorm:"size:64;index"`
+	UserAgent string `gorm:"size:255"`
+	Reason    string `gorm:"size:30;not null"`
+}
+
+func (v3LoginFailure) TableName() string {
+	return "login_failure"
+}
+
+// Versi 4: totp_and_recovery_code
+
+type v4User struct {
+	TOTPSecret    string     `gorm:"column:totp_secret;size:64"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at;null"`
+	TOTPLastStep  int64      `gorm:"column:totp_last_step;not null;default:0"`
+}
+
+func (v4User) TableName() string {
+	return "user"
+}
+
+type v4Session struct {
+	AMR string `gorm:"column:amr;size:50"`
+}
+
+func (v4Session) TableName() string {
+	return "session"
+}
+
+type v4RecoveryCode struct {
+	gorm.Model
+	UserID   uint         `gorm:"not null;index"`
+	CodeHash string       `gorm:"size:64;not null"`
+	UsedAt   *time.Time   `gorm:"null"`
+	User     baselineUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+func (v4RecoveryCode) TableName() string {
+	return "recovery_code"
+}
+
+// Versi 5: api_key
+
+type v5APIKey struct {
+	gorm.Model
+	Name        string       `gorm:"size:100;not null"`
+	Prefix      string       `gorm:"size:20;uniqueIndex;not null"`
+	KeyHash     string       `gorm:"size:64;uniqueIndex;not null"`
+	UserID      uint         `gorm:"not null;index"`
+	Scopes      string       `gorm:"size:500;not null"`
+	CreatedByID uint         `gorm:"not null"`
+	ExpiresAt   *time.Time   `gorm:"null"`
+	LastUsedAt  *time.Time   `gorm:"null"`
+	LastUsedIP  string       `gorm:"size:64"`
+	RevokedAt   *time.Time   `gorm:"null"`
+	User        baselineUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+func (v5APIKey) TableName() string {
+	return "api_key"
+}
+
+// Versi 6: oidc_login
+
+type v6User struct {
+	OIDCSubject           *string `gorm:"column:oidc_subject;size:191;uniqueIndex"`
+	PasswordLoginDisabled bool    `gorm:"column:password_login_disabled;not null;default:false"`
+}
+
+func (v6User) TableName() string {
+	return "user"
+}
+
+type v6OIDCLogin struct {
+	gorm.Model
+	StateHash string    `gorm:"size:64;uniqueIndex;not null"`
+	Nonce     string    `gorm:"size:64;not null"`
+	Verifier  string    `gorm:"size:128;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (v6OIDCLogin) TableName() string {
+	return "oidc_login"
+}
+
+// Versi 7: immutable_stock_movement
+
+type v7StockMovement struct {
+	ID            uint `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	ItemID        uint         `gorm:"not null;index"`
+	Delta         int          `gorm:"not null"`
+	Reason        string       `gorm:"size:50;not null"`
+	DetailID      *uint        `gorm:"null"`
+	TransactionID *uint        `gorm:"null"`
+	ActorID       *uint        `gorm:"null"`
+	Note          string       `gorm:"size:255"`
+	Item          baselineItem `gorm:"foreignKey:ItemID;constraint:OnDelete:RESTRICT;"`
+}
+
+func (v7StockMovement) TableName() string {
+	return "stock_movement"
+}
+
+// Versi 8: account_wide_session_revocation
+
+type v8SessionRevocation struct {
+	gorm.Model
+	UserID        uint      `gorm:"not null;uniqueIndex"`
+	RevokedBefore time.Time `gorm:"not null"`
+}
+
+func (v8SessionRevocation) TableName() string {
+	return "session_revocation"
+}
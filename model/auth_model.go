@@ -0,0 +1,274 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokedToken menyimpan jti token JWT yang sudah dicabut sampai token tersebut kedaluwarsa
+type RevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (u *RevokedToken) TableName() string {
+	return "revoked_token"
+}
+
+// SessionRevocation mencatat batas waktu di mana semua token milik satu akun dianggap tidak berlaku,
+// apa pun role akun saat token diterbitkan
+type SessionRevocation struct {
+	gorm.Model
+	UserID        uint      `gorm:"not null;uniqueIndex"`
+	RevokedBefore time.Time `gorm:"not null"`
+}
+
+func (u *SessionRevocation) TableName() string {
+	return "session_revocation"
+}
+
+// Session mewakili satu perangkat/login. Semua refresh token hasil rotasi dari satu login
+// berada di sesi (family) yang sama sehingga bisa dicabut sekaligus.
+type Session struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index"`
+	Role   string `gorm:"size:50;not null"`
+	Device string `gorm:"size:255"`
+	IP     string `gorm:"size:64"`
+	// AMR adalah metode autentikasi saat login, dipisah spasi (misalnya "pwd otp"), diteruskan
+	// ke access token hasil refresh
+	AMR        string     `gorm:"column:amr;size:50"`
+	LastUsedAt time.Time  `gorm:"not null"`
+	RevokedAt  *time.Time `gorm:"null"`
+}
+
+func (u *Session) TableName() string {
+	return "session"
+}
+
+// RefreshToken hanya menyimpan hash SHA-256 dari token, bukan token aslinya
+type RefreshToken struct {
+	gorm.Model
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"`
+	SessionID uint       `gorm:"not null;index"`
+	UserID    uint       `gorm:"not null"`
+	Role      string     `gorm:"size:50;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"null"`
+	RevokedAt *time.Time `gorm:"null"`
+	Session   Session    `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE;"`
+}
+
+func (u *RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// AdminInvitation adalah undangan sekali pakai untuk membuat akun admin baru
+type AdminInvitation struct {
+	gorm.Model
+	Email       string     `gorm:"size:100;not null"`
+	TokenHash   string     `gorm:"size:64;uniqueIndex;not null"`
+	InvitedByID uint       `gorm:"not null"`
+	ExpiresAt   time.Time  `gorm:"not null"`
+	UsedAt      *time.Time `gorm:"null"`
+}
+
+func (u *AdminInvitation) TableName() string {
+	return "admin_invitation"
+}
+
+// Tambahkan metode ToMap untuk konversi undangan ke map
+func (u *AdminInvitation) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"invitation_id": u.ID,
+		"email":         u.Email,
+		"invited_by":    u.InvitedByID,
+		"expires_at":    u.ExpiresAt.Format(time.RFC3339),
+		"created_at":    u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Tujuan UserToken
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserToken adalah token sekali pakai yang dikirim lewat email, untuk reset password atau
+// verifikasi email. Seperti refresh token, yang disimpan hanya hash SHA-256-nya.
+type UserToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index"`
+	Purpose   string     `gorm:"size:30;not null"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"null"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+func (u *UserToken) TableName() string {
+	return "user_token"
+}
+
+// LoginAttempt menghitung login gagal berturut-turut untuk satu subject, yaitu akun
+// ("account:<email>") atau alamat IP ("ip:<alamat>"), beserta batas waktu kuncinya
+type LoginAttempt struct {
+	gorm.Model
+	Subject       string     `gorm:"size:191;uniqueIndex;not null"`
+	Failures      int        `gorm:"not null"`
+	LastFailureAt time.Time  `gorm:"not null"`
+	LockedUntil   *time.Time `gorm:"index"`
+}
+
+func (u *LoginAttempt) TableName() string {
+	return "login_attempt"
+}
+
+// Tambahkan metode ToMap untuk konversi login attempt ke map
+func (u *LoginAttempt) ToMap() map[string]interface{} {
+	lockedUntil := ""
+	if u.LockedUntil != nil {
+		lockedUntil = u.LockedUntil.Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"subject":         u.Subject,
+		"failures":        u.Failures,
+		"last_failure_at": u.LastFailureAt.Format(time.RFC3339),
+		"locked_until":    lockedUntil,
+	}
+}
+
+// Alasan LoginFailure
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureLocked             = "locked"
+)
+
+// LoginFailure adalah catatan audit untuk setiap login yang gagal. UserID kosong jika email
+// tidak terdaftar.
+type LoginFailure struct {
+	gorm.Model
+	Email     string `gorm:"size:100;index"`
+	UserID    *uint  `gorm:"null"`
+	IP        string `gorm:"size:64;index"`
+	UserAgent string `gorm:"size:255"`
+	Reason    string `gorm:"size:30;not null"`
+}
+
+func (u *LoginFailure) TableName() string {
+	return "login_failure"
+}
+
+// Tambahkan metode ToMap untuk konversi login failure ke map
+func (u *LoginFailure) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"login_failure_id": u.ID,
+		"email":            u.Email,
+		"user_id":          u.UserID,
+		"ip":               u.IP,
+		"user_agent":       u.UserAgent,
+		"reason":           u.Reason,
+		"created_at":       u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// LoginFailuresToMap mengonversi daftar login failure ke slice map
+func LoginFailuresToMap(failures []LoginFailure) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, failure := range failures {
+		result = append(result, failure.ToMap())
+	}
+	return result
+}
+
+// RecoveryCode adalah kode cadangan sekali pakai untuk login jika perangkat TOTP hilang.
+// Yang disimpan hanya hash SHA-256 dari kode yang sudah dinormalisasi.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"not null;index"`
+	CodeHash string     `gorm:"size:64;not null"`
+	UsedAt   *time.Time `gorm:"null"`
+	User     User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+func (u *RecoveryCode) TableName() string {
+	return "recovery_code"
+}
+
+// APIKey adalah kredensial untuk klien mesin (kiosk, skrip sinkronisasi) yang bertindak atas
+// nama satu akun. Yang disimpan hanya hash SHA-256 key dan prefix-nya untuk dikenali.
+type APIKey struct {
+	gorm.Model
+	Name   string `gorm:"size:100;not null"`
+	Prefix string `gorm:"size:20;uniqueIndex;not null"`
+	// KeyHash adalah hash SHA-256 dari key lengkap
+	KeyHash string `gorm:"size:64;uniqueIndex;not null"`
+	UserID  uint   `gorm:"not null;index"`
+	// Scopes adalah permission yang boleh dipakai key, dipisah spasi. Permission efektifnya
+	// adalah irisan scope dengan permission role akun saat request.
+	Scopes      string     `gorm:"size:500;not null"`
+	CreatedByID uint       `gorm:"not null"`
+	ExpiresAt   *time.Time `gorm:"null"`
+	LastUsedAt  *time.Time `gorm:"null"`
+	LastUsedIP  string     `gorm:"size:64"`
+	RevokedAt   *time.Time `gorm:"null"`
+	User        User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+func (u *APIKey) TableName() string {
+	return "api_key"
+}
+
+// ScopeList mengembalikan scope key sebagai slice
+func (u *APIKey) ScopeList() []string {
+	return strings.Fields(u.Scopes)
+}
+
+// Tambahkan metode ToMap untuk konversi API key ke map, tanpa hash key
+func (u *APIKey) ToMap() map[string]interface{} {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"api_key_id":   u.ID,
+		"name":         u.Name,
+		"prefix":       u.Prefix,
+		"user_id":      u.UserID,
+		"scopes":       u.ScopeList(),
+		"created_by":   u.CreatedByID,
+		"expires_at":   format(u.ExpiresAt),
+		"last_used_at": format(u.LastUsedAt),
+		"last_used_ip": u.LastUsedIP,
+		"revoked_at":   format(u.RevokedAt),
+		"created_at":   u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// APIKeysToMap mengonversi daftar API key ke slice map
+func APIKeysToMap(keys []APIKey) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, key := range keys {
+		result = append(result, key.ToMap())
+	}
+	return result
+}
+
+// OIDCLogin menyimpan login SSO yang sedang berjalan sampai IdP memanggil callback. State hanya
+// disimpan hash-nya, dan PKCE verifier tidak pernah dikirim ke browser.
+type OIDCLogin struct {
+	gorm.Model
+	StateHash string    `gorm:"size:64;uniqueIndex;not null"`
+	Nonce     string    `gorm:"size:64;not null"`
+	Verifier  string    `gorm:"size:128;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (u *OIDCLogin) TableName() string {
+	return "oidc_login"
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -40,22 +41,23 @@ func ItemsToMap(items []Item) []map[string]interface{} {
 
 type Detail struct {
 	gorm.Model
-	Code         string        `gorm:"size:100;not null"`
+	Code         string        `gorm:"size:100;not null;uniqueIndex"`
 	Out          time.Time     `gorm:"null"`
 	Entry        time.Time     `gorm:"null"`
 	Status       string        `gorm:"size:50;not null;default:'pending'"`
+	OverdueAt    *time.Time    `gorm:"null"`
 	Transactions []Transaction `gorm:"foreignKey:DetailID;constraint:OnDelete:CASCADE;"`
 }
 
 // BeforeSave hook untuk validasi Status
 func (t *Detail) BeforeSave(tx *gorm.DB) error {
-	allowedStatuses := []string{"pending", "loaned", "return", "rejected"}
+	allowedStatuses := []string{"pending", "approved", "loaned", "returned", "rejected", "cancelled", "overdue", "lost"}
 	for _, allowedStatus := range allowedStatuses {
 		if t.Status == allowedStatus {
 			return nil
 		}
 	}
-	return fmt.Errorf("invalid status: %s, allowed values are: pending, loaned, return, rejected", t.Status)
+	return fmt.Errorf("invalid status: %s, allowed values are: %s", t.Status, strings.Join(allowedStatuses, ", "))
 }
 
 func (u *Detail) TableName() string {
@@ -70,6 +72,7 @@ func (u *Detail) ToMap() map[string]interface{} {
 		"out":        u.Out,
 		"entry":      u.Entry,
 		"status":     u.Status,
+		"overdue_at": u.OverdueAt,
 		"created_at": u.CreatedAt.Format(time.RFC3339),
 		"updated_at": u.UpdatedAt.Format(time.RFC3339),
 	}
@@ -133,3 +136,57 @@ func TransactionsToMap(transactions []Transaction) []map[string]interface{} {
 	}
 	return result
 }
+
+// StockMovement adalah catatan perubahan stok yang tidak pernah diubah atau dihapus.
+// Jumlah semua Delta untuk satu item harus sama dengan Item.Stock.
+type StockMovement struct {
+	ID            uint `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	ItemID        uint   `gorm:"not null;index"`
+	Delta         int    `gorm:"not null"`
+	Reason        string `gorm:"size:50;not null"`
+	DetailID      *uint  `gorm:"null"`
+	TransactionID *uint  `gorm:"null"`
+	ActorID       *uint  `gorm:"null"`
+	Note          string `gorm:"size:255"`
+	Item          Item   `gorm:"foreignKey:ItemID;constraint:OnDelete:RESTRICT;"`
+}
+
+// BeforeSave hook untuk validasi Reason
+func (t *StockMovement) BeforeSave(tx *gorm.DB) error {
+	allowedReasons := []string{"purchase", "adjustment", "loan", "return", "loss"}
+	for _, allowedReason := range allowedReasons {
+		if t.Reason == allowedReason {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid reason: %s, allowed values are: %s", t.Reason, strings.Join(allowedReasons, ", "))
+}
+
+func (u *StockMovement) TableName() string {
+	return "stock_movement"
+}
+
+// Tambahkan metode ToMap untuk konversi stock movement ke map
+func (u *StockMovement) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"movement_id":    u.ID,
+		"item_id":        u.ItemID,
+		"delta":          u.Delta,
+		"reason":         u.Reason,
+		"detail_id":      u.DetailID,
+		"transaction_id": u.TransactionID,
+		"actor_id":       u.ActorID,
+		"note":           u.Note,
+		"created_at":     u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Fungsi untuk mengonversi slice StockMovement ke slice map
+func StockMovementsToMap(movements []StockMovement) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, movement := range movements {
+		result = append(result, movement.ToMap())
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Daftar permission yang dikenal aplikasi
+const (
+	PermissionItemWrite     = "item:write"
+	PermissionLoanRequest   = "loan:request"
+	PermissionLoanManage    = "loan:manage"
+	PermissionDetailApprove = "detail:approve"
+	PermissionUserManage    = "user:manage"
+	PermissionAdminManage   = "admin:manage"
+)
+
+// MFAOnlyPermissions adalah permission yang tidak diberikan ke API key milik role yang wajib MFA,
+// karena API key tidak punya faktor kedua: persetujuan peminjaman dan pengelolaan akun
+var MFAOnlyPermissions = []string{PermissionDetailApprove, PermissionUserManage, PermissionAdminManage}
+
+// Daftar role bawaan
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DefaultRolePermissions adalah permission awal untuk role bawaan saat pertama kali dibuat
+var DefaultRolePermissions = map[string][]string{
+	RoleUser: {
+		PermissionLoanRequest,
+	},
+	RoleAdmin: {
+		PermissionItemWrite,
+		PermissionLoanManage,
+		PermissionDetailApprove,
+		PermissionUserManage,
+		PermissionAdminManage,
+	},
+}
+
+type Role struct {
+	gorm.Model
+	Name        string       `gorm:"size:50;uniqueIndex;not null"`
+	Permissions []Permission `gorm:"many2many:role_permission;"`
+}
+
+func (u *Role) TableName() string {
+	return "role"
+}
+
+// PermissionNames mengembalikan nama semua permission milik role
+func (u *Role) PermissionNames() []string {
+	names := []string{}
+	for _, permission := range u.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
+
+type Permission struct {
+	gorm.Model
+	Name string `gorm:"size:100;uniqueIndex;not null"`
+}
+
+func (u *Permission) TableName() string {
+	return "permission"
+}
@@ -8,11 +8,22 @@ import (
 
 type User struct {
 	gorm.Model
-	Name         string        `gorm:"size:100;not null"`
-	Email        string        `gorm:"size:100;unique;not null"`
-	Password     string        `gorm:"size:255;not null"`
-	Role         string        `gorm:"size:50;not null" default:"user"`
-	Transactions []Transaction `gorm:"foreignKey:UserID"`
+	Name     string `gorm:"size:100;not null"`
+	Email    string `gorm:"size:100;unique;not null"`
+	Password string `gorm:"size:255;not null"`
+	Role     string `gorm:"size:50;not null" default:"user"`
+	// EmailVerifiedAt kosong berarti email belum diverifikasi dan akun belum bisa mengajukan peminjaman
+	EmailVerifiedAt *time.Time `gorm:"null"`
+	// TOTPSecret diisi saat enrollment dimulai, TOTP baru aktif setelah TOTPEnabledAt terisi
+	TOTPSecret    string     `gorm:"column:totp_secret;size:64"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at;null"`
+	// TOTPLastStep adalah periode kode TOTP terakhir yang dipakai, mencegah kode dipakai ulang
+	TOTPLastStep int64 `gorm:"column:totp_last_step;not null;default:0"`
+	// OIDCSubject adalah klaim sub dari identity provider untuk akun yang terhubung dengan SSO
+	OIDCSubject *string `gorm:"column:oidc_subject;size:191;uniqueIndex"`
+	// PasswordLoginDisabled menolak login dengan password sehingga akun hanya bisa login lewat SSO
+	PasswordLoginDisabled bool          `gorm:"column:password_login_disabled;not null;default:false"`
+	Transactions          []Transaction `gorm:"foreignKey:UserID"`
 }
 
 func (u *User) TableName() string {
@@ -22,11 +33,16 @@ func (u *User) TableName() string {
 // Tambahkan metode ToMap untuk konversi user ke map
 func (u *User) ToMap() map[string]interface{} {
 	return map[string]interface{}{
-		"user_id":    u.ID,
-		"name":       u.Name,
-		"email":      u.Email,
-		"created_at": u.CreatedAt.Format(time.RFC3339),
-		"updated_at": u.UpdatedAt.Format(time.RFC3339),
+		"user_id":        u.ID,
+		"name":           u.Name,
+		"email":          u.Email,
+		"role":           u.Role,
+		"email_verified": u.EmailVerifiedAt != nil,
+		"mfa_enabled":    u.TOTPEnabledAt != nil,
+		"sso_linked":     u.OIDCSubject != nil,
+		"password_login": !u.PasswordLoginDisabled,
+		"created_at":     u.CreatedAt.Format(time.RFC3339),
+		"updated_at":     u.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
@@ -38,36 +54,3 @@ func UsersToMap(users []User) []map[string]interface{} {
 	}
 	return result
 }
-
-type Admin struct {
-	gorm.Model
-	ID       uint   `gorm:"primaryKey"`
-	Name     string `gorm:"size:100;not null"`
-	Email    string `gorm:"size:100;unique;not null"`
-	Password string `gorm:"size:255;not null"`
-	Role     string `gorm:"size:50;not null" default:"admin"`
-}
-
-func (u *Admin) TableName() string {
-	return "admin"
-}
-
-// Tambahkan metode ToMap untuk konversi user ke map
-func (u *Admin) ToMap() map[string]interface{} {
-	return map[string]interface{}{
-		"admin_id":   u.ID,
-		"name":       u.Name,
-		"email":      u.Email,
-		"created_at": u.CreatedAt.Format(time.RFC3339),
-		"updated_at": u.UpdatedAt.Format(time.RFC3339),
-	}
-}
-
-// Fungsi untuk mengonversi slice User ke slice map
-func AdminsToMap(admins []Admin) []map[string]interface{} {
-	result := []map[string]interface{}{}
-	for _, admin := range admins {
-		result = append(result, admin.ToMap())
-	}
-	return result
-}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"Gin-Inventory/model"
+	"Gin-Inventory/stock"
+
+	"gorm.io/gorm"
+)
+
+type gormItemRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormItemRepository) List(query ListQuery) ([]model.Item, int64, error) {
+	items := []model.Item{}
+	total, err := list(r.db.Model(&model.Item{}), ItemList, query, &items)
+	return items, total, err
+}
+
+func (r *gormItemRepository) Find(id uint) (model.Item, error) {
+	var item model.Item
+	err := first(r.db, &item, id)
+	return item, err
+}
+
+func (r *gormItemRepository) Create(item *model.Item, initial model.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		initial.ItemID = item.ID
+		if err := stock.Move(tx, &initial); err != nil {
+			return err
+		}
+		item.Stock += initial.Delta
+		return nil
+	})
+}
+
+func (r *gormItemRepository) Update(item *model.Item, target *int, movement model.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(item).Update("name", item.Name).Error; err != nil {
+			return err
+		}
+		if target == nil {
+			return nil
+		}
+		return stock.Set(tx, item, *target, &movement)
+	})
+}
+
+func (r *gormItemRepository) InUse(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Transaction{}).Where("item_id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// Delete menghapus item secara soft delete karena riwayat ledger-nya tetap disimpan
+func (r *gormItemRepository) Delete(item *model.Item) error {
+	return r.db.Delete(item).Error
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListSpec mendefinisikan parameter query yang boleh dipakai sebuah daftar beserta kolomnya.
+// Semua nama kolom berasal dari spec ini, bukan dari input pengguna.
+type ListSpec struct {
+	// Filters memetakan parameter query ke kolom untuk filter sama dengan, misalnya ?status=loaned
+	Filters map[string]string
+	// TimeFilters memetakan awalan parameter ke kolom waktu: ?created_after= dan ?created_before=
+	TimeFilters map[string]string
+	// Sorts memetakan kunci sort ke kolom, ?sort=-created_at,name (awalan "-" berarti menurun).
+	// Kolom kunci "id" (primary key) selalu ditambahkan ke ORDER BY agar urutan antarhalaman stabil.
+	Sorts map[string]string
+	// DefaultSort dipakai jika ?sort tidak diisi
+	DefaultSort string
+}
+
+// Sort adalah satu kunci dari ListSpec.Sorts beserta arahnya
+type Sort struct {
+	Key  string
+	Desc bool
+}
+
+// TimeRange membatasi kolom waktu: After inklusif, Before eksklusif, zero time berarti tanpa batas
+type TimeRange struct {
+	After  time.Time
+	Before time.Time
+}
+
+// ListQuery adalah filter, urutan dan halaman sebuah daftar. Kuncinya adalah nama parameter
+// di ListSpec, bukan kolom, dan sudah divalidasi oleh helper.ParseList.
+type ListQuery struct {
+	Filters map[string]string
+	Ranges  map[string]TimeRange
+	Sorts   []Sort
+	Page    int
+	PerPage int
+}
+
+// Offset adalah jumlah baris sebelum halaman q
+func (q ListQuery) Offset() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+// Spesifikasi setiap daftar. Alias tabel mengikuti query di repository masing-masing.
+var (
+	// ItemList dipakai GET /item
+	ItemList = ListSpec{
+		Filters:     map[string]string{"name": "item.name"},
+		TimeFilters: map[string]string{"created": "item.created_at"},
+		Sorts: map[string]string{
+			"id":         "item.id",
+			"name":       "item.name",
+			"stock":      "item.stock",
+			"created_at": "item.created_at",
+		},
+		DefaultSort: "id",
+	}
+
+	// MovementList dipakai GET /item/:item_id/movements
+	MovementList = ListSpec{
+		Filters:     map[string]string{"reason": "stock_movement.reason", "detail_id": "stock_movement.detail_id"},
+		TimeFilters: map[string]string{"created": "stock_movement.created_at"},
+		Sorts: map[string]string{
+			"id":         "stock_movement.id",
+			"created_at": "stock_movement.created_at",
+		},
+		DefaultSort: "id",
+	}
+
+	// CartList dipakai GET /chart
+	CartList = ListSpec{
+		Filters: map[string]string{
+			"status":  "t.status",
+			"item_id": "t.item_id",
+			"user_id": "t.user_id",
+		},
+		TimeFilters: map[string]string{"created": "t.created_at"},
+		Sorts: map[string]string{
+			"id":         "t.id",
+			"quantity":   "t.quantity",
+			"status":     "t.status",
+			"item_name":  "i.name",
+			"created_at": "t.created_at",
+		},
+		DefaultSort: "id",
+	}
+
+	// DetailList dipakai GET /detail
+	DetailList = ListSpec{
+		Filters: map[string]string{
+			"status":  "d.status",
+			"code":    "d.code",
+			"item_id": "t.item_id",
+			"user_id": "t.user_id",
+		},
+		TimeFilters: map[string]string{
+			"created": "d.created_at",
+			"out":     "d.out",
+			"entry":   "d.entry",
+		},
+		Sorts: map[string]string{
+			"id":         "d.id",
+			"code":       "d.code",
+			"status":     "d.status",
+			"out":        "d.out",
+			"entry":      "d.entry",
+			"created_at": "d.created_at",
+		},
+		DefaultSort: "id",
+	}
+
+	// UserList dipakai GET /user dan GET /admin
+	UserList = ListSpec{
+		Filters:     map[string]string{"name": "name", "email": "email"},
+		TimeFilters: map[string]string{"created": "created_at"},
+		Sorts: map[string]string{
+			"id":         "id",
+			"name":       "name",
+			"email":      "email",
+			"created_at": "created_at",
+		},
+		DefaultSort: "id",
+	}
+
+	// LoginFailureList dipakai GET /login-failures
+	LoginFailureList = ListSpec{
+		Filters:     map[string]string{"email": "email", "ip": "ip", "reason": "reason", "user_id": "user_id"},
+		TimeFilters: map[string]string{"created": "created_at"},
+		Sorts: map[string]string{
+			"id":         "id",
+			"email":      "email",
+			"ip":         "ip",
+			"created_at": "created_at",
+		},
+		DefaultSort: "-created_at",
+	}
+
+	// APIKeyList dipakai GET /api-keys
+	APIKeyList = ListSpec{
+		Filters:     map[string]string{"user_id": "user_id", "prefix": "prefix", "name": "name"},
+		TimeFilters: map[string]string{"created": "created_at", "last_used": "last_used_at", "expires": "expires_at"},
+		Sorts: map[string]string{
+			"id":           "id",
+			"name":         "name",
+			"created_at":   "created_at",
+			"last_used_at": "last_used_at",
+			"expires_at":   "expires_at",
+		},
+		DefaultSort: "-created_at",
+	}
+)
+
+// list menerapkan filter q ke query, menghitung total, lalu mengisi dest dengan satu halaman.
+// selects diisi untuk query dengan join sehingga hasilnya di-Scan ke dest; kosong berarti Find.
+func list(query *gorm.DB, spec ListSpec, q ListQuery, dest interface{}, selects ...string) (int64, error) {
+	query = filter(query, spec, q)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	query = query.Order(orderBy(spec, q.Sorts)).Limit(q.PerPage).Offset(q.Offset())
+	if len(selects) > 0 {
+		return total, query.Select(strings.Join(selects, ", ")).Scan(dest).Error
+	}
+	return total, query.Find(dest).Error
+}
+
+func filter(query *gorm.DB, spec ListSpec, q ListQuery) *gorm.DB {
+	for param, value := range q.Filters {
+		if column, ok := spec.Filters[param]; ok {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for prefix, r := range q.Ranges {
+		column, ok := spec.TimeFilters[prefix]
+		if !ok {
+			continue
+		}
+		if !r.After.IsZero() {
+			query = query.Where(column+" >= ?", r.After)
+		}
+		if !r.Before.IsZero() {
+			query = query.Where(column+" < ?", r.Before)
+		}
+	}
+	return query
+}
+
+// orderBy membuat klausa ORDER BY dari sorts. Kunci yang tidak ada di spec diabaikan.
+func orderBy(spec ListSpec, sorts []Sort) string {
+	pk, ok := spec.Sorts["id"]
+	if !ok {
+		pk = "id"
+	}
+
+	var orders []string
+	sortedByPK := false
+	for _, sort := range sorts {
+		column, ok := spec.Sorts[sort.Key]
+		if !ok {
+			continue
+		}
+		direction := "ASC"
+		if sort.Desc {
+			direction = "DESC"
+		}
+		orders = append(orders, column+" "+direction)
+		if column == pk {
+			sortedByPK = true
+		}
+	}
+
+	// Baris dengan nilai sort yang sama diurutkan berdasarkan primary key, tanpa itu database
+	// bebas menukar urutannya sehingga baris bisa muncul dua kali atau terlewat antarhalaman
+	if !sortedByPK {
+		orders = append(orders, pk+" ASC")
+	}
+	return strings.Join(orders, ", ")
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/metrics"
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormLoanRepository struct {
+	db *gorm.DB
+}
+
+// Nama tabel diberi alias dan di-quote karena "transaction" dan "user" adalah kata kunci SQL
+func (r *gormLoanRepository) Carts(filter CartFilter, query ListQuery) ([]CartSummary, int64, error) {
+	base := r.db.Table("?", clause.Table{Name: "transaction", Alias: "t"}).
+		Joins("LEFT JOIN ? ON i.id = t.item_id", clause.Table{Name: "item", Alias: "i"}).
+		Joins("LEFT JOIN ? ON u.id = t.user_id", clause.Table{Name: "user", Alias: "u"})
+	if filter.UserID != 0 {
+		base = base.Where("t.user_id = ?", filter.UserID)
+	}
+
+	carts := []CartSummary{}
+	total, err := list(base, CartList, query, &carts,
+		"u.name AS user_name, t.id, t.quantity, t.status, i.id AS item_id, i.name AS item_name, i.stock AS stock, t.created_at, t.updated_at")
+	return carts, total, err
+}
+
+func (r *gormLoanRepository) FindCart(id uint) (model.Transaction, error) {
+	var cart model.Transaction
+	err := first(r.db, &cart, id)
+	return cart, err
+}
+
+func (r *gormLoanRepository) FindDraftCart(userID, itemID uint) (model.Transaction, error) {
+	var cart model.Transaction
+	err := first(r.db.Where("user_id = ? AND item_id = ? AND status = ?", userID, itemID, "draft"), &cart)
+	return cart, err
+}
+
+func (r *gormLoanRepository) DraftCarts(userID uint) ([]model.Transaction, error) {
+	var carts []model.Transaction
+	err := r.db.Where("user_id = ? AND status = ?", userID, "draft").Find(&carts).Error
+	return carts, err
+}
+
+func (r *gormLoanRepository) SaveCart(cart *model.Transaction) error {
+	return r.db.Save(cart).Error
+}
+
+func (r *gormLoanRepository) DeleteCart(cart *model.Transaction) error {
+	return r.db.Unscoped().Delete(cart).Error
+}
+
+func (r *gormLoanRepository) Capacity(item model.Item) (int, error) {
+	return loan.Capacity(r.db, item)
+}
+
+func (r *gormLoanRepository) Calendar(itemID uint, from, to time.Time) (loan.Calendar, error) {
+	return loan.ItemCalendar(r.db, itemID, from, to)
+}
+
+// cartQuantities menjumlahkan keranjang per item dan mengembalikan ID item yang perlu dikunci
+func cartQuantities(carts []model.Transaction) (map[uint]int, []uint) {
+	quantities := map[uint]int{}
+	var itemIDs []uint
+	for _, cart := range carts {
+		if _, ok := quantities[cart.ItemID]; !ok {
+			itemIDs = append(itemIDs, cart.ItemID)
+		}
+		quantities[cart.ItemID] += cart.Quantity
+	}
+	return quantities, itemIDs
+}
+
+func (r *gormLoanRepository) Submit(carts []model.Transaction, detail *model.Detail, codes loan.CodeGenerator, from, to time.Time) ([]model.Transaction, error) {
+	quantities, itemIDs := cartQuantities(carts)
+
+	// Buat detail baru dengan kode unik, diulang dengan kode berikutnya jika bentrok
+	var created model.Detail
+	var submitted []model.Transaction
+	err := loan.CreateWithCode(r.db, codes, time.Now(), func(tx *gorm.DB, code string) error {
+		// Kunci baris item agar dua pengajuan paralel tidak sama-sama lolos pemeriksaan ketersediaan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", itemIDs).Find(&[]model.Item{}).Error; err != nil {
+			return err
+		}
+		if err := loan.CheckAvailability(tx, quantities, from, to); err != nil {
+			return err
+		}
+
+		created = *detail
+		created.Code = code
+		if err := tx.Create(&created).Error; err != nil {
+			return err
+		}
+
+		// Hubungkan setiap keranjang dengan detail baru
+		submitted = nil
+		for _, cart := range carts {
+			cart.DetailID = &created.ID
+			cart.Status = loan.StatusPending
+			if err := tx.Save(&cart).Error; err != nil {
+				return fmt.Errorf("Failed to update transaction ID %d", cart.ID)
+			}
+			submitted = append(submitted, cart)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	*detail = created
+	return submitted, nil
+}
+
+// details adalah query detail dengan alias d (detail), t (transaction), i (item) dan u (user)
+func (r *gormLoanRepository) details() *gorm.DB {
+	return r.db.Table("?", clause.Table{Name: "detail", Alias: "d"}).
+		Joins("LEFT JOIN ? ON t.detail_id = d.id", clause.Table{Name: "transaction", Alias: "t"}).
+		Joins("LEFT JOIN ? ON i.id = t.item_id", clause.Table{Name: "item", Alias: "i"}).
+		Joins("LEFT JOIN ? ON u.id = t.user_id", clause.Table{Name: "user", Alias: "u"})
+}
+
+func (r *gormLoanRepository) Details(filter DetailFilter, query ListQuery) ([]DetailSummary, int64, error) {
+	// Satu baris per detail walau join menghasilkan satu baris per keranjang, sehingga
+	// pagination dan total menghitung detail. Keranjangnya dimuat sesudahnya.
+	base := r.details().Distinct("d.id")
+	if filter.UserID != 0 {
+		base = base.Where("t.user_id = ?", filter.UserID)
+	}
+	if filter.Overdue != nil {
+		if *filter.Overdue {
+			base = base.Where("d.status = ?", loan.StatusOverdue)
+		} else {
+			base = base.Where("d.status <> ?", loan.StatusOverdue)
+		}
+	}
+
+	details := []DetailSummary{}
+	total, err := list(base, DetailList, query, &details,
+		"u.name AS user_name, d.id, d.code, d.out, d.entry, d.status, d.overdue_at, d.created_at, d.updated_at")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, len(details))
+	for i := range details {
+		ids[i] = details[i].ID
+	}
+	lines, err := r.detailLines(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range details {
+		details[i].Items = []DetailLine{}
+		for _, line := range lines {
+			if line.DetailID == details[i].ID {
+				details[i].Items = append(details[i].Items, line)
+			}
+		}
+	}
+	return details, total, nil
+}
+
+// detailLines mengembalikan keranjang milik detail-detail tersebut, urut per detail
+func (r *gormLoanRepository) detailLines(detailIDs []uint) ([]DetailLine, error) {
+	lines := []DetailLine{}
+	if len(detailIDs) == 0 {
+		return lines, nil
+	}
+	err := r.db.Table("?", clause.Table{Name: "transaction", Alias: "t"}).
+		Joins("LEFT JOIN ? ON i.id = t.item_id", clause.Table{Name: "item", Alias: "i"}).
+		Select("t.detail_id, t.item_id, i.name AS item_name, t.quantity").
+		Where("t.detail_id IN ? AND t.deleted_at IS NULL", detailIDs).
+		Order("t.detail_id, t.id").
+		Scan(&lines).Error
+	return lines, err
+}
+
+func (r *gormLoanRepository) FindDetail(id uint) (model.Detail, error) {
+	var detail model.Detail
+	err := first(r.db.Preload("Transactions"), &detail, id)
+	return detail, err
+}
+
+func (r *gormLoanRepository) FindDetailByCode(code string) (model.Detail, error) {
+	var detail model.Detail
+	err := first(r.db.Where("code = ?", code), &detail)
+	return detail, err
+}
+
+func (r *gormLoanRepository) View(id uint) (DetailView, error) {
+	var view DetailView
+	err := r.details().
+		Select("u.name AS user_name, d.code, d.out, d.entry, d.status, t.quantity, i.name AS item_name").
+		Where("d.id = ?", id).
+		Scan(&view).Error
+	if err != nil {
+		return view, err
+	}
+	if view.Code == "" && view.Status == "" && view.ItemName == "" {
+		return view, ErrNotFound
+	}
+	return view, nil
+}
+
+func (r *gormLoanRepository) OwnsDetail(detailID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Transaction{}).Where("detail_id = ? AND user_id = ?", detailID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormLoanRepository) UpdateDates(detail *model.Detail, from, to time.Time) error {
+	quantities, itemIDs := cartQuantities(detail.Transactions)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris item seperti Submit agar perubahan tanggal tidak lolos bersamaan dengan pengajuan lain
+		if len(itemIDs) > 0 {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", itemIDs).Find(&[]model.Item{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := loan.CheckAvailabilityExcept(tx, quantities, from, to, detail.ID); err != nil {
+			return err
+		}
+
+		// Ubah detail hanya jika statusnya belum diubah oleh request lain secara bersamaan
+		result := tx.Model(detail).Where("status = ?", loan.StatusPending).Updates(map[string]interface{}{
+			"out":   detail.Out,
+			"entry": detail.Entry,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return loan.ErrConflict
+		}
+		return nil
+	})
+}
+
+func (r *gormLoanRepository) Transition(detailID uint, action string, actor loan.Actor) (model.Detail, error) {
+	detail, err := loan.Apply(r.db, detailID, action, actor)
+	if err == nil {
+		metrics.LoanTransitions.WithLabelValues(action).Inc()
+	}
+	return detail, err
+}
+
+func (r *gormLoanRepository) DeleteDetail(detail *model.Detail) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("detail_id = ?", detail.ID).Delete(&model.Transaction{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(detail).Error
+	})
+}
@@ -0,0 +1,215 @@
+// Package repository memisahkan akses database dari handler. Handler menerima interface di
+// bawah ini yang hanya memakai tipe model dan struct biasa, sehingga aturan bisnisnya bisa diuji
+// dengan implementasi palsu tanpa database. Implementasi GORM dipakai di produksi, dan OpenSQLite
+// menyediakan database SQLite sementara untuk pengujian.
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound       = errors.New("record not found")
+	ErrInvitationUsed = errors.New("invitation already used")
+	ErrTokenUsed      = errors.New("token already used")
+	ErrAPIKeyRevoked  = errors.New("api key already revoked")
+	ErrSetupCompleted = errors.New("an admin already exists")
+)
+
+// ItemRepository mengelola data item
+type ItemRepository interface {
+	// List mengembalikan satu halaman item menurut ItemList beserta jumlah seluruhnya
+	List(query ListQuery) ([]model.Item, int64, error)
+	Find(id uint) (model.Item, error)
+	// Create menyimpan item baru beserta pergerakan stok awalnya dalam satu transaksi
+	Create(item *model.Item, initial model.StockMovement) error
+	// Update menyimpan nama item, dan jika stock tidak nil mengubah stok lewat ledger.
+	// Gagal dengan stock.ErrConflict jika stok sudah berubah sejak item dibaca.
+	Update(item *model.Item, stock *int, movement model.StockMovement) error
+	// InUse memeriksa apakah item dipakai dalam transaksi
+	InUse(id uint) (bool, error)
+	Delete(item *model.Item) error
+}
+
+// StockRepository mengelola ledger pergerakan stok
+type StockRepository interface {
+	// Movements mengembalikan satu halaman riwayat pergerakan stok item menurut MovementList
+	Movements(itemID uint, query ListQuery) ([]model.StockMovement, int64, error)
+	// Move mencatat pergerakan stok dan menerapkannya ke stok item, lihat stock.Move
+	Move(movement *model.StockMovement) error
+}
+
+// LoanRepository mengelola keranjang (transaction) dan peminjaman (detail)
+type LoanRepository interface {
+	// Carts mengembalikan satu halaman keranjang menurut CartList
+	Carts(filter CartFilter, query ListQuery) ([]CartSummary, int64, error)
+	FindCart(id uint) (model.Transaction, error)
+	// FindDraftCart mencari keranjang draft milik user untuk item tertentu
+	FindDraftCart(userID, itemID uint) (model.Transaction, error)
+	DraftCarts(userID uint) ([]model.Transaction, error)
+	// SaveCart membuat keranjang baru atau memperbarui yang sudah ada
+	SaveCart(cart *model.Transaction) error
+	DeleteCart(cart *model.Transaction) error
+
+	// Capacity adalah jumlah unit item yang dimiliki, lihat loan.Capacity
+	Capacity(item model.Item) (int, error)
+	Calendar(itemID uint, from, to time.Time) (loan.Calendar, error)
+
+	// Submit mengajukan keranjang sebagai detail baru dengan kode unik setelah memastikan
+	// item tersedia pada rentang from-to. Keranjang yang dikembalikan sudah berstatus pending.
+	Submit(carts []model.Transaction, detail *model.Detail, codes loan.CodeGenerator, from, to time.Time) ([]model.Transaction, error)
+	// Details mengembalikan satu halaman detail menurut DetailList beserta keranjangnya. Satu
+	// detail dihitung sekali walau berisi beberapa keranjang.
+	Details(filter DetailFilter, query ListQuery) ([]DetailSummary, int64, error)
+	// FindDetail mencari detail beserta keranjangnya
+	FindDetail(id uint) (model.Detail, error)
+	FindDetailByCode(code string) (model.Detail, error)
+	View(id uint) (DetailView, error)
+	// OwnsDetail memeriksa apakah detail berisi keranjang milik user
+	OwnsDetail(detailID, userID uint) (bool, error)
+	// UpdateDates menyimpan tanggal detail selama statusnya masih pending setelah memastikan
+	// item keranjangnya tersedia pada rentang from-to, tanpa menghitung reservasi detail itu sendiri.
+	// Gagal dengan loan.ErrConflict jika status sudah diubah request lain.
+	UpdateDates(detail *model.Detail, from, to time.Time) error
+	// Transition menjalankan aksi state machine, lihat loan.Apply
+	Transition(detailID uint, action string, actor loan.Actor) (model.Detail, error)
+	// DeleteDetail menghapus detail beserta keranjangnya
+	DeleteDetail(detail *model.Detail) error
+}
+
+// UserRepository mengelola akun user dan admin, undangan admin serta token yang dikirim lewat email
+type UserRepository interface {
+	// List mengembalikan satu halaman akun dengan role tertentu menurut UserList
+	List(role string, query ListQuery) ([]model.User, int64, error)
+	Find(id uint) (model.User, error)
+	FindByEmail(email string) (model.User, error)
+	EmailExists(email string) (bool, error)
+	CountByRole(role string) (int64, error)
+	Create(user *model.User) error
+	// CreateFirstAdmin membuat admin pertama, gagal dengan ErrSetupCompleted jika sudah ada admin.
+	// Pemeriksaan dan pembuatan berada dalam satu transaksi agar dua request setup tidak sama-sama lolos.
+	CreateFirstAdmin(admin *model.User) error
+	Save(user *model.User) error
+	// Delete menghapus akun beserta keranjang dan detailnya. Barang yang masih dipinjam
+	// dikembalikan ke stok atas nama actorID.
+	Delete(user *model.User, actorID uint) error
+
+	CreateInvitation(invitation *model.AdminInvitation) error
+	FindInvitation(tokenHash string) (model.AdminInvitation, error)
+	// AcceptInvitation menandai undangan terpakai dan membuat akun admin dalam satu transaksi,
+	// gagal dengan ErrInvitationUsed jika undangan sudah dipakai
+	AcceptInvitation(invitation model.AdminInvitation, admin *model.User) error
+
+	// CreateToken menyimpan token email baru dan membatalkan token lain milik akun yang sama
+	// dengan tujuan yang sama, sehingga hanya tautan terakhir yang berlaku
+	CreateToken(token *model.UserToken) error
+	FindToken(purpose, tokenHash string) (model.UserToken, error)
+	// ResetPassword menandai token terpakai dan mengganti password akun dalam satu transaksi,
+	// gagal dengan ErrTokenUsed jika token sudah dipakai
+	ResetPassword(token model.UserToken, passwordHash string) error
+	// VerifyEmail menandai token terpakai dan email akun terverifikasi, gagal dengan ErrTokenUsed
+	VerifyEmail(token model.UserToken) error
+
+	// LoginFailures mengembalikan satu halaman catatan audit login gagal menurut LoginFailureList
+	LoginFailures(query ListQuery) ([]model.LoginFailure, int64, error)
+
+	// RolePermissions mengembalikan nama permission milik role
+	RolePermissions(role string) ([]string, error)
+	// APIKeys mengembalikan satu halaman API key menurut APIKeyList
+	APIKeys(query ListQuery) ([]model.APIKey, int64, error)
+	FindAPIKey(id uint) (model.APIKey, error)
+	CreateAPIKey(key *model.APIKey) error
+	// RevokeAPIKey mencabut API key, gagal dengan ErrAPIKeyRevoked jika key sudah dicabut
+	RevokeAPIKey(key *model.APIKey) error
+}
+
+// DetailView adalah detail beserta nama peminjam dan item untuk GET /detail/:detail_id
+type DetailView struct {
+	Code     string    `json:"code"`
+	UserName string    `json:"user"`
+	Out      time.Time `json:"out"`
+	Entry    time.Time `json:"entry"`
+	Status   string    `json:"status"`
+	Quantity int       `json:"quantity"`
+	ItemName string    `json:"item_name"`
+}
+
+// CartFilter membatasi daftar keranjang, UserID 0 berarti keranjang semua akun
+type CartFilter struct {
+	UserID uint
+}
+
+// CartSummary adalah satu keranjang pada daftar keranjang
+type CartSummary struct {
+	ID        uint   `json:"transaction_id"`
+	ItemID    uint   `json:"item_id"`
+	UserName  string `json:"user"`
+	ItemName  string `json:"item_name"`
+	Stock     int    `json:"stock"`
+	Quantity  int    `json:"quantity"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// DetailFilter membatasi daftar detail. UserID 0 berarti detail semua akun, Overdue nil berarti
+// tanpa filter keterlambatan.
+type DetailFilter struct {
+	UserID  uint
+	Overdue *bool
+}
+
+// DetailSummary adalah satu detail pada daftar detail beserta keranjangnya
+type DetailSummary struct {
+	ID        uint         `json:"detail_id"`
+	Code      string       `json:"code"`
+	UserName  string       `json:"user"`
+	Out       time.Time    `json:"out"`
+	Entry     time.Time    `json:"entry"`
+	Status    string       `json:"status"`
+	OverdueAt *time.Time   `json:"overdue_at"`
+	Items     []DetailLine `json:"items" gorm:"-"`
+	CreatedAt string       `json:"created_at"`
+	UpdatedAt string       `json:"updated_at"`
+}
+
+// DetailLine adalah satu keranjang pada daftar detail
+type DetailLine struct {
+	DetailID uint   `json:"-"`
+	ItemID   uint   `json:"item_id"`
+	ItemName string `json:"item_name"`
+	Quantity int    `json:"quantity"`
+}
+
+// Repositories mengumpulkan semua repository yang dipakai route
+type Repositories struct {
+	Items ItemRepository
+	Stock StockRepository
+	Loans LoanRepository
+	Users UserRepository
+}
+
+// NewGorm membuat semua repository di atas koneksi GORM db
+func NewGorm(db *gorm.DB) Repositories {
+	return Repositories{
+		Items: &gormItemRepository{db: db},
+		Stock: &gormStockRepository{db: db},
+		Loans: &gormLoanRepository{db: db},
+		Users: &gormUserRepository{db: db},
+	}
+}
+
+// first menjalankan query.First dan menerjemahkan gorm.ErrRecordNotFound menjadi ErrNotFound
+func first(query *gorm.DB, dest interface{}, conds ...interface{}) error {
+	err := query.First(dest, conds...).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/model"
+)
+
+func TestGormRepositories(t *testing.T) {
+	db, err := OpenSQLite("")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	repos := NewGorm(db)
+
+	if _, err := repos.Items.Find(42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	admin := model.User{Name: "Admin", Email: "admin@example.com", Password: "x", Role: model.RoleAdmin}
+	borrower := model.User{Name: "Borrower", Email: "borrower@example.com", Password: "x", Role: model.RoleUser}
+	repos.Users.Create(&admin)
+	repos.Users.Create(&borrower)
+
+	item := model.Item{Name: "Drill"}
+	if err := repos.Items.Create(&item, model.StockMovement{Delta: 4, Reason: "purchase"}); err != nil || item.Stock != 4 {
+		t.Fatalf("expected item with initial stock 4, got %d (%v)", item.Stock, err)
+	}
+
+	// Barang yang masih dipinjam dikembalikan ke stok saat peminjamnya dihapus
+	detail := model.Detail{Code: "loaned", Status: loan.StatusLoaned}
+	db.Create(&detail)
+	db.Create(&model.Transaction{UserID: borrower.ID, ItemID: item.ID, DetailID: &detail.ID, Quantity: 3, Status: "pending"})
+	db.Model(&item).UpdateColumn("stock", 1)
+
+	if inUse, _ := repos.Items.InUse(item.ID); !inUse {
+		t.Error("expected item to be in use")
+	}
+	if err := repos.Users.Delete(&borrower, admin.ID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	item, _ = repos.Items.Find(item.ID)
+	if item.Stock != 4 {
+		t.Errorf("expected stock to be restored to 4, got %d", item.Stock)
+	}
+	if _, err := repos.Loans.FindDetail(detail.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected detail to be deleted, got %v", err)
+	}
+
+	// Undangan hanya bisa dipakai sekali
+	invitation := model.AdminInvitation{Email: "new@example.com", TokenHash: "hash", InvitedByID: admin.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	repos.Users.CreateInvitation(&invitation)
+	first := model.User{Name: "New", Email: "new@example.com", Password: "x", Role: model.RoleAdmin}
+	if err := repos.Users.AcceptInvitation(invitation, &first); err != nil {
+		t.Fatalf("failed to accept invitation: %v", err)
+	}
+	second := model.User{Name: "New", Email: "new2@example.com", Password: "x", Role: model.RoleAdmin}
+	if err := repos.Users.AcceptInvitation(invitation, &second); !errors.Is(err, ErrInvitationUsed) {
+		t.Errorf("expected ErrInvitationUsed, got %v", err)
+	}
+	if count, _ := repos.Users.CountByRole(model.RoleAdmin); count != 2 {
+		t.Errorf("expected 2 admins, got %d", count)
+	}
+
+	// Token email baru membatalkan token lama dengan tujuan yang sama, dan hanya bisa dipakai sekali
+	old := model.UserToken{UserID: admin.ID, Purpose: model.TokenPurposePasswordReset, TokenHash: "old", ExpiresAt: time.Now().Add(time.Hour)}
+	repos.Users.CreateToken(&old)
+	current := model.UserToken{UserID: admin.ID, Purpose: model.TokenPurposePasswordReset, TokenHash: "current", ExpiresAt: time.Now().Add(time.Hour)}
+	repos.Users.CreateToken(&current)
+	if err := repos.Users.ResetPassword(old, "new-hash"); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("expected superseded token to be rejected, got %v", err)
+	}
+	if _, err := repos.Users.FindToken(model.TokenPurposeEmailVerification, "current"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected token lookup to check the purpose, got %v", err)
+	}
+	if err := repos.Users.ResetPassword(current, "new-hash"); err != nil {
+		t.Fatalf("failed to reset password: %v", err)
+	}
+	if err := repos.Users.ResetPassword(current, "other-hash"); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("expected ErrTokenUsed, got %v", err)
+	}
+	if reset, _ := repos.Users.FindByEmail(admin.Email); reset.Password != "new-hash" || reset.EmailVerifiedAt == nil {
+		t.Errorf("expected password to be reset and email verified, got %+v", reset)
+	}
+}
+
+func TestCreateFirstAdminOnlyOnce(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "setup.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	repos := NewGorm(db)
+
+	// Request setup bersamaan: hanya satu yang boleh membuat admin
+	var wg sync.WaitGroup
+	created := make(chan bool, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			admin := model.User{Name: "Admin", Email: fmt.Sprintf("admin%d@example.com", i), Password: "x", Role: model.RoleAdmin}
+			created <- repos.Users.CreateFirstAdmin(&admin) == nil
+		}(i)
+	}
+	wg.Wait()
+	close(created)
+
+	successes := 0
+	for ok := range created {
+		if ok {
+			successes++
+		}
+	}
+	if count, _ := repos.Users.CountByRole(model.RoleAdmin); successes != 1 || count != 1 {
+		t.Errorf("expected exactly one admin to be created, got %d successes and %d admins", successes, count)
+	}
+
+	late := model.User{Name: "Late", Email: "late@example.com", Password: "x", Role: model.RoleAdmin}
+	if err := repos.Users.CreateFirstAdmin(&late); !errors.Is(err, ErrSetupCompleted) {
+		t.Errorf("expected ErrSetupCompleted, got %v", err)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	itemSpec := ListSpec{Sorts: map[string]string{"id": "id", "name": "name", "stock": "stock"}}
+	detailSpec := ListSpec{Sorts: map[string]string{"id": "d.id", "code": "d.code", "out": "d.out"}}
+	noIDSpec := ListSpec{Sorts: map[string]string{"created_at": "created_at"}}
+
+	cases := []struct {
+		sorts []Sort
+		spec  ListSpec
+		want  string
+	}{
+		// Kolom bernama sama dengan key-nya tetap mendapat tiebreak primary key
+		{[]Sort{{Key: "name"}}, itemSpec, "name ASC, id ASC"},
+		{[]Sort{{Key: "stock", Desc: true}, {Key: "name"}}, itemSpec, "stock DESC, name ASC, id ASC"},
+		{[]Sort{{Key: "id", Desc: true}}, itemSpec, "id DESC"},
+		{[]Sort{{Key: "stock"}, {Key: "id", Desc: true}}, itemSpec, "stock ASC, id DESC"},
+		{[]Sort{{Key: "code", Desc: true}}, detailSpec, "d.code DESC, d.id ASC"},
+		{[]Sort{{Key: "out"}, {Key: "id"}}, detailSpec, "d.out ASC, d.id ASC"},
+		{[]Sort{{Key: "created_at", Desc: true}}, noIDSpec, "created_at DESC, id ASC"},
+		{[]Sort{{Key: "password"}}, itemSpec, "id ASC"},
+	}
+	for _, tc := range cases {
+		if got := orderBy(tc.spec, tc.sorts); got != tc.want {
+			t.Errorf("orderBy(%+v) = %q, want %q", tc.sorts, got, tc.want)
+		}
+	}
+}
+
+func TestListQueries(t *testing.T) {
+	db, err := OpenSQLite("")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	repos := NewGorm(db)
+
+	for i := 1; i <= 5; i++ {
+		db.Create(&model.Item{Name: fmt.Sprintf("Item %d", i), Stock: i % 2})
+	}
+	page := ListQuery{Filters: map[string]string{"name": "Item 3"}, Page: 1, PerPage: 2}
+	if items, total, err := repos.Items.List(page); err != nil || total != 1 || len(items) != 1 || items[0].Name != "Item 3" {
+		t.Errorf("expected only Item 3, got %v (total %d, %v)", items, total, err)
+	}
+	page = ListQuery{Sorts: []Sort{{Key: "name", Desc: true}}, Page: 2, PerPage: 2}
+	items, total, err := repos.Items.List(page)
+	if err != nil || total != 5 || len(items) != 2 || items[0].Name != "Item 3" || items[1].Name != "Item 2" {
+		t.Errorf("expected Item 3 and Item 2 on page 2, got %v (total %d, %v)", items, total, err)
+	}
+
+	alice := model.User{Name: "Alice", Email: "alice@example.com", Password: "x", Role: model.RoleUser}
+	bob := model.User{Name: "Bob", Email: "bob@example.com", Password: "x", Role: model.RoleUser}
+	repos.Users.Create(&alice)
+	repos.Users.Create(&bob)
+
+	// Detail Alice berisi dua keranjang tetapi dihitung satu kali
+	loaned := model.Detail{Code: "loaned", Status: loan.StatusLoaned}
+	overdue := model.Detail{Code: "overdue", Status: loan.StatusOverdue}
+	db.Create(&loaned)
+	db.Create(&overdue)
+	db.Create(&model.Transaction{UserID: alice.ID, ItemID: 1, DetailID: &loaned.ID, Quantity: 1, Status: "finish"})
+	db.Create(&model.Transaction{UserID: alice.ID, ItemID: 2, DetailID: &loaned.ID, Quantity: 1, Status: "finish"})
+	db.Create(&model.Transaction{UserID: bob.ID, ItemID: 3, DetailID: &overdue.ID, Quantity: 1, Status: "finish"})
+
+	all := ListQuery{Page: 1, PerPage: 20}
+	details, total, err := repos.Loans.Details(DetailFilter{}, all)
+	if err != nil || total != 2 || len(details) != 2 || len(details[0].Items) != 2 || len(details[1].Items) != 1 {
+		t.Fatalf("expected 2 details with 2 and 1 items, got %+v (total %d, %v)", details, total, err)
+	}
+	if details, total, _ := repos.Loans.Details(DetailFilter{UserID: bob.ID}, all); total != 1 || details[0].Code != "overdue" || details[0].UserName != "Bob" {
+		t.Errorf("expected only Bob's detail, got %+v", details)
+	}
+	notOverdue := false
+	if details, total, _ := repos.Loans.Details(DetailFilter{Overdue: &notOverdue}, all); total != 1 || details[0].Code != "loaned" {
+		t.Errorf("expected only the loaned detail, got %+v", details)
+	}
+
+	if carts, total, err := repos.Loans.Carts(CartFilter{UserID: alice.ID}, all); err != nil || total != 2 || carts[0].ItemName != "Item 1" || carts[0].UserName != "Alice" {
+		t.Errorf("expected Alice's 2 carts, got %+v (total %d, %v)", carts, total, err)
+	}
+}
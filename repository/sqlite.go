@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"Gin-Inventory/config"
+	"Gin-Inventory/migration"
+
+	"gorm.io/gorm"
+)
+
+// OpenSQLite membuka database SQLite dengan skema terbaru sebagai pengganti MySQL saat pengujian.
+// Path kosong berarti database di memori; gunakan file sementara jika beberapa request perlu
+// berjalan paralel.
+func OpenSQLite(path string) (*gorm.DB, error) {
+	if path == "" {
+		path = ":memory:"
+	}
+	db, err := config.OpenDatabase("sqlite://" + path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migration.Up(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"Gin-Inventory/model"
+	"Gin-Inventory/stock"
+
+	"gorm.io/gorm"
+)
+
+type gormStockRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormStockRepository) Movements(itemID uint, query ListQuery) ([]model.StockMovement, int64, error) {
+	movements := []model.StockMovement{}
+	total, err := list(r.db.Model(&model.StockMovement{}).Where("item_id = ?", itemID), MovementList, query, &movements)
+	return movements, total, err
+}
+
+func (r *gormStockRepository) Move(movement *model.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return stock.Move(tx, movement)
+	})
+}
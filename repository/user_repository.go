@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"Gin-Inventory/loan"
+	"Gin-Inventory/model"
+	"Gin-Inventory/stock"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) List(role string, query ListQuery) ([]model.User, int64, error) {
+	users := []model.User{}
+	total, err := list(r.db.Model(&model.User{}).Where("role = ?", role), UserList, query, &users)
+	return users, total, err
+}
+
+func (r *gormUserRepository) Find(id uint) (model.User, error) {
+	var user model.User
+	err := first(r.db, &user, id)
+	return user, err
+}
+
+func (r *gormUserRepository) FindByEmail(email string) (model.User, error) {
+	var user model.User
+	err := first(r.db.Where("email = ?", email), &user)
+	return user, err
+}
+
+func (r *gormUserRepository) EmailExists(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUserRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+func (r *gormUserRepository) Create(user *model.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *gormUserRepository) CreateFirstAdmin(admin *model.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Baris role admin dikunci sebagai mutex, sehingga request setup berikutnya baru
+		// menghitung admin setelah transaksi ini selesai
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", model.RoleAdmin).Find(&[]model.Role{}).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.User{}).Where("role = ?", model.RoleAdmin).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSetupCompleted
+		}
+		return tx.Create(admin).Error
+	})
+}
+
+func (r *gormUserRepository) Save(user *model.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *gormUserRepository) Delete(user *model.User, actorID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Cari semua transaksi milik user
+		var transactions []model.Transaction
+		if err := tx.Where("user_id = ?", user.ID).Find(&transactions).Error; err != nil {
+			return err
+		}
+
+		// Proses jika barang masih dipinjam (loaned atau overdue), kembalikan stok item
+		var detailIDs []uint
+		for _, transaction := range transactions {
+			if transaction.DetailID == nil {
+				continue
+			}
+			detailIDs = append(detailIDs, *transaction.DetailID)
+
+			var detail model.Detail
+			if err := first(tx, &detail, *transaction.DetailID); err != nil {
+				return err
+			}
+
+			if detail.Status == loan.StatusLoaned || detail.Status == loan.StatusOverdue {
+				// Detail akan dihapus, jadi referensi ke detail dan transaksi tidak disimpan di ledger
+				movement := model.StockMovement{
+					ItemID:  transaction.ItemID,
+					Delta:   transaction.Quantity,
+					Reason:  stock.ReasonReturn,
+					ActorID: &actorID,
+					Note:    fmt.Sprintf("returned on deletion of user %d", user.ID),
+				}
+				if err := stock.Move(tx, &movement); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Hapus detail dan transaksi terkait, lalu user
+		if len(detailIDs) > 0 {
+			if err := tx.Unscoped().Where("id IN (?)", detailIDs).Delete(&model.Detail{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&model.Transaction{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(user).Error
+	})
+}
+
+func (r *gormUserRepository) CreateInvitation(invitation *model.AdminInvitation) error {
+	return r.db.Create(invitation).Error
+}
+
+func (r *gormUserRepository) FindInvitation(tokenHash string) (model.AdminInvitation, error) {
+	var invitation model.AdminInvitation
+	err := first(r.db.Where("token_hash = ?", tokenHash), &invitation)
+	return invitation, err
+}
+
+func (r *gormUserRepository) AcceptInvitation(invitation model.AdminInvitation, admin *model.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Tandai undangan terpakai secara kondisional agar tidak bisa dipakai dua kali
+		result := tx.Model(&model.AdminInvitation{}).Where("id = ? AND used_at IS NULL", invitation.ID).Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationUsed
+		}
+		return tx.Create(admin).Error
+	})
+}
+
+func (r *gormUserRepository) CreateToken(token *model.UserToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *gormUserRepository) FindToken(purpose, tokenHash string) (model.UserToken, error) {
+	var token model.UserToken
+	err := first(r.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash), &token)
+	return token, err
+}
+
+func (r *gormUserRepository) ResetPassword(token model.UserToken, passwordHash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := useToken(tx, token); err != nil {
+			return err
+		}
+		// Reset lewat email juga membuktikan alamat email milik akun ini
+		now := time.Now()
+		return tx.Model(&model.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password":          passwordHash,
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", now),
+		}).Error
+	})
+}
+
+func (r *gormUserRepository) VerifyEmail(token model.UserToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := useToken(tx, token); err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", token.UserID).Update("email_verified_at", time.Now()).Error
+	})
+}
+
+func (r *gormUserRepository) LoginFailures(query ListQuery) ([]model.LoginFailure, int64, error) {
+	failures := []model.LoginFailure{}
+	total, err := list(r.db.Model(&model.LoginFailure{}), LoginFailureList, query, &failures)
+	return failures, total, err
+}
+
+func (r *gormUserRepository) RolePermissions(role string) ([]string, error) {
+	var found model.Role
+	err := r.db.Preload("Permissions").Where("name = ?", role).First(&found).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return found.PermissionNames(), nil
+}
+
+func (r *gormUserRepository) APIKeys(query ListQuery) ([]model.APIKey, int64, error) {
+	keys := []model.APIKey{}
+	total, err := list(r.db.Model(&model.APIKey{}), APIKeyList, query, &keys)
+	return keys, total, err
+}
+
+func (r *gormUserRepository) FindAPIKey(id uint) (model.APIKey, error) {
+	var key model.APIKey
+	err := first(r.db, &key, id)
+	return key, err
+}
+
+func (r *gormUserRepository) CreateAPIKey(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *gormUserRepository) RevokeAPIKey(key *model.APIKey) error {
+	now := time.Now()
+	result := r.db.Model(&model.APIKey{}).Where("id = ? AND revoked_at IS NULL", key.ID).Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyRevoked
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+// useToken menandai token terpakai secara kondisional agar tidak bisa dipakai dua kali
+func useToken(tx *gorm.DB, token model.UserToken) error {
+	result := tx.Model(&model.UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenUsed
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Package response berisi envelope yang dipakai semua response sukses API:
+// {"data": ..., "message": ..., "meta": ..., "links": ...}. Response error memakai
+// application/problem+json dari middleware.ErrorHandler.
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope adalah bentuk semua response sukses. Data selalu ada (null jika tidak ada isinya),
+// Message, Meta dan Links hanya jika relevan.
+type Envelope struct {
+	Data    interface{} `json:"data"`
+	Message string      `json:"message,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"`
+	Links   interface{} `json:"links,omitempty"`
+}
+
+// JSON mengirim data dalam envelope
+func JSON(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, Envelope{Data: data})
+}
+
+// Message mengirim data dalam envelope beserta pesan untuk pengguna, data boleh nil
+func Message(c *gin.Context, status int, message string, data interface{}) {
+	c.JSON(status, Envelope{Data: data, Message: message})
+}
@@ -0,0 +1,14 @@
+package route
+
+import (
+	"Gin-Inventory/controller"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SetupHealthRoutes memasang /healthz dan /readyz di luar /api/v1 untuk orkestrator container
+func SetupHealthRoutes(r gin.IRouter, db *gorm.DB) {
+	r.GET("/healthz", controller.HealthzHandler)
+	r.GET("/readyz", controller.ReadyzHandler(db))
+}
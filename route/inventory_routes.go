@@ -1,32 +1,52 @@
 package route
 
 import (
+	"Gin-Inventory/config"
 	"Gin-Inventory/controller"
+	"Gin-Inventory/loan"
 	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupItemRoutes(api *gin.RouterGroup) {
-	api.GET("/item", controller.GetAllItemHandler)
-	api.GET("/item/:item_id", controller.GetItemHandler)
+func SetupItemRoutes(api *gin.RouterGroup, cfg config.Config, authCfg middleware.AuthConfig, repos repository.Repositories) {
+	loanCodes := loan.CodeGenerator{Prefix: cfg.LoanCodePrefix, DateFormat: cfg.LoanCodeDateFormat}
+
+	api.GET("/item", controller.GetAllItemHandler(repos.Items))
+	api.GET("/item/:item_id", controller.GetItemHandler(repos.Items))
+	api.GET("/item/:item_id/availability", controller.GetItemAvailabilityHandler(repos.Loans))
 
 	auth := api.Group("/")
-	auth.Use(middleware.AuthMiddleware())
+	auth.Use(middleware.AuthMiddleware(authCfg))
 	{
-		auth.POST("/item", controller.CreateItemHandler)
-		auth.PUT("/item/:item_id", controller.UpdateItemHandler)
-		auth.DELETE("/item/:item_id", controller.DeleteItemHandler)
+		itemWrite := middleware.RequirePermission(model.PermissionItemWrite)
+		auth.POST("/item", itemWrite, controller.CreateItemHandler(repos.Items))
+		auth.PUT("/item/:item_id", itemWrite, controller.UpdateItemHandler(repos.Items))
+		auth.DELETE("/item/:item_id", itemWrite, controller.DeleteItemHandler(repos.Items))
+		auth.GET("/item/:item_id/movements", itemWrite, controller.GetItemMovementsHandler(repos.Items, repos.Stock))
+		auth.POST("/item/:item_id/movements", itemWrite, controller.CreateItemMovementHandler(repos.Items, repos.Stock))
 
-		auth.GET("/chart", controller.GetTransactionsHandler)
-		auth.POST("/chart", controller.CreateTransactionHandler)
-		auth.PUT("/chart/:chart_id", controller.UpdateTransactionHandler)
-		auth.DELETE("/chart/:chart_id", controller.DeleteTransactionHandler)
+		loanRequest := middleware.RequirePermission(model.PermissionLoanRequest)
+		auth.GET("/chart", controller.GetTransactionsHandler(repos.Loans))
+		auth.POST("/chart", loanRequest, controller.CreateTransactionHandler(repos.Items, repos.Loans))
+		auth.PUT("/chart/:chart_id", controller.UpdateTransactionHandler(repos.Loans))
+		auth.DELETE("/chart/:chart_id", controller.DeleteTransactionHandler(repos.Loans))
 
-		auth.GET("/detail", controller.GetAllDetailHandler)
-		auth.GET("/detail/:detail_id", controller.GetDetailHandler)
-		auth.POST("/detail", controller.CreateDetailHandler)
-		auth.PUT("/detail/:detail_id", controller.UpdateDetailHandler)
-		auth.DELETE("/detail/:detail_id", controller.DeleteDetailHandler)
+		auth.GET("/detail", controller.GetAllDetailHandler(repos.Loans))
+		auth.GET("/detail/:detail_id", controller.GetDetailHandler(repos.Loans))
+		auth.GET("/detail/by-code/:code", controller.GetDetailByCodeHandler(repos.Loans))
+		// Pengajuan peminjaman hanya untuk akun yang email-nya sudah diverifikasi
+		auth.POST("/detail", loanRequest, middleware.RequireVerifiedEmail(), controller.CreateDetailHandler(repos.Loans, loanCodes))
+		auth.PUT("/detail/:detail_id", controller.UpdateDetailHandler(repos.Loans))
+		auth.POST("/detail/:detail_id/approve", controller.TransitionDetailHandler(repos.Loans, loan.ActionApprove))
+		auth.POST("/detail/:detail_id/reject", controller.TransitionDetailHandler(repos.Loans, loan.ActionReject))
+		auth.POST("/detail/:detail_id/cancel", controller.TransitionDetailHandler(repos.Loans, loan.ActionCancel))
+		auth.POST("/detail/:detail_id/resubmit", controller.TransitionDetailHandler(repos.Loans, loan.ActionResubmit))
+		auth.POST("/detail/:detail_id/checkout", controller.TransitionDetailHandler(repos.Loans, loan.ActionCheckout))
+		auth.POST("/detail/:detail_id/return", controller.TransitionDetailHandler(repos.Loans, loan.ActionReturn))
+		auth.POST("/detail/:detail_id/lost", controller.TransitionDetailHandler(repos.Loans, loan.ActionLose))
+		auth.DELETE("/detail/:detail_id", controller.DeleteDetailHandler(repos.Loans))
 	}
 }
@@ -0,0 +1,14 @@
+package route
+
+import (
+	"Gin-Inventory/config"
+	"Gin-Inventory/controller"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SetupMetricsRoutes memasang /metrics di luar /api/v1 untuk di-scrape Prometheus
+func SetupMetricsRoutes(r gin.IRouter, cfg config.Config, registry *prometheus.Registry) {
+	r.GET("/metrics", controller.MetricsHandler(registry, cfg.MetricsToken))
+}
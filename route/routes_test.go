@@ -0,0 +1,700 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Gin-Inventory/config"
+	"Gin-Inventory/mail"
+	"Gin-Inventory/mfa"
+	"Gin-Inventory/middleware"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/scheduler"
+	"Gin-Inventory/sso"
+	"Gin-Inventory/sso/ssotest"
+
+	"github.com/gin-gonic/gin"
+)
+
+const setupToken = "test-setup-token"
+
+// testServer adalah router lengkap seperti di main.go di atas database SQLite sementara
+type testServer struct {
+	t      *testing.T
+	router *gin.Engine
+	mailer *recordingMailer
+}
+
+// recordingMailer menyimpan email yang dikirim agar token di dalamnya bisa dipakai test
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var tokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)|\n([A-Za-z0-9_-]{43})\n`)
+
+// wait menunggu sampai jumlah email terkirim mencapai count, untuk email yang dikirim di background
+func (m *recordingMailer) wait(t *testing.T, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m.mu.Lock()
+		sent := len(m.sent)
+		m.mu.Unlock()
+		if sent >= count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d emails, got %d", count, sent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// token mengambil token dari email terakhir ke alamat to
+func (m *recordingMailer) token(t *testing.T, to string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To != to {
+			continue
+		}
+		match := tokenPattern.FindStringSubmatch(m.sent[i].Body)
+		if match == nil {
+			t.Fatalf("no token in email to %s: %q", to, m.sent[i].Body)
+		}
+		return match[1] + match[2]
+	}
+	t.Fatalf("no email sent to %s", to)
+	return ""
+}
+
+// newTestServer menyiapkan router lengkap; options mengubah konfigurasi default sebelum dipakai
+func newTestServer(t *testing.T, options ...func(*config.Config)) *testServer {
+	t.Helper()
+
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "e2e.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.JWTSecret = "test-secret"
+	cfg.TokenStore = "memory"
+	cfg.AdminSetupToken = setupToken
+	for _, option := range options {
+		option(&cfg)
+	}
+	config.PrepareDatabase(cfg, db)
+
+	auth := middleware.AuthConfig{
+		DB:         db,
+		Secret:     []byte(cfg.JWTSecret),
+		TokenTTL:   cfg.JWTExpire,
+		RefreshTTL: cfg.RefreshTokenExpire,
+		Store:      config.NewRevocationStore(cfg, db),
+		Attempts:   config.NewLoginAttemptStore(cfg, db),
+		Lockout: middleware.LockoutPolicy{
+			MaxAttempts:      cfg.LoginMaxAttempts,
+			MaxAttemptsPerIP: cfg.LoginMaxAttemptsPerIP,
+			Lockout:          cfg.LoginLockout,
+			MaxLockout:       cfg.LoginMaxLockout,
+			Window:           cfg.LoginAttemptWindow,
+		},
+		MFARequiredRoles: cfg.MFARequiredRoles,
+		MFAIssuer:        cfg.MFAIssuer,
+		MFAChallengeTTL:  cfg.MFAChallengeExpire,
+		SSO:              config.NewSSOProvider(cfg),
+		SSOStateTTL:      cfg.OIDCStateExpire,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		t.Fatalf("failed to set trusted proxies: %v", err)
+	}
+	r.Use(middleware.ErrorHandler())
+	repos := repository.NewGorm(db)
+	api := r.Group("/api/v1")
+	mailer := &recordingMailer{}
+	tasks := scheduler.New(scheduler.RealClock{})
+	t.Cleanup(tasks.Wait)
+	SetupUserRoutes(api, cfg, auth, repos, mailer, tasks)
+	SetupItemRoutes(api, cfg, auth, repos)
+
+	return &testServer{t: t, router: r, mailer: mailer}
+}
+
+// do mengirim request ke /api/v1 dan mengembalikan status beserta body JSON
+func (s *testServer) do(method, path, token string, body interface{}, headers ...string) (int, map[string]interface{}) {
+	s.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, "/api/v1"+path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	result := map[string]interface{}{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return w.Code, result
+}
+
+// expect menjalankan request dan menggagalkan test jika status tidak sesuai
+func (s *testServer) expect(status int, method, path, token string, body interface{}, headers ...string) map[string]interface{} {
+	s.t.Helper()
+
+	code, result := s.do(method, path, token, body, headers...)
+	if code != status {
+		s.t.Fatalf("%s %s: expected %d, got %d %v", method, path, status, code, result)
+	}
+	return result
+}
+
+func (s *testServer) login(email, password string) string {
+	s.t.Helper()
+
+	result := s.expect(200, http.MethodPost, "/login", "", gin.H{"email": email, "password": password})
+	return data(result)["token"].(string)
+}
+
+// register mendaftarkan user lalu memverifikasi email-nya lewat tautan yang dikirim
+func (s *testServer) register(name, email, password string) {
+	s.t.Helper()
+
+	s.expect(201, http.MethodPost, "/user", "", gin.H{"name": name, "email": email, "password": password})
+	s.expect(200, http.MethodGet, "/email/verify?token="+s.mailer.token(s.t, email), "", nil)
+}
+
+// data mengambil isi "data" dari envelope response, atau objek di dalamnya untuk setiap key
+func data(result map[string]interface{}, keys ...string) map[string]interface{} {
+	object := result["data"].(map[string]interface{})
+	for _, key := range keys {
+		object = object[key].(map[string]interface{})
+	}
+	return object
+}
+
+// id mengambil field ID dari objek, misalnya id(data(result), "item_id")
+func id(object map[string]interface{}, field string) uint {
+	return uint(object[field].(float64))
+}
+
+func (s *testServer) stock(itemID uint) int {
+	s.t.Helper()
+
+	result := s.expect(200, http.MethodGet, fmt.Sprintf("/item/%d", itemID), "", nil)
+	return int(data(result)["stock"].(float64))
+}
+
+func TestBorrowingFlow(t *testing.T) {
+	s := newTestServer(t)
+
+	// Admin pertama dibuat dengan setup token, lalu menambahkan item
+	s.expect(403, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"})
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+	item := id(data(s.expect(201, http.MethodPost, "/item", admin, gin.H{"name": "Projector", "stock": 2})), "item_id")
+
+	// Registrasi dan login peminjam
+	s.register("Borrower", "borrower@example.com", "secret")
+	s.expect(401, http.MethodPost, "/login", "", gin.H{"email": "borrower@example.com", "password": "wrong"})
+	borrower := s.login("borrower@example.com", "secret")
+
+	// Keranjang tidak boleh melebihi jumlah unit
+	s.expect(401, http.MethodPost, "/chart", "", gin.H{"item_id": item, "quantity": 1})
+	s.expect(400, http.MethodPost, "/chart", borrower, gin.H{"item_id": item, "quantity": 3})
+	s.expect(404, http.MethodPost, "/chart", borrower, gin.H{"item_id": 999, "quantity": 1})
+	s.expect(201, http.MethodPost, "/chart", borrower, gin.H{"item_id": item, "quantity": 2})
+	speaker := id(data(s.expect(201, http.MethodPost, "/item", admin, gin.H{"name": "Speaker", "stock": 1})), "item_id")
+	s.expect(201, http.MethodPost, "/chart", borrower, gin.H{"item_id": speaker, "quantity": 1})
+
+	// Ajukan keranjang sebagai detail
+	result := s.expect(201, http.MethodPost, "/detail", borrower, gin.H{"out": "2030-01-10", "entry": "2030-01-12"})
+	detail := id(data(result, "detail"), "detail_id")
+	code := data(result, "detail")["code"].(string)
+	s.expect(200, http.MethodGet, "/detail/by-code/"+code, borrower, nil)
+
+	// Daftar detail dihitung per detail, bukan per keranjang
+	list := s.expect(200, http.MethodGet, "/detail", borrower, nil)
+	details := list["data"].([]interface{})
+	if total := list["meta"].(map[string]interface{})["total"].(float64); total != 1 || len(details) != 1 {
+		t.Fatalf("expected 1 detail, got total %v with %d rows", total, len(details))
+	}
+	if items := details[0].(map[string]interface{})["items"].([]interface{}); len(items) != 2 {
+		t.Errorf("expected the detail to list 2 items, got %v", items)
+	}
+
+	// Peminjam tidak boleh menyetujui atau mengeluarkan barangnya sendiri
+	s.expect(403, http.MethodPost, fmt.Sprintf("/detail/%d/approve", detail), borrower, nil)
+	s.expect(400, http.MethodPost, fmt.Sprintf("/detail/%d/checkout", detail), admin, nil)
+
+	// Admin menyetujui dan mengeluarkan barang, stok berkurang
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/approve", detail), admin, nil)
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/checkout", detail), admin, nil)
+	if stock := s.stock(item); stock != 0 {
+		t.Errorf("expected stock 0 after checkout, got %d", stock)
+	}
+
+	// Detail yang sedang dipinjam tidak bisa diubah, dibatalkan atau dihapus
+	s.expect(400, http.MethodPut, fmt.Sprintf("/detail/%d", detail), borrower, gin.H{"entry": "2030-01-20"})
+	s.expect(400, http.MethodPost, fmt.Sprintf("/detail/%d/cancel", detail), borrower, nil)
+	s.expect(400, http.MethodDelete, fmt.Sprintf("/detail/%d", detail), borrower, nil)
+	s.expect(400, http.MethodDelete, fmt.Sprintf("/item/%d", item), admin, nil)
+
+	// Pengembalian hanya oleh admin, stok kembali
+	s.expect(403, http.MethodPost, fmt.Sprintf("/detail/%d/return", detail), borrower, nil)
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/return", detail), admin, nil)
+	s.expect(400, http.MethodPost, fmt.Sprintf("/detail/%d/return", detail), admin, nil)
+	if stock := s.stock(item); stock != 2 {
+		t.Errorf("expected stock 2 after return, got %d", stock)
+	}
+
+	// Checkout dan pengembalian tercatat di ledger bersama stok awal
+	movements := s.expect(200, http.MethodGet, fmt.Sprintf("/item/%d/movements", item), admin, nil)
+	if total := movements["meta"].(map[string]interface{})["total"].(float64); total != 3 {
+		t.Errorf("expected 3 stock movements, got %v", total)
+	}
+}
+
+func TestPermissionFailures(t *testing.T) {
+	s := newTestServer(t)
+
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	s.expect(403, http.MethodPost, "/admin", "", gin.H{"name": "Second", "email": "second@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+
+	s.register("Alice", "alice@example.com", "secret")
+	s.register("Bob", "bob@example.com", "secret")
+	alice := s.login("alice@example.com", "secret")
+	bob := s.login("bob@example.com", "secret")
+
+	// Token tidak ada atau tidak valid
+	s.expect(401, http.MethodGet, "/detail", "", nil)
+	s.expect(401, http.MethodGet, "/detail", "not-a-token", nil)
+
+	// User biasa tidak boleh mengelola item, user lain atau admin
+	if problem := s.expect(403, http.MethodPost, "/item", alice, gin.H{"name": "Camera", "stock": 1}); problem["code"] != "FORBIDDEN" {
+		t.Errorf("expected FORBIDDEN problem, got %v", problem)
+	}
+	s.expect(403, http.MethodPost, "/admin/invitation", alice, gin.H{"email": "x@example.com"})
+	s.expect(401, http.MethodGet, "/admin", "", nil)
+	s.expect(403, http.MethodGet, "/admin", alice, nil)
+	s.expect(403, http.MethodGet, "/user", alice, nil)
+	s.expect(200, http.MethodGet, "/admin", admin, nil)
+	item := id(data(s.expect(201, http.MethodPost, "/item", admin, gin.H{"name": "Camera", "stock": 1})), "item_id")
+
+	// Keranjang dan detail orang lain tidak bisa diakses
+	cart := id(data(s.expect(201, http.MethodPost, "/chart", alice, gin.H{"item_id": item, "quantity": 1})), "transaction_id")
+	s.expect(403, http.MethodPut, fmt.Sprintf("/chart/%d", cart), bob, gin.H{"item_id": item, "quantity": 1})
+	s.expect(403, http.MethodDelete, fmt.Sprintf("/chart/%d", cart), bob, nil)
+
+	detail := id(data(s.expect(201, http.MethodPost, "/detail", alice, gin.H{"out": "2030-02-01"}), "detail"), "detail_id")
+	s.expect(403, http.MethodGet, fmt.Sprintf("/detail/%d", detail), bob, nil)
+	s.expect(403, http.MethodPost, fmt.Sprintf("/detail/%d/cancel", detail), bob, nil)
+	s.expect(200, http.MethodGet, fmt.Sprintf("/detail/%d", detail), admin, nil)
+
+	// Daftar hanya berisi milik sendiri kecuali untuk admin
+	if total := s.expect(200, http.MethodGet, "/detail", bob, nil)["meta"].(map[string]interface{})["total"].(float64); total != 0 {
+		t.Errorf("expected bob to see no details, got %v", total)
+	}
+	if total := s.expect(200, http.MethodGet, "/detail", admin, nil)["meta"].(map[string]interface{})["total"].(float64); total != 1 {
+		t.Errorf("expected admin to see 1 detail, got %v", total)
+	}
+
+	// Token yang sudah logout ditolak
+	s.expect(200, http.MethodPost, "/logout", bob, nil)
+	s.expect(401, http.MethodGet, "/detail", bob, nil)
+}
+
+func TestStockEdgeCases(t *testing.T) {
+	s := newTestServer(t)
+
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+	item := id(data(s.expect(201, http.MethodPost, "/item", admin, gin.H{"name": "Tripod", "stock": 1})), "item_id")
+
+	s.register("Alice", "alice@example.com", "secret")
+	s.register("Bob", "bob@example.com", "secret")
+	alice := s.login("alice@example.com", "secret")
+	bob := s.login("bob@example.com", "secret")
+
+	// Stok tidak bisa dikurangi melebihi yang ada
+	problem := s.expect(400, http.MethodPost, fmt.Sprintf("/item/%d/movements", item), admin, gin.H{"delta": -2, "reason": "loss"})
+	if problem["code"] != "INSUFFICIENT_STOCK" {
+		t.Errorf("expected INSUFFICIENT_STOCK, got %v", problem)
+	}
+
+	// Arah perubahan harus sesuai alasan, hanya penyesuaian yang boleh dua arah
+	s.expect(400, http.MethodPost, fmt.Sprintf("/item/%d/movements", item), admin, gin.H{"delta": -1, "reason": "purchase"})
+	s.expect(400, http.MethodPost, fmt.Sprintf("/item/%d/movements", item), admin, gin.H{"delta": 1, "reason": "loss"})
+
+	// Stok bisa diubah menjadi 0, dan item dengan riwayat ledger tetap bisa dihapus
+	cable := id(data(s.expect(201, http.MethodPost, "/item", admin, gin.H{"name": "Cable", "stock": 3})), "item_id")
+	s.expect(200, http.MethodPut, fmt.Sprintf("/item/%d", cable), admin, gin.H{"stock": 0})
+	if stock := s.stock(cable); stock != 0 {
+		t.Errorf("expected stock 0 after update, got %d", stock)
+	}
+	s.expect(200, http.MethodDelete, fmt.Sprintf("/item/%d", cable), admin, nil)
+	s.expect(404, http.MethodGet, fmt.Sprintf("/item/%d", cable), "", nil)
+
+	// Satu unit yang sudah dipesan alice tidak bisa dipesan bob pada tanggal yang bertabrakan
+	s.expect(201, http.MethodPost, "/chart", alice, gin.H{"item_id": item, "quantity": 1})
+	aliceDetail := id(data(s.expect(201, http.MethodPost, "/detail", alice, gin.H{"out": "2030-03-10", "entry": "2030-03-12"}), "detail"), "detail_id")
+	s.expect(201, http.MethodPost, "/chart", bob, gin.H{"item_id": item, "quantity": 1})
+	s.expect(400, http.MethodPost, "/detail", bob, gin.H{"out": "2030-03-11", "entry": "2030-03-13"})
+	bobDetail := id(data(s.expect(201, http.MethodPost, "/detail", bob, gin.H{"out": "2030-03-13", "entry": "2030-03-14"}), "detail"), "detail_id")
+
+	// Mengubah tanggal juga memeriksa ketersediaan, tanpa menghitung pesanan bob sendiri
+	if problem := s.expect(400, http.MethodPut, fmt.Sprintf("/detail/%d", bobDetail), bob, gin.H{"out": "2030-03-11", "entry": "2030-03-13"}); problem["code"] != "INSUFFICIENT_STOCK" {
+		t.Errorf("expected INSUFFICIENT_STOCK, got %v", problem)
+	}
+	if problem := s.expect(400, http.MethodPut, fmt.Sprintf("/detail/%d", bobDetail), bob, gin.H{"entry": "2030-03-12"}); problem["code"] != "INVALID_DATE" {
+		t.Errorf("expected INVALID_DATE, got %v", problem)
+	}
+	s.expect(200, http.MethodPut, fmt.Sprintf("/detail/%d", bobDetail), bob, gin.H{"out": "2030-03-13", "entry": "2030-03-15"})
+
+	// Persetujuan memakai kalender: bob disetujui walau unit sedang dipinjam alice karena
+	// tanggalnya tidak bertabrakan, tetapi checkout menunggu barangnya kembali ke gudang
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/approve", aliceDetail), admin, nil)
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/checkout", aliceDetail), admin, nil)
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/approve", bobDetail), admin, nil)
+	if problem := s.expect(400, http.MethodPost, fmt.Sprintf("/detail/%d/checkout", bobDetail), admin, nil); problem["code"] != "INSUFFICIENT_STOCK" {
+		t.Errorf("expected INSUFFICIENT_STOCK, got %v", problem)
+	}
+
+	// Item yang hilang tidak kembali ke stok
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/lost", aliceDetail), admin, nil)
+	if stock := s.stock(item); stock != 0 {
+		t.Errorf("expected stock 0 after loss, got %d", stock)
+	}
+
+	// Pembelian baru membuat checkout bob bisa diproses
+	s.expect(201, http.MethodPost, fmt.Sprintf("/item/%d/movements", item), admin, gin.H{"delta": 1, "reason": "purchase"})
+	s.expect(200, http.MethodPost, fmt.Sprintf("/detail/%d/checkout", bobDetail), admin, nil)
+	if stock := s.stock(item); stock != 0 {
+		t.Errorf("expected stock 0 after second checkout, got %d", stock)
+	}
+}
+
+func TestAccountRecovery(t *testing.T) {
+	s := newTestServer(t)
+
+	// Akun baru belum bisa mengajukan peminjaman sebelum email diverifikasi
+	s.expect(201, http.MethodPost, "/user", "", gin.H{"name": "Carol", "email": "carol@example.com", "password": "secret"})
+	carol := s.login("carol@example.com", "secret")
+	if problem := s.expect(403, http.MethodPost, "/detail", carol, gin.H{}); problem["code"] != "EMAIL_NOT_VERIFIED" {
+		t.Errorf("expected EMAIL_NOT_VERIFIED, got %v", problem)
+	}
+
+	// Tautan yang dikirim ulang menggantikan tautan sebelumnya
+	first := s.mailer.token(t, "carol@example.com")
+	if body := s.mailer.sent[len(s.mailer.sent)-1].Body; !strings.Contains(body, "http://localhost:8080/api/v1/email/verify?token=") {
+		t.Errorf("expected the verification link to use PUBLIC_URL instead of the request host, got %q", body)
+	}
+	s.expect(202, http.MethodPost, "/email/verify/resend", carol, nil)
+	s.expect(400, http.MethodGet, "/email/verify?token="+first, "", nil)
+	s.expect(200, http.MethodPost, "/email/verify?token="+s.mailer.token(t, "carol@example.com"), "", nil)
+	s.expect(409, http.MethodPost, "/email/verify/resend", carol, nil)
+	// Keranjang masih kosong, tapi pemeriksaan email sudah dilewati
+	s.expect(404, http.MethodPost, "/detail", carol, gin.H{})
+
+	// Email yang tidak terdaftar mendapat jawaban yang sama tanpa email terkirim
+	sent := len(s.mailer.sent)
+	s.expect(202, http.MethodPost, "/password/forgot", "", gin.H{"email": "nobody@example.com"})
+	if len(s.mailer.sent) != sent {
+		t.Errorf("expected no email for an unknown address")
+	}
+
+	// Reset password hanya bisa sekali dan mencabut semua sesi
+	s.expect(202, http.MethodPost, "/password/forgot", "", gin.H{"email": "carol@example.com"})
+	s.mailer.wait(t, sent+1)
+	reset := s.mailer.token(t, "carol@example.com")
+	s.expect(200, http.MethodPost, "/password/reset", "", gin.H{"token": reset, "password": "new-secret"})
+	s.expect(400, http.MethodPost, "/password/reset", "", gin.H{"token": reset, "password": "other-secret"})
+	s.expect(401, http.MethodGet, "/chart", carol, nil)
+	s.expect(401, http.MethodPost, "/login", "", gin.H{"email": "carol@example.com", "password": "secret"})
+	s.login("carol@example.com", "new-secret")
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := newTestServer(t)
+
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+	s.register("Dave", "dave@example.com", "secret")
+
+	// Kegagalan kelima mengunci akun, password yang benar pun ditolak sampai kunci dibuka
+	for i := 0; i < 5; i++ {
+		s.expect(401, http.MethodPost, "/login", "", gin.H{"email": "dave@example.com", "password": "wrong"})
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader([]byte(`{"email": "dave@example.com", "password": "secret"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != 429 || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Akun lain dari IP yang sama belum terkena batas per IP
+	s.login("admin@example.com", "secret")
+
+	lockouts := s.expect(200, http.MethodGet, "/lockouts", admin, nil)["data"].([]interface{})
+	if len(lockouts) != 1 || lockouts[0].(map[string]interface{})["subject"] != "account:dave@example.com" {
+		t.Errorf("expected dave's account to be locked, got %v", lockouts)
+	}
+	failures := s.expect(200, http.MethodGet, "/login-failures?email=dave@example.com", admin, nil)
+	if total := failures["meta"].(map[string]interface{})["total"]; total != float64(6) {
+		t.Errorf("expected 6 audit records (5 wrong passwords, 1 while locked), got %v", total)
+	}
+
+	s.expect(200, http.MethodDelete, "/lockouts/account:dave@example.com", admin, nil)
+	s.expect(404, http.MethodDelete, "/lockouts/account:dave@example.com", admin, nil)
+	s.login("dave@example.com", "secret")
+
+	// X-Forwarded-For dari klien yang bukan proxy tepercaya tidak mengganti alamat IP
+	s.expect(401, http.MethodPost, "/login", "", gin.H{"email": "dave@example.com", "password": "wrong"}, "X-Forwarded-For", "203.0.113.9")
+	spoofed := s.expect(200, http.MethodGet, "/login-failures?ip=203.0.113.9", admin, nil)
+	if total := spoofed["meta"].(map[string]interface{})["total"]; total != float64(0) {
+		t.Errorf("expected the forwarded address to be ignored, got %v records", total)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.MFARequiredRoles = []string{"admin"}
+	})
+
+	// Admin yang wajib MFA hanya bisa enrollment sebelum memakai faktor kedua
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+	if problem := s.expect(403, http.MethodGet, "/lockouts", admin, nil); problem["code"] != "MFA_REQUIRED" {
+		t.Fatalf("expected MFA_REQUIRED, got %v", problem)
+	}
+
+	setup := data(s.expect(200, http.MethodPost, "/mfa/totp/setup", admin, nil))
+	otpauth, err := url.Parse(setup["otpauth_url"].(string))
+	if err != nil || otpauth.Query().Get("secret") != setup["secret"] {
+		t.Fatalf("unexpected otpauth url %v", setup["otpauth_url"])
+	}
+	secret := setup["secret"].(string)
+	s.expect(401, http.MethodPost, "/mfa/totp/enable", admin, gin.H{"code": "000000"})
+	code, err := mfa.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	enabled := data(s.expect(200, http.MethodPost, "/mfa/totp/enable", admin, gin.H{"code": code}))
+	recoveryCodes := enabled["recovery_codes"].([]interface{})
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("expected 10 recovery codes, got %v", recoveryCodes)
+	}
+
+	// Enrollment mencabut semua sesi; token baru harus diterbitkan di detik berikutnya
+	s.expect(401, http.MethodPost, "/logout", admin, nil)
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	// Password benar hanya menghasilkan challenge, bukan token
+	challenge := data(s.expect(200, http.MethodPost, "/login", "", gin.H{"email": "admin@example.com", "password": "secret"}))
+	if challenge["mfa_required"] != true || challenge["token"] != nil {
+		t.Fatalf("expected an MFA challenge, got %v", challenge)
+	}
+	challengeToken := challenge["challenge_token"].(string)
+	s.expect(401, http.MethodGet, "/lockouts", challengeToken, nil)
+	s.expect(401, http.MethodPost, "/login/mfa", "", gin.H{"challenge_token": challengeToken, "code": "000000"})
+
+	// Recovery code menyelesaikan login sekali; challenge dan kode yang sama tidak bisa dipakai ulang
+	recovery := recoveryCodes[0].(string)
+	tokens := data(s.expect(200, http.MethodPost, "/login/mfa", "", gin.H{"challenge_token": challengeToken, "code": recovery}))
+	admin = tokens["token"].(string)
+	s.expect(200, http.MethodGet, "/lockouts", admin, nil)
+	s.expect(401, http.MethodPost, "/login/mfa", "", gin.H{"challenge_token": challengeToken, "code": recovery})
+
+	next := data(s.expect(200, http.MethodPost, "/login", "", gin.H{"email": "admin@example.com", "password": "secret"}))
+	s.expect(401, http.MethodPost, "/login/mfa", "", gin.H{"challenge_token": next["challenge_token"], "code": recovery})
+
+	// Role yang wajib MFA tidak bisa mematikan TOTP
+	s.expect(403, http.MethodPost, "/mfa/totp/disable", admin, gin.H{"code": recoveryCodes[1]})
+
+	// Sesi hasil refresh tetap membawa faktor kedua
+	refreshed := data(s.expect(200, http.MethodPost, "/token/refresh", "", gin.H{"refresh_token": tokens["refresh_token"]}))
+	s.expect(200, http.MethodGet, "/lockouts", refreshed["token"].(string), nil)
+
+	// API key tidak punya faktor kedua, jadi tidak bisa membawa permission persetujuan atau pengelolaan akun
+	s.expect(400, http.MethodPost, "/api-keys", admin, gin.H{"name": "Approver", "scopes": []string{"detail:approve"}})
+	s.expect(400, http.MethodPost, "/api-keys", admin, gin.H{"name": "Accounts", "scopes": []string{"item:write", "user:manage"}})
+	sync := data(s.expect(201, http.MethodPost, "/api-keys", admin, gin.H{"name": "Sync", "scopes": []string{"item:write"}}))
+	s.expect(201, http.MethodPost, "/item", "", gin.H{"name": "Scanner", "stock": 1}, "X-API-Key", sync["key"].(string))
+}
+
+func TestAPIKeys(t *testing.T) {
+	s := newTestServer(t)
+
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+	s.register("Kiosk", "kiosk@example.com", "secret")
+	users := s.expect(200, http.MethodGet, "/user?email=kiosk@example.com", admin, nil)["data"].([]interface{})
+	kioskID := id(users[0].(map[string]interface{}), "user_id")
+
+	// Key hanya bisa dibuat untuk akun sendiri dengan scope dari permission role-nya, user_id diabaikan
+	s.expect(400, http.MethodPost, "/api-keys", admin, gin.H{"name": "Kiosk", "scopes": []string{"loan:request"}})
+	s.expect(400, http.MethodPost, "/api-keys", admin, gin.H{"name": "Sync", "scopes": []string{"item:write"}, "expires_at": "2000-01-01T00:00:00Z"})
+	sync := data(s.expect(201, http.MethodPost, "/api-keys", admin, gin.H{"name": "Sync", "user_id": kioskID, "scopes": []string{"item:write"}, "expires_at": "2030-01-01T00:00:00Z"}))
+	if id(sync, "user_id") == kioskID {
+		t.Fatalf("expected the key to belong to its creator, got %v", sync)
+	}
+	syncKey := sync["key"].(string)
+	if !strings.HasPrefix(syncKey, sync["prefix"].(string)+"_") {
+		t.Fatalf("expected key %q to start with its prefix %v", syncKey, sync["prefix"])
+	}
+
+	// Key bertindak atas nama pemiliknya, terbatas pada scope-nya
+	s.expect(201, http.MethodPost, "/item", "", gin.H{"name": "Scanner", "stock": 1}, "X-API-Key", syncKey)
+	s.expect(403, http.MethodDelete, fmt.Sprintf("/user/%d", kioskID), "", nil, "X-API-Key", syncKey)
+	s.expect(403, http.MethodGet, "/lockouts", "", nil, "X-API-Key", syncKey)
+
+	// Key tidak bisa mengubah akun, membuat key lain atau logout
+	if problem := s.expect(403, http.MethodPut, fmt.Sprintf("/user/%d", kioskID), "", gin.H{"password": "stolen"}, "X-API-Key", syncKey); problem["code"] != "SESSION_REQUIRED" {
+		t.Errorf("expected SESSION_REQUIRED, got %v", problem)
+	}
+	s.expect(403, http.MethodPost, "/api-keys", "", gin.H{"name": "Copy", "scopes": []string{"item:write"}}, "X-API-Key", syncKey)
+	s.expect(403, http.MethodPost, "/logout", "", nil, "X-API-Key", syncKey)
+	s.expect(401, http.MethodGet, "/chart", "", nil, "X-API-Key", "gik_00000000_unknown")
+
+	keys := s.expect(200, http.MethodGet, "/api-keys?name=Sync", admin, nil)["data"].([]interface{})
+	listed := keys[0].(map[string]interface{})
+	if len(keys) != 1 || listed["prefix"] != sync["prefix"] || listed["last_used_at"] == "" || listed["key"] != nil {
+		t.Errorf("expected the sync key with its last use and without the key, got %v", keys)
+	}
+
+	// Key yang dicabut langsung ditolak
+	s.expect(200, http.MethodDelete, fmt.Sprintf("/api-keys/%d", id(sync, "api_key_id")), admin, nil)
+	s.expect(409, http.MethodDelete, fmt.Sprintf("/api-keys/%d", id(sync, "api_key_id")), admin, nil)
+	if problem := s.expect(401, http.MethodPost, "/item", "", gin.H{"name": "Printer", "stock": 1}, "X-API-Key", syncKey); problem["code"] != "INVALID_API_KEY" {
+		t.Errorf("expected INVALID_API_KEY, got %v", problem)
+	}
+}
+
+// ssoLogin menjalankan login SSO lengkap: GET /oidc/login, login di IdP tiruan dengan claims,
+// lalu callback. Yang dikembalikan adalah path callback (untuk diulang) beserta hasilnya.
+func (s *testServer) ssoLogin(idp *ssotest.IdP, claims map[string]interface{}) (string, int, map[string]interface{}) {
+	s.t.Helper()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		s.t.Fatalf("expected a redirect to the identity provider, got %d %s", w.Code, w.Body.String())
+	}
+	callback, err := idp.Login(w.Header().Get("Location"), claims)
+	if err != nil {
+		s.t.Fatalf("login at identity provider failed: %v", err)
+	}
+
+	u, _ := url.Parse(callback)
+	path := strings.TrimPrefix(u.Path, "/api/v1") + "?" + u.RawQuery
+	code, result := s.do(http.MethodGet, path, "", nil)
+	return path, code, result
+}
+
+func TestSingleSignOn(t *testing.T) {
+	idp := ssotest.New("inventory", "client-secret")
+	defer idp.Close()
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.OIDCIssuer = idp.URL
+		cfg.OIDCClientID = "inventory"
+		cfg.OIDCClientSecret = "client-secret"
+		cfg.OIDCRedirectURL = "http://inventory.example.com/api/v1/oidc/callback"
+		cfg.OIDCRoleMapping = []sso.RoleMapping{{Group: "inventory-admins", Role: "admin"}}
+	})
+
+	s.expect(201, http.MethodPost, "/admin", "", gin.H{"name": "Admin", "email": "admin@example.com", "password": "secret"}, "X-Setup-Token", setupToken)
+	admin := s.login("admin@example.com", "secret")
+
+	// Login password bisa dimatikan per akun
+	s.register("Bob", "bob@example.com", "secret")
+	bob := s.expect(200, http.MethodGet, "/user?email=bob@example.com", admin, nil)["data"].([]interface{})[0].(map[string]interface{})
+	s.expect(200, http.MethodPut, fmt.Sprintf("/user/%d/password-login", id(bob, "user_id")), admin, gin.H{"enabled": false})
+	if problem := s.expect(403, http.MethodPost, "/login", "", gin.H{"email": "bob@example.com", "password": "secret"}); problem["code"] != "PASSWORD_LOGIN_DISABLED" {
+		t.Errorf("expected PASSWORD_LOGIN_DISABLED, got %v", problem)
+	}
+
+	// Login SSO pertama membuat akun tanpa password dengan role default
+	staffClaims := map[string]interface{}{"sub": "staff-1", "email": "staff@example.com", "email_verified": true, "name": "Staff", "groups": []string{"staff"}}
+	_, code, result := s.ssoLogin(idp, staffClaims)
+	if code != 200 {
+		t.Fatalf("expected SSO login to succeed, got %d %v", code, result)
+	}
+	s.expect(200, http.MethodGet, "/chart", data(result)["token"].(string), nil)
+	staff := s.expect(200, http.MethodGet, "/user?email=staff@example.com", admin, nil)["data"].([]interface{})[0].(map[string]interface{})
+	if staff["sso_linked"] != true || staff["password_login"] != false || staff["email_verified"] != true || staff["role"] != "user" {
+		t.Errorf("unexpected provisioned account %v", staff)
+	}
+	s.expect(401, http.MethodPost, "/login", "", gin.H{"email": "staff@example.com", "password": "secret"})
+
+	// Login berikutnya memakai akun yang sama, dan callback tidak bisa diulang
+	callback, code, _ := s.ssoLogin(idp, staffClaims)
+	if code != 200 {
+		t.Fatalf("expected second SSO login to succeed, got %d", code)
+	}
+	s.expect(400, http.MethodGet, callback, "", nil)
+	if total := s.expect(200, http.MethodGet, "/user?email=staff@example.com", admin, nil)["meta"].(map[string]interface{})["total"]; total != float64(1) {
+		t.Errorf("expected a single staff account, got %v", total)
+	}
+
+	// Akun lokal dihubungkan lewat email yang diverifikasi IdP, role mengikuti grup
+	adminClaims := map[string]interface{}{"sub": "admin-1", "email": "admin@example.com", "email_verified": true, "groups": []string{"inventory-admins"}}
+	_, code, result = s.ssoLogin(idp, adminClaims)
+	if code != 200 {
+		t.Fatalf("expected SSO login to link the admin account, got %d %v", code, result)
+	}
+	ssoAdmin := data(result)["token"].(string)
+	s.expect(200, http.MethodGet, "/lockouts", ssoAdmin, nil)
+
+	// Email yang tidak diverifikasi IdP tidak boleh mengambil alih akun lokal
+	s.register("Carol", "carol@example.com", "secret")
+	_, code, _ = s.ssoLogin(idp, map[string]interface{}{"sub": "carol-1", "email": "carol@example.com", "email_verified": false})
+	if code != 403 {
+		t.Errorf("expected linking an unverified email to be rejected, got %d", code)
+	}
+
+	// Keluar dari grup admin di IdP menurunkan role, token lama dengan role admin tidak berlaku lagi
+	adminClaims["groups"] = []string{}
+	_, code, result = s.ssoLogin(idp, adminClaims)
+	if code != 200 {
+		t.Fatalf("expected SSO login to succeed, got %d %v", code, result)
+	}
+	s.expect(401, http.MethodGet, "/lockouts", ssoAdmin, nil)
+	s.expect(403, http.MethodGet, "/lockouts", data(result)["token"].(string), nil)
+}
@@ -1,32 +1,79 @@
 package route
 
 import (
+	"Gin-Inventory/config"
 	"Gin-Inventory/controller"
+	"Gin-Inventory/mail"
 	"Gin-Inventory/middleware"
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/scheduler"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupUserRoutes(api *gin.RouterGroup) {
-	api.POST("/login", middleware.LoginHandler)
+func SetupUserRoutes(api *gin.RouterGroup, cfg config.Config, authCfg middleware.AuthConfig, repos repository.Repositories, mailer mail.Mailer, tasks *scheduler.Scheduler) {
+	api.POST("/login", middleware.LoginHandler(authCfg))
+	api.POST("/login/mfa", middleware.MFALoginHandler(authCfg))
+	api.POST("/token/refresh", middleware.RefreshTokenHandler(authCfg))
+	if authCfg.SSO != nil {
+		api.GET("/oidc/login", middleware.OIDCLoginHandler(authCfg))
+		api.GET("/oidc/callback", middleware.OIDCCallbackHandler(authCfg))
+	}
+	api.POST("/password/forgot", controller.ForgotPasswordHandler(repos.Users, mailer, tasks, cfg.MailTimeout, cfg.PasswordResetExpire, cfg.PasswordResetURL))
+	api.POST("/password/reset", controller.ResetPasswordHandler(repos.Users, authCfg))
+	api.GET("/email/verify", controller.VerifyEmailHandler(repos.Users))
+	api.POST("/email/verify", controller.VerifyEmailHandler(repos.Users))
+
+	api.POST("/user", controller.CreateUserHandler(repos.Users, mailer, cfg.EmailVerificationExpire, cfg.PublicURL))
 
-	api.GET("/user", controller.GetAllUserHandler)
-	api.POST("/user", controller.CreateUserHandler)
+	api.POST("/admin", controller.CreateAdminHandler(repos.Users, cfg.AdminSetupToken))
+	api.POST("/admin/invitation/:token", controller.AcceptAdminInvitationHandler(repos.Users))
 
-	api.GET("/admin", controller.GetAllAdminHandler)
-	api.POST("/admin", controller.CreateAdminHandler)
+	// Route yang tetap bisa diakses akun yang wajib MFA tetapi belum enrollment TOTP
+	mfaSetup := api.Group("/")
+	mfaSetup.Use(middleware.AuthMiddleware(authCfg.WithoutMFARequirement()), middleware.RequireSession())
+	{
+		mfaSetup.POST("/logout", middleware.LogoutHandler(authCfg))
+		mfaSetup.POST("/logout/all", middleware.LogoutAllHandler(authCfg))
+		mfaSetup.POST("/mfa/totp/setup", middleware.TOTPSetupHandler(authCfg))
+		mfaSetup.POST("/mfa/totp/enable", middleware.TOTPEnableHandler(authCfg))
+		mfaSetup.POST("/mfa/totp/disable", middleware.TOTPDisableHandler(authCfg))
+		mfaSetup.POST("/mfa/recovery-codes", middleware.RecoveryCodesHandler(authCfg))
+	}
 
 	auth := api.Group("/")
-	auth.Use(middleware.AuthMiddleware())
+	auth.Use(middleware.AuthMiddleware(authCfg))
 	{
-		auth.POST("/logout", middleware.LogoutHandler)
+		// API key tidak boleh mengubah akun pemiliknya
+		session := middleware.RequireSession()
+		auth.POST("/email/verify/resend", session, controller.ResendVerificationHandler(repos.Users, mailer, cfg.EmailVerificationExpire, cfg.PublicURL))
+
+		// Daftar akun hanya untuk pengelola akun, agar email admin tidak bisa dikumpulkan untuk
+		// dikunci lewat login gagal
+		userManage := middleware.RequirePermission(model.PermissionUserManage)
+		adminManage := middleware.RequirePermission(model.PermissionAdminManage)
+		auth.GET("/user", userManage, controller.GetAllUserHandler(repos.Users))
+		auth.GET("/admin", adminManage, controller.GetAllAdminHandler(repos.Users))
+
+		auth.GET("/user/:id", controller.GetUserHandler(repos.Users))
+		auth.PUT("/user/:id", session, controller.UpdateUserHandler(repos.Users, mailer, cfg.EmailVerificationExpire, cfg.PublicURL))
+		auth.DELETE("/user/:id", userManage, controller.DeleteUserHandler(repos.Users))
+		auth.PUT("/user/:id/password-login", userManage, session, controller.SetPasswordLoginHandler(repos.Users))
+
+		// Login gagal: akun dan IP yang dikunci serta catatan auditnya
+		auth.GET("/lockouts", userManage, controller.GetLockoutsHandler(authCfg.Attempts))
+		auth.DELETE("/lockouts/:subject", userManage, controller.ClearLockoutHandler(authCfg.Attempts))
+		auth.GET("/login-failures", userManage, controller.GetLoginFailuresHandler(repos.Users))
 
-		auth.GET("/user/:id", controller.GetUserHandler)
-		auth.PUT("/user/:id", controller.UpdateUserHandler)
-		auth.DELETE("/user/:id", controller.DeleteUserHandler)
+		auth.POST("/admin/invitation", adminManage, controller.CreateAdminInvitationHandler(repos.Users, cfg.AdminInvitationExpire, cfg.PublicURL))
+		auth.GET("/admin/:id", adminManage, controller.GetAdminHandler(repos.Users))
+		auth.PUT("/admin/:id", adminManage, controller.UpdateAdminHandler(repos.Users))
+		auth.DELETE("/admin/:id", adminManage, controller.DeleteAdminHandler(repos.Users))
 
-		auth.GET("/admin/:id", controller.GetAdminHandler)
-		auth.PUT("/admin/:id", controller.UpdateAdminHandler)
-		auth.DELETE("/admin/:id", controller.DeleteAdminHandler)
+		// API key untuk klien mesin hanya bisa dikelola lewat login, bukan dengan API key lain
+		auth.GET("/api-keys", adminManage, session, controller.GetAPIKeysHandler(repos.Users))
+		auth.POST("/api-keys", adminManage, session, controller.CreateAPIKeyHandler(repos.Users, cfg.MFARequiredRoles))
+		auth.DELETE("/api-keys/:id", adminManage, session, controller.RevokeAPIKeyHandler(repos.Users))
 	}
 }
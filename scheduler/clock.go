@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock adalah sumber waktu scheduler. Di produksi memakai RealClock, di test memakai FakeClock
+// agar job bisa dijalankan tanpa menunggu waktu sebenarnya.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock memakai waktu sistem
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// FakeClock adalah Clock yang hanya maju saat Advance dipanggil
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance memajukan waktu dan membangunkan semua After yang sudah jatuh tempo
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	remaining := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.at.After(c.now) {
+			remaining = append(remaining, waiter)
+			continue
+		}
+		waiter.ch <- c.now
+	}
+	c.waiters = remaining
+}
+
+// Waiters mengembalikan jumlah After yang sedang menunggu, berguna untuk sinkronisasi di test
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job adalah pekerjaan latar belakang yang dijalankan berkala
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Scheduler menjalankan setiap job di goroutine sendiri: sekali saat Start, lalu setiap Interval
+type Scheduler struct {
+	clock Clock
+	jobs  []Job
+	wg    sync.WaitGroup
+}
+
+func New(clock Clock) *Scheduler {
+	return &Scheduler{clock: clock}
+}
+
+// Add mendaftarkan job, harus dipanggil sebelum Start
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start menjalankan semua job sampai ctx dibatalkan
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+}
+
+// Go menjalankan pekerjaan sekali jalan di background, misalnya mengirim email dari handler.
+// Pekerjaan ikut ditunggu Wait dan WaitContext saat shutdown, dan context-nya dibatalkan setelah
+// timeout agar tidak menahan shutdown tanpa batas.
+func (s *Scheduler) Go(timeout time.Duration, run func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		run(ctx)
+	}()
+}
+
+// Wait menunggu semua job dan pekerjaan Go selesai setelah ctx dibatalkan
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// WaitContext seperti Wait, tetapi berhenti menunggu saat ctx selesai dan mengembalikan ctx.Err()
+func (s *Scheduler) WaitContext(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	for {
+		if err := job.Run(ctx, s.clock.Now()); err != nil {
+			log.Printf("Job %s failed: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(job.Interval):
+		}
+	}
+}
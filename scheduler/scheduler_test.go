@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsJobOnEveryInterval(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	runs := make(chan time.Time, 10)
+
+	s := New(clock)
+	s.Add(Job{
+		Name:     "test",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, now time.Time) error {
+			runs <- now
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	// Job langsung dijalankan sekali saat Start
+	if got := <-runs; !got.Equal(start) {
+		t.Fatalf("expected first run at %v, got %v", start, got)
+	}
+
+	// Belum satu jam, job belum jalan lagi
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	clock.Advance(30 * time.Minute)
+	select {
+	case got := <-runs:
+		t.Fatalf("unexpected run at %v", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(30 * time.Minute)
+	if got := <-runs; !got.Equal(start.Add(time.Hour)) {
+		t.Fatalf("expected second run at %v, got %v", start.Add(time.Hour), got)
+	}
+
+	// Setelah ctx dibatalkan semua job berhenti
+	cancel()
+	s.Wait()
+}
+
+func TestWaitContextDrainsRunningJob(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s := New(clock)
+	s.Add(Job{
+		Name:     "slow",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, now time.Time) error {
+			close(started)
+			<-release
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	<-started
+	cancel()
+
+	// Job yang sedang berjalan tidak diputus, WaitContext menyerah saat deadline habis
+	timeout, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	if err := s.WaitContext(timeout); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded while job is running, got %v", err)
+	}
+
+	close(release)
+	if err := s.WaitContext(context.Background()); err != nil {
+		t.Errorf("expected job to finish, got %v", err)
+	}
+}
+
+func TestGoIsDrainedAndBounded(t *testing.T) {
+	s := New(NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	release := make(chan struct{})
+	released := make(chan error, 1)
+	stuck := make(chan error, 1)
+
+	// Pekerjaan yang sedang berjalan ditunggu saat shutdown
+	s.Go(time.Hour, func(ctx context.Context) {
+		<-release
+		released <- ctx.Err()
+	})
+	timeout, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	if err := s.WaitContext(timeout); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded while the task is running, got %v", err)
+	}
+	close(release)
+
+	// Pekerjaan yang macet dihentikan oleh timeout-nya sendiri
+	s.Go(10*time.Millisecond, func(ctx context.Context) {
+		<-ctx.Done()
+		stuck <- ctx.Err()
+	})
+	if err := s.WaitContext(context.Background()); err != nil {
+		t.Fatalf("expected tasks to finish, got %v", err)
+	}
+	if err := <-released; err != nil {
+		t.Errorf("expected the first task to finish before its timeout, got %v", err)
+	}
+	if err := <-stuck; err != context.DeadlineExceeded {
+		t.Errorf("expected the stuck task to be cancelled by its timeout, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+// Package sso menghubungkan login aplikasi dengan identity provider (IdP) OpenID Connect lewat
+// authorization code flow dengan PKCE. Package ini hanya berbicara dengan IdP; pencocokan
+// identity ke akun lokal dilakukan handler di package middleware.
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Config berisi pengaturan client OpenID Connect dan pemetaan grup ke role
+type Config struct {
+	// Issuer adalah URL issuer IdP, dipakai untuk discovery /.well-known/openid-configuration
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL adalah URL callback yang didaftarkan di IdP, yaitu /api/v1/oidc/callback
+	RedirectURL string
+	Scopes      []string
+	// GroupsClaim adalah nama klaim ID token yang berisi daftar grup
+	GroupsClaim string
+	// RoleMapping memetakan grup IdP ke role lokal; pemetaan pertama yang cocok menentukan role
+	RoleMapping []RoleMapping
+	// DefaultRole adalah role jika tidak ada grup yang cocok, kosong berarti login ditolak
+	DefaultRole string
+	// AutoProvision membuat akun lokal saat login SSO pertama jika email belum terdaftar
+	AutoProvision bool
+	// HTTPClient dipakai untuk discovery dan token endpoint, nil berarti http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// RoleMapping memetakan satu grup IdP ke role lokal
+type RoleMapping struct {
+	Group string
+	Role  string
+}
+
+// ParseRoleMapping membaca pemetaan berformat "grup=role,grup=role"
+func ParseRoleMapping(value string) ([]RoleMapping, error) {
+	var mappings []RoleMapping
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid role mapping %q, expected group=role", pair)
+		}
+		mappings = append(mappings, RoleMapping{Group: group, Role: role})
+	}
+	return mappings, nil
+}
+
+// Role menentukan role lokal dari grup identity. ok bernilai false jika tidak ada grup yang
+// cocok dan DefaultRole kosong.
+func (cfg Config) Role(groups []string) (role string, ok bool) {
+	for _, mapping := range cfg.RoleMapping {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role, true
+			}
+		}
+	}
+	return cfg.DefaultRole, cfg.DefaultRole != ""
+}
+
+// Identity adalah klaim ID token yang dipakai untuk mencocokkan dan membuat akun lokal
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	// AMR adalah metode autentikasi di IdP, misalnya ["pwd", "mfa"]
+	AMR []string
+}
+
+// Provider adalah client OpenID Connect. Discovery dilakukan saat pertama kali dipakai, sehingga
+// server tetap bisa start walau IdP sedang tidak bisa dihubungi.
+type Provider struct {
+	cfg Config
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// New membuat Provider dari cfg
+func New(cfg Config) *Provider {
+	return &Provider{cfg: cfg}
+}
+
+// Config mengembalikan pengaturan provider
+func (p *Provider) Config() Config {
+	return p.cfg
+}
+
+// NewVerifier membuat PKCE code verifier baru
+func NewVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// AuthCodeURL mengembalikan URL halaman login IdP dengan state, nonce dan PKCE challenge S256
+// dari verifier
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange menukar authorization code dengan token di IdP, lalu memverifikasi tanda tangan,
+// audience, masa berlaku dan nonce ID token sebelum mengembalikan klaimnya
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	config, verifierFor, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := config.Exchange(p.context(ctx), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response does not contain an id_token")
+	}
+	idToken, err := verifierFor.Verify(p.context(ctx), rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("id_token nonce does not match")
+	}
+
+	var claims struct {
+		Email             string   `json:"email"`
+		EmailVerified     bool     `json:"email_verified"`
+		Name              string   `json:"name"`
+		PreferredUsername string   `json:"preferred_username"`
+		AMR               []string `json:"amr"`
+	}
+	var raw map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("invalid id_token claims: %w", err)
+	}
+	if err := idToken.Claims(&raw); err != nil {
+		return Identity{}, fmt.Errorf("invalid id_token claims: %w", err)
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	return Identity{
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: claims.EmailVerified,
+		Name:          name,
+		Groups:        stringList(raw[p.cfg.GroupsClaim]),
+		AMR:           claims.AMR,
+	}, nil
+}
+
+// discover mengambil metadata IdP sekali lalu menyimpannya. Kegagalan tidak disimpan agar
+// request berikutnya mencoba lagi.
+func (p *Provider) discover(ctx context.Context) (oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider == nil {
+		provider, err := oidc.NewProvider(p.context(ctx), p.cfg.Issuer)
+		if err != nil {
+			return oauth2.Config{}, nil, fmt.Errorf("failed to discover identity provider: %w", err)
+		}
+		p.provider = provider
+	}
+
+	config := oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     p.provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	return config, p.provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}), nil
+}
+
+// context menambahkan HTTPClient ke ctx untuk dipakai go-oidc dan oauth2
+func (p *Provider) context(ctx context.Context) context.Context {
+	if p.cfg.HTTPClient == nil {
+		return ctx
+	}
+	return oidc.ClientContext(ctx, p.cfg.HTTPClient)
+}
+
+// stringList membaca klaim grup yang bisa berupa array string atau satu string
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
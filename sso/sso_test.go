@@ -0,0 +1,109 @@
+package sso_test
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"Gin-Inventory/sso"
+	"Gin-Inventory/sso/ssotest"
+)
+
+func TestRoleMapping(t *testing.T) {
+	mappings, err := sso.ParseRoleMapping(" inventory-admins=admin, staff = user ,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []sso.RoleMapping{{Group: "inventory-admins", Role: "admin"}, {Group: "staff", Role: "user"}}
+	if !reflect.DeepEqual(mappings, want) {
+		t.Fatalf("got %v, want %v", mappings, want)
+	}
+	if _, err := sso.ParseRoleMapping("admins"); err == nil {
+		t.Errorf("expected an error for a mapping without a role")
+	}
+
+	// Pemetaan pertama yang cocok menang, tanpa kecocokan dipakai DefaultRole
+	cfg := sso.Config{RoleMapping: mappings}
+	if role, ok := cfg.Role([]string{"staff", "inventory-admins"}); !ok || role != "admin" {
+		t.Errorf("expected admin, got %q %v", role, ok)
+	}
+	if _, ok := cfg.Role([]string{"finance"}); ok {
+		t.Errorf("expected no role without a default")
+	}
+	cfg.DefaultRole = "user"
+	if role, ok := cfg.Role(nil); !ok || role != "user" {
+		t.Errorf("expected the default role, got %q %v", role, ok)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := ssotest.New("inventory", "client-secret")
+	defer idp.Close()
+
+	provider := sso.New(sso.Config{
+		Issuer:       idp.URL,
+		ClientID:     "inventory",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost:8080/api/v1/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		GroupsClaim:  "groups",
+	})
+	ctx := context.Background()
+	claims := map[string]interface{}{
+		"sub":            "alice-123",
+		"email":          "Alice@Example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"groups":         []string{"staff"},
+		"amr":            []string{"pwd", "mfa"},
+	}
+
+	login := func(verifier, nonce string) string {
+		t.Helper()
+		authURL, err := provider.AuthCodeURL(ctx, "state", nonce, verifier)
+		if err != nil {
+			t.Fatalf("failed to build authorization URL: %v", err)
+		}
+		callback, err := idp.Login(authURL, claims)
+		if err != nil {
+			t.Fatalf("login at identity provider failed: %v", err)
+		}
+		u, _ := url.Parse(callback)
+		if u.Query().Get("state") != "state" {
+			t.Fatalf("expected state to be passed back, got %s", callback)
+		}
+		return u.Query().Get("code")
+	}
+
+	verifier := sso.NewVerifier()
+	code := login(verifier, "nonce")
+	identity, err := provider.Exchange(ctx, code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	want := sso.Identity{
+		Subject:       "alice-123",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "Alice",
+		Groups:        []string{"staff"},
+		AMR:           []string{"pwd", "mfa"},
+	}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("got %+v, want %+v", identity, want)
+	}
+
+	// Authorization code hanya bisa ditukar sekali
+	if _, err := provider.Exchange(ctx, code, verifier, "nonce"); err == nil {
+		t.Errorf("expected a used code to be rejected")
+	}
+	// Code yang dicuri tidak berguna tanpa PKCE verifier-nya
+	if _, err := provider.Exchange(ctx, login(verifier, "nonce"), sso.NewVerifier(), "nonce"); err == nil {
+		t.Errorf("expected a wrong code verifier to be rejected")
+	}
+	// ID token dari login lain ditolak karena nonce-nya berbeda
+	if _, err := provider.Exchange(ctx, login(verifier, "other"), verifier, "nonce"); err == nil {
+		t.Errorf("expected a nonce mismatch to be rejected")
+	}
+}
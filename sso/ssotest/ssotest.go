@@ -0,0 +1,195 @@
+// Package ssotest menyediakan identity provider OpenID Connect tiruan untuk pengujian login SSO
+// tanpa IdP sungguhan. Discovery dan JWKS dilayani oidctest dari go-oidc; package ini menambahkan
+// langkah login di halaman IdP (Login) dan token endpoint dengan pemeriksaan PKCE.
+package ssotest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+)
+
+const keyID = "ssotest"
+
+// IdP adalah identity provider tiruan di atas httptest.Server. Issuer-nya adalah IdP.URL.
+type IdP struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key       *rsa.PrivateKey
+	discovery *oidctest.Server
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant adalah authorization code yang belum ditukar beserta data request login-nya
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+// New menjalankan IdP tiruan untuk satu client. Panggil Close setelah selesai.
+func New(clientID, clientSecret string) *IdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("ssotest: generating key: " + err.Error())
+	}
+
+	idp := &IdP{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		discovery: &oidctest.Server{
+			PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: keyID, Algorithm: oidc.RS256}},
+		},
+		codes: map[string]grant{},
+	}
+	idp.Server = httptest.NewServer(idp)
+	idp.discovery.SetIssuer(idp.URL)
+	return idp
+}
+
+// ServeHTTP melayani token endpoint, sisanya (discovery dan JWKS) diteruskan ke oidctest
+func (idp *IdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		idp.serveToken(w, r)
+		return
+	}
+	idp.discovery.ServeHTTP(w, r)
+}
+
+// Login mensimulasikan user yang membuka authURL (URL halaman login IdP dari aplikasi) dan
+// berhasil login dengan klaim claims, misalnya {"sub": "123", "email": "...", "groups": [...]}.
+// Hasilnya adalah URL callback aplikasi beserta code dan state.
+func (idp *IdP) Login(authURL string, claims map[string]interface{}) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(authURL, idp.URL+"/auth?") {
+		return "", fmt.Errorf("unexpected authorization endpoint %s", authURL)
+	}
+
+	query := u.Query()
+	switch {
+	case query.Get("client_id") != idp.ClientID:
+		return "", errors.New("unknown client_id")
+	case query.Get("response_type") != "code":
+		return "", errors.New("response_type must be code")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		return "", errors.New("PKCE with S256 is required")
+	case query.Get("redirect_uri") == "":
+		return "", errors.New("redirect_uri is required")
+	}
+
+	code := randomString()
+	idp.mu.Lock()
+	idp.codes[code] = grant{
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		claims:      claims,
+	}
+	idp.mu.Unlock()
+
+	callback, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		return "", err
+	}
+	values := callback.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	callback.RawQuery = values.Encode()
+	return callback.String(), nil
+}
+
+// serveToken menukar authorization code dengan ID token setelah memeriksa client dan PKCE verifier
+func (idp *IdP) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != idp.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(idp.ClientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	// Authorization code hanya bisa ditukar sekali
+	idp.mu.Lock()
+	g, found := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || g.redirectURI != r.PostForm.Get("redirect_uri") || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": idp.URL,
+		"aud": idp.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+	for key, value := range g.claims {
+		claims[key] = value
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     oidctest.SignIDToken(idp.key, keyID, oidc.RS256, string(payload)),
+	})
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic("ssotest: reading random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
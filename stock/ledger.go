@@ -0,0 +1,109 @@
+package stock
+
+import (
+	"errors"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+)
+
+// Daftar alasan pergerakan stok
+const (
+	ReasonPurchase   = "purchase"
+	ReasonAdjustment = "adjustment"
+	ReasonLoan       = "loan"
+	ReasonReturn     = "return"
+	ReasonLoss       = "loss"
+)
+
+var (
+	ErrItemNotFound      = errors.New("item not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrConflict          = errors.New("stock was changed by another request")
+)
+
+// Move mencatat pergerakan stok dan menerapkannya ke Item.Stock dalam transaksi tx yang sama.
+// Pengurangan memakai UPDATE bersyarat (stock >= jumlah) sehingga stok tidak pernah negatif.
+func Move(tx *gorm.DB, movement *model.StockMovement) error {
+	query := tx.Model(&model.Item{}).Where("id = ?", movement.ItemID)
+	if movement.Delta < 0 {
+		query = query.Where("stock >= ?", -movement.Delta)
+	}
+
+	result := query.UpdateColumn("stock", gorm.Expr("stock + ?", movement.Delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Tidak ada baris yang berubah: item tidak ada atau stok tidak cukup
+		var count int64
+		if err := tx.Model(&model.Item{}).Where("id = ?", movement.ItemID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrItemNotFound
+		}
+		return ErrInsufficientStock
+	}
+
+	return tx.Create(movement).Error
+}
+
+// Set mengubah stok item ke nilai target dan mencatat selisihnya sebagai pergerakan.
+// Gagal dengan ErrConflict jika stok sudah berubah sejak item dibaca.
+func Set(tx *gorm.DB, item *model.Item, target int, movement *model.StockMovement) error {
+	movement.ItemID = item.ID
+	movement.Delta = target - item.Stock
+	if movement.Delta == 0 {
+		return nil
+	}
+
+	result := tx.Model(&model.Item{}).Where("id = ? AND stock = ?", item.ID, item.Stock).UpdateColumn("stock", target)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+
+	item.Stock = target
+	return tx.Create(movement).Error
+}
+
+// Drift adalah selisih antara stok tersimpan dan stok hasil perhitungan ulang dari ledger
+type Drift struct {
+	ItemID      uint
+	Name        string
+	Stock       int
+	LedgerStock int
+}
+
+// Reconcile menghitung ulang stok setiap item dari ledger dan mengembalikan item yang tidak cocok.
+// Jika fix bernilai true, Item.Stock diperbarui mengikuti ledger.
+func Reconcile(db *gorm.DB, fix bool) ([]Drift, error) {
+	var rows []Drift
+	err := db.Model(&model.Item{}).
+		Select("item.id AS item_id, item.name AS name, item.stock AS stock, COALESCE(SUM(stock_movement.delta), 0) AS ledger_stock").
+		Joins("LEFT JOIN stock_movement ON stock_movement.item_id = item.id").
+		Group("item.id, item.name, item.stock").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := []Drift{}
+	for _, row := range rows {
+		if row.Stock == row.LedgerStock {
+			continue
+		}
+		drifts = append(drifts, row)
+
+		if fix {
+			if err := db.Model(&model.Item{}).Where("id = ?", row.ItemID).UpdateColumn("stock", row.LedgerStock).Error; err != nil {
+				return drifts, err
+			}
+		}
+	}
+	return drifts, nil
+}
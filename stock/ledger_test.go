@@ -0,0 +1,51 @@
+package stock
+
+import (
+	"errors"
+	"testing"
+
+	"Gin-Inventory/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMoveAndReconcile(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Item{}, &model.StockMovement{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	item := model.Item{Name: "Projector"}
+	db.Create(&item)
+
+	if err := Move(db, &model.StockMovement{ItemID: item.ID, Delta: 5, Reason: ReasonPurchase}); err != nil {
+		t.Fatalf("purchase failed: %v", err)
+	}
+	if err := Move(db, &model.StockMovement{ItemID: item.ID, Delta: -6, Reason: ReasonLoss}); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+	if err := Move(db, &model.StockMovement{ItemID: 999, Delta: 1, Reason: ReasonPurchase}); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("expected ErrItemNotFound, got %v", err)
+	}
+
+	drifts, err := Reconcile(db, false)
+	if err != nil || len(drifts) != 0 {
+		t.Fatalf("expected no drift, got %v (%v)", drifts, err)
+	}
+
+	// Perubahan stok di luar ledger terdeteksi sebagai drift
+	db.Model(&item).UpdateColumn("stock", 8)
+	drifts, err = Reconcile(db, true)
+	if err != nil || len(drifts) != 1 || drifts[0].Stock != 8 || drifts[0].LedgerStock != 5 {
+		t.Fatalf("expected one drift 8 vs 5, got %v (%v)", drifts, err)
+	}
+
+	db.First(&item, item.ID)
+	if item.Stock != 5 {
+		t.Errorf("expected stock to be fixed to 5, got %d", item.Stock)
+	}
+}
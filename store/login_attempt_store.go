@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore menyimpan jumlah login gagal berturut-turut dan batas waktu kunci untuk
+// setiap subject (akun atau alamat IP)
+type LoginAttemptStore interface {
+	// Get mengembalikan catatan subject, zero value jika subject belum pernah gagal
+	Get(subject string) (model.LoginAttempt, error)
+	// RecordFailure menambah hitungan gagal subject dan mengembalikan jumlahnya. Catatan yang
+	// kegagalan terakhirnya lebih lama dari window dan tidak sedang terkunci dilupakan.
+	RecordFailure(subject string, now time.Time, window time.Duration) (int, error)
+	// Lock mengunci subject sampai waktu until
+	Lock(subject string, until time.Time) error
+	// Clear menghapus catatan subject, setelah login berhasil atau dibuka admin
+	Clear(subject string) error
+	// Locked mengembalikan semua subject yang masih terkunci pada waktu now
+	Locked(now time.Time) ([]model.LoginAttempt, error)
+	// Prune menghapus semua catatan yang sudah dilupakan, dijalankan berkala oleh scheduler
+	Prune(ctx context.Context, now time.Time, window time.Duration) error
+}
+
+// MemoryLoginAttemptStore adalah implementasi LoginAttemptStore di memori untuk satu instance server
+type MemoryLoginAttemptStore struct {
+	mu       sync.RWMutex
+	attempts map[string]model.LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: map[string]model.LoginAttempt{}}
+}
+
+func (s *MemoryLoginAttemptStore) Get(subject string) (model.LoginAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.attempts[subject], nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(subject string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Catatan lama subject ini dihitung dari awal, catatan subject lain dibersihkan oleh Prune
+	attempt := s.attempts[subject]
+	if stale(attempt, now, window) {
+		attempt = model.LoginAttempt{}
+	}
+	attempt.Subject = subject
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[subject] = attempt
+	return attempt.Failures, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(subject string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[subject]
+	attempt.Subject = subject
+	attempt.LockedUntil = &until
+	s.attempts[subject] = attempt
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Clear(subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, subject)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Locked(now time.Time) ([]model.LoginAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	locked := []model.LoginAttempt{}
+	for _, attempt := range s.attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			locked = append(locked, attempt)
+		}
+	}
+	return locked, nil
+}
+
+func (s *MemoryLoginAttemptStore) Prune(ctx context.Context, now time.Time, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if stale(attempt, now, window) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
+
+// stale memeriksa apakah catatan sudah boleh dilupakan
+func stale(attempt model.LoginAttempt, now time.Time, window time.Duration) bool {
+	locked := attempt.LockedUntil != nil && attempt.LockedUntil.After(now)
+	return !locked && !attempt.LastFailureAt.After(now.Add(-window))
+}
+
+// GormLoginAttemptStore adalah implementasi LoginAttemptStore berbasis database
+// sehingga hitungan login gagal dan kunci berlaku di semua replika server
+type GormLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+func NewGormLoginAttemptStore(db *gorm.DB) *GormLoginAttemptStore {
+	return &GormLoginAttemptStore{db: db}
+}
+
+func (s *GormLoginAttemptStore) Get(subject string) (model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	err := s.db.Where("subject = ?", subject).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.LoginAttempt{}, nil
+	}
+	return attempt, err
+}
+
+func (s *GormLoginAttemptStore) RecordFailure(subject string, now time.Time, window time.Duration) (int, error) {
+	var attempt model.LoginAttempt
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Catatan lama subject ini dihapus agar hitungan mulai dari awal, subject lain dibersihkan oleh Prune
+		if err := staleAttempts(tx.Unscoped().Where("subject = ?", subject), now, window).
+			Delete(&model.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+
+		// Upsert dalam satu statement agar replika yang mencatat bersamaan tidak saling menimpa
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "subject"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("failures + 1"),
+				"last_failure_at": now,
+				"updated_at":      now,
+			}),
+		}).Create(&model.LoginAttempt{Subject: subject, Failures: 1, LastFailureAt: now}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("subject = ?", subject).First(&attempt).Error
+	})
+	return attempt.Failures, err
+}
+
+func (s *GormLoginAttemptStore) Lock(subject string, until time.Time) error {
+	return s.db.Model(&model.LoginAttempt{}).Where("subject = ?", subject).Update("locked_until", until).Error
+}
+
+func (s *GormLoginAttemptStore) Clear(subject string) error {
+	return s.db.Unscoped().Where("subject = ?", subject).Delete(&model.LoginAttempt{}).Error
+}
+
+func (s *GormLoginAttemptStore) Locked(now time.Time) ([]model.LoginAttempt, error) {
+	locked := []model.LoginAttempt{}
+	err := s.db.Where("locked_until > ?", now).Order("locked_until DESC").Find(&locked).Error
+	return locked, err
+}
+
+func (s *GormLoginAttemptStore) Prune(ctx context.Context, now time.Time, window time.Duration) error {
+	return staleAttempts(s.db.WithContext(ctx).Unscoped(), now, window).Delete(&model.LoginAttempt{}).Error
+}
+
+// staleAttempts memilih catatan yang sudah boleh dilupakan, sama dengan stale
+func staleAttempts(db *gorm.DB, now time.Time, window time.Duration) *gorm.DB {
+	return db.Where("last_failure_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", now.Add(-window), now)
+}
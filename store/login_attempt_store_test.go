@@ -0,0 +1,71 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Gin-Inventory/repository"
+	"Gin-Inventory/store"
+)
+
+func TestLoginAttemptStores(t *testing.T) {
+	db, err := repository.OpenSQLite("")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	stores := map[string]store.LoginAttemptStore{
+		"memory":   store.NewMemoryLoginAttemptStore(),
+		"database": store.NewGormLoginAttemptStore(db),
+	}
+	for name, attempts := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			window := time.Hour
+
+			for i := 1; i <= 3; i++ {
+				failures, err := attempts.RecordFailure("account:a@example.com", now, window)
+				if err != nil || failures != i {
+					t.Fatalf("expected %d failures, got %d (%v)", i, failures, err)
+				}
+			}
+			attempts.RecordFailure("ip:192.0.2.1", now, window)
+
+			if err := attempts.Lock("account:a@example.com", now.Add(time.Minute)); err != nil {
+				t.Fatalf("Lock failed: %v", err)
+			}
+			locked, _ := attempts.Locked(now)
+			if len(locked) != 1 || locked[0].Subject != "account:a@example.com" || locked[0].Failures != 3 {
+				t.Errorf("expected only the account to be locked, got %+v", locked)
+			}
+
+			// Kegagalan setelah window terlewati dihitung dari awal, kecuali subject masih terkunci
+			later := now.Add(2 * window)
+			if failures, _ := attempts.RecordFailure("ip:192.0.2.1", later, window); failures != 1 {
+				t.Errorf("expected failures to restart after the window, got %d", failures)
+			}
+			if locked, _ := attempts.Locked(later); len(locked) != 0 {
+				t.Errorf("expected lock to have expired, got %+v", locked)
+			}
+
+			// Prune membuang catatan yang sudah dilupakan tanpa menyentuh yang masih berlaku
+			if err := attempts.Prune(context.Background(), later, window); err != nil {
+				t.Fatalf("Prune failed: %v", err)
+			}
+			if attempt, _ := attempts.Get("account:a@example.com"); attempt.Failures != 0 {
+				t.Errorf("expected the stale account record to be pruned, got %+v", attempt)
+			}
+			if attempt, _ := attempts.Get("ip:192.0.2.1"); attempt.Failures != 1 {
+				t.Errorf("expected the recent IP record to be kept, got %+v", attempt)
+			}
+
+			if err := attempts.Clear("ip:192.0.2.1"); err != nil {
+				t.Fatalf("Clear failed: %v", err)
+			}
+			if attempt, _ := attempts.Get("ip:192.0.2.1"); attempt.Failures != 0 {
+				t.Errorf("expected cleared subject to be empty, got %+v", attempt)
+			}
+		})
+	}
+}
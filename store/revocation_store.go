@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"Gin-Inventory/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore menyimpan daftar token yang sudah dicabut (denylist berdasarkan jti)
+// serta batas waktu "logout dari semua sesi" untuk setiap akun.
+type RevocationStore interface {
+	// Revoke mencabut satu token sampai waktu kedaluwarsanya
+	Revoke(jti string, expiresAt time.Time) error
+	// IsRevoked memeriksa apakah jti sudah dicabut dan belum kedaluwarsa
+	IsRevoked(jti string) (bool, error)
+	// RevokeAll mencabut semua token akun yang diterbitkan sebelum waktu before
+	RevokeAll(userID uint, before time.Time) error
+	// RevokedBefore mengembalikan batas waktu pencabutan akun, atau zero time jika tidak ada
+	RevokedBefore(userID uint) (time.Time, error)
+	// Prune menghapus token yang sudah kedaluwarsa pada waktu now, dijalankan berkala oleh scheduler
+	Prune(ctx context.Context, now time.Time) error
+}
+
+// MemoryRevocationStore adalah implementasi RevocationStore di memori untuk satu instance server
+type MemoryRevocationStore struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	sessions map[uint]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:   map[string]time.Time{},
+		sessions: map[uint]time.Time{},
+	}
+}
+
+func (s *MemoryRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exp, ok := s.tokens[jti]
+	return ok && exp.After(time.Now()), nil
+}
+
+func (s *MemoryRevocationStore) RevokeAll(userID uint, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[userID] = before
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokedBefore(userID uint) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sessions[userID], nil
+}
+
+func (s *MemoryRevocationStore) Prune(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, exp := range s.tokens {
+		if !exp.After(now) {
+			delete(s.tokens, key)
+		}
+	}
+	return nil
+}
+
+// GormRevocationStore adalah implementasi RevocationStore berbasis database
+// sehingga pencabutan token berlaku di semua replika server
+type GormRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationStore(db *gorm.DB) *GormRevocationStore {
+	return &GormRevocationStore{db: db}
+}
+
+func (s *GormRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	// Logout bersamaan dengan token yang sama cukup tercatat sekali
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "jti"}},
+		DoNothing: true,
+	}).Create(&model.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (s *GormRevocationStore) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&model.RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, time.Now()).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *GormRevocationStore) RevokeAll(userID uint, before time.Time) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&model.SessionRevocation{UserID: userID, RevokedBefore: before}).Error
+}
+
+func (s *GormRevocationStore) RevokedBefore(userID uint) (time.Time, error) {
+	var revocation model.SessionRevocation
+	err := s.db.Where("user_id = ?", userID).First(&revocation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return revocation.RevokedBefore, nil
+}
+
+func (s *GormRevocationStore) Prune(ctx context.Context, now time.Time) error {
+	return s.db.WithContext(ctx).Unscoped().Where("expires_at <= ?", now).Delete(&model.RevokedToken{}).Error
+}
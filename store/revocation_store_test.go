@@ -0,0 +1,87 @@
+package store_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"Gin-Inventory/model"
+	"Gin-Inventory/repository"
+	"Gin-Inventory/store"
+)
+
+func TestRevocationStores(t *testing.T) {
+	db, err := repository.OpenSQLite("")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	stores := map[string]store.RevocationStore{
+		"memory":   store.NewMemoryRevocationStore(),
+		"database": store.NewGormRevocationStore(db),
+	}
+	for name, revocations := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+
+			// Logout bersamaan dengan token yang sama tidak boleh gagal
+			var wg sync.WaitGroup
+			errs := make(chan error, 5)
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- revocations.Revoke("jti-1", now.Add(time.Hour))
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("Revoke failed: %v", err)
+				}
+			}
+			revocations.Revoke("jti-expired", now.Add(-time.Minute))
+
+			if revoked, _ := revocations.IsRevoked("jti-1"); !revoked {
+				t.Errorf("expected jti-1 to be revoked")
+			}
+			if revoked, _ := revocations.IsRevoked("jti-expired"); revoked {
+				t.Errorf("expected an expired token not to count as revoked")
+			}
+			if revoked, _ := revocations.IsRevoked("jti-2"); revoked {
+				t.Errorf("expected jti-2 not to be revoked")
+			}
+
+			if err := revocations.Prune(context.Background(), now); err != nil {
+				t.Fatalf("Prune failed: %v", err)
+			}
+			if revoked, _ := revocations.IsRevoked("jti-1"); !revoked {
+				t.Errorf("expected Prune to keep tokens that have not expired")
+			}
+
+			// RevokeAll menimpa batas sebelumnya per akun
+			if before, _ := revocations.RevokedBefore(1); !before.IsZero() {
+				t.Errorf("expected no cutoff, got %s", before)
+			}
+			cutoff := now.Truncate(time.Second)
+			revocations.RevokeAll(1, cutoff.Add(-time.Hour))
+			if err := revocations.RevokeAll(1, cutoff); err != nil {
+				t.Fatalf("RevokeAll failed: %v", err)
+			}
+			if before, _ := revocations.RevokedBefore(1); !before.Equal(cutoff) {
+				t.Errorf("expected cutoff %s, got %s", cutoff, before)
+			}
+			if before, _ := revocations.RevokedBefore(2); !before.IsZero() {
+				t.Errorf("expected the cutoff to apply to a single account, got %s", before)
+			}
+		})
+	}
+
+	var remaining int64
+	db.Unscoped().Model(&model.RevokedToken{}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("expected only the unexpired token to remain after Prune, got %d rows", remaining)
+	}
+}